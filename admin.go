@@ -0,0 +1,241 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var roleLevels = map[string]int{
+	"viewer":   1,
+	"operator": 2,
+	"admin":    3,
+}
+
+type adminIdentity struct {
+	Name string
+	Role string
+}
+
+type AdjustmentRequest struct {
+	Address string `json:"address"`
+	Amount  int    `json:"amount"`
+	Reason  string `json:"reason"`
+}
+
+type adminHandler func(w http.ResponseWriter, r *http.Request, admin *adminIdentity)
+
+func authenticateAdmin(r *http.Request) *adminIdentity {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil
+	}
+
+	tokens := serverConfig.Admin.Tokens
+	if serverConfig.Admin.Token != "" {
+		tokens = append([]AdminToken{{Name: "admin", Token: serverConfig.Admin.Token, Role: "admin"}}, tokens...)
+	}
+
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t.Token)) == 1 {
+			return &adminIdentity{Name: t.Name, Role: t.Role}
+		}
+	}
+
+	return nil
+}
+
+// requireRole authenticates the bearer token and only runs the handler when
+// the token's role is at least the given role.
+func requireRole(role string, next adminHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admin := authenticateAdmin(r)
+		if admin == nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeErrorResponse(w, r, http.StatusUnauthorized, "unauthorized", "unauthorized")
+			return
+		}
+
+		annotate(r, slog.String("admin", admin.Name))
+
+		if roleLevels[admin.Role] < roleLevels[role] {
+			writeErrorResponse(w, r, http.StatusForbidden, "forbidden", "forbidden")
+			return
+		}
+
+		next(w, r, admin)
+	}
+}
+
+// audit records an admin action. Actions that change the ledger pass their
+// transaction so the entry is committed, or rolled back, with the change.
+func audit(db dbtx, r *http.Request, admin *adminIdentity, action, target, details string) {
+	insertAuditLog(db, AuditEntry{
+		Time:      int(time.Now().Unix()),
+		Actor:     admin.Name,
+		Role:      admin.Role,
+		Action:    action,
+		Target:    target,
+		Details:   details,
+		RequestID: requestID(r),
+	})
+}
+
+func getAdminConfig(w http.ResponseWriter, r *http.Request, admin *adminIdentity) {
+	audit(sqliteDatabase, r, admin, "config.view", "", "")
+
+	config := serverConfig.redacted()
+	writeResponse(w, r, map[string]interface{}{"ok": true, "config": config}, AdminConfigResponse{Config: config})
+}
+
+func getAdminAudit(w http.ResponseWriter, r *http.Request, admin *adminIdentity) {
+	entries, err := queryAuditLog(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve audit log")
+		return
+	}
+
+	audit(sqliteDatabase, r, admin, "audit.view", "", "")
+
+	result := AuditLogResponse{Entries: entries}
+	if result.Entries == nil {
+		result.Entries = []AuditEntry{}
+	}
+
+	writeResponse(w, r, map[string]interface{}{"ok": true, "entries": entries}, result)
+}
+
+// getAdminConflicts lists the peer entries this node refused because they
+// conflict with its ledger.
+func getAdminConflicts(w http.ResponseWriter, r *http.Request, admin *adminIdentity) {
+	conflicts, err := queryPeerConflicts(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve conflicts")
+		return
+	}
+
+	audit(sqliteDatabase, r, admin, "conflicts.view", "", "")
+
+	result := PeerConflictsResponse{Conflicts: conflicts}
+	if result.Conflicts == nil {
+		result.Conflicts = []PeerConflict{}
+	}
+
+	writeResponse(w, r, map[string]interface{}{"ok": true, "conflicts": result.Conflicts}, result)
+}
+
+func freezeAddress(w http.ResponseWriter, r *http.Request, admin *adminIdentity) {
+	setFrozen(w, r, admin, true)
+}
+
+func unfreezeAddress(w http.ResponseWriter, r *http.Request, admin *adminIdentity) {
+	setFrozen(w, r, admin, false)
+}
+
+func setFrozen(w http.ResponseWriter, r *http.Request, admin *adminIdentity, frozen bool) {
+	address := r.PathValue("address")
+
+	if !validateAddress(address) {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_address", "invalid address")
+		return
+	}
+
+	action := "address.unfreeze"
+	if frozen {
+		action = "address.freeze"
+	}
+
+	err := withLedgerTx(func(tx *sql.Tx) error {
+		if !setAddressFrozen(tx, address, frozen, int(time.Now().Unix())) {
+			return errUnknownAddress
+		}
+		audit(tx, r, admin, action, address, "")
+		return nil
+	})
+	if errors.Is(err, errUnknownAddress) {
+		writeErrorResponse(w, r, http.StatusNotFound, "address_not_found", "address not found")
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	writeResponse(w, r, map[string]interface{}{"ok": true, "address": address, "frozen": frozen}, FrozenResponse{Address: address, Frozen: frozen})
+}
+
+// createAdjustment mints (positive amount) or burns (negative amount) funds
+// on an address. The change is recorded as a transaction to or from the
+// special "adjustment" sender so the ledger still balances.
+func createAdjustment(w http.ResponseWriter, r *http.Request, admin *adminIdentity) {
+	var req AdjustmentRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	if !validateAddress(req.Address) {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_address", "invalid address")
+		return
+	}
+
+	if req.Amount == 0 {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_amount", "amount must not be zero")
+		return
+	}
+
+	if strings.TrimSpace(req.Reason) == "" {
+		writeErrorResponse(w, r, http.StatusBadRequest, "missing_reason", "reason is required")
+		return
+	}
+
+	now := int(time.Now().Unix())
+	var txID int64
+
+	err := withLedgerTx(func(tx *sql.Tx) error {
+		if queryAddress(tx, req.Address)+req.Amount < 0 {
+			return errInsufficientFunds
+		}
+
+		creditAddress(tx, req.Address, req.Amount)
+
+		if req.Amount > 0 {
+			txID = insertTransaction(tx, "adjustment", req.Amount, 0, req.Address, req.Reason, now)
+		} else {
+			txID = insertTransaction(tx, req.Address, -req.Amount, 0, "adjustment", req.Reason, now)
+		}
+
+		insertAdjustment(tx, req.Address, req.Amount, req.Reason, admin.Name, txID, now)
+		audit(tx, r, admin, "adjustment.create", req.Address, fmt.Sprintf("amount=%d reason=%q transaction=%d", req.Amount, req.Reason, txID))
+		return nil
+	})
+	if errors.Is(err, errInsufficientFunds) {
+		writeErrorResponse(w, r, http.StatusBadRequest, "insufficient_funds", "insufficient funds")
+		return
+	}
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	writeResponse(w, r, map[string]interface{}{"ok": true, "transaction": txID}, AdjustmentResult{TransactionID: txID})
+}
+
+func triggerVerification(w http.ResponseWriter, r *http.Request, admin *adminIdentity) {
+	report, err := verifyLedger(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "verification failed")
+		return
+	}
+
+	audit(sqliteDatabase, r, admin, "ledger.verify", "", fmt.Sprintf("ok=%t problems=%d", report.OK, len(report.Problems)))
+
+	writeResponse(w, r, map[string]interface{}{"ok": true, "report": report}, report)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// AssetRequest issues a new asset owned by the address of pkey. A mintable
+// asset can later be minted by its issuer, otherwise the supply is fixed.
+type AssetRequest struct {
+	Pkey     string `json:"pkey"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Supply   int    `json:"supply"`
+	Mintable bool   `json:"mintable"`
+}
+
+type MintRequest struct {
+	Pkey    string `json:"pkey"`
+	Address string `json:"address"`
+	Amount  int    `json:"amount"`
+}
+
+func assetResponse(asset *Asset) AssetResponse {
+	return AssetResponse{
+		ID:       asset.ID,
+		Name:     asset.Name,
+		Issuer:   asset.Issuer,
+		Supply:   asset.Supply,
+		Mintable: asset.Mintable,
+		Created:  asset.Created,
+	}
+}
+
+func createAsset(w http.ResponseWriter, r *http.Request) {
+	var req AssetRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	if !validateAssetID(req.ID) || req.ID == nativeAsset {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_asset_id", "asset id must be 2 to 12 uppercase letters and digits and not "+nativeAsset)
+		return
+	}
+
+	if req.Name == "" || !validateMemo(req.Name) {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_asset_name", "invalid asset name")
+		return
+	}
+
+	if req.Supply < 0 || (req.Supply == 0 && !req.Mintable) {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_supply", "supply must be positive unless the asset is mintable")
+		return
+	}
+
+	asset := &Asset{
+		ID:       req.ID,
+		Name:     req.Name,
+		Issuer:   generateAddress(req.Pkey),
+		Supply:   req.Supply,
+		Mintable: req.Mintable,
+		Created:  int(time.Now().Unix()),
+	}
+
+	txID, err := issueAsset(asset)
+	switch {
+	case errors.Is(err, errAssetExists):
+		writeErrorResponse(w, r, http.StatusConflict, "asset_exists", "asset already exists")
+		return
+	case err != nil:
+		log.Println("issuing asset failed:", err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	annotate(r, slog.String("asset", asset.ID))
+
+	result := AssetResult{TransactionID: txID, Asset: assetResponse(asset)}
+	response := map[string]interface{}{"ok": true, "assets": []AssetResponse{result.Asset}}
+	if txID != 0 {
+		response["transaction"] = txID
+	}
+	writeResponse(w, r, response, result)
+}
+
+func mintAssetSupply(w http.ResponseWriter, r *http.Request) {
+	var req MintRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	issuer := generateAddress(req.Pkey)
+	if req.Address == "" {
+		req.Address = issuer
+	}
+
+	if !validateAddress(req.Address) {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_address", "invalid address")
+		return
+	}
+
+	if req.Amount <= 0 {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_amount", "amount must be positive")
+		return
+	}
+
+	id := r.PathValue("id")
+	txID, err := mintAsset(id, issuer, req.Address, req.Amount, int(time.Now().Unix()))
+	switch {
+	case errors.Is(err, errUnknownAsset):
+		writeErrorResponse(w, r, http.StatusNotFound, "asset_not_found", "asset not found")
+		return
+	case errors.Is(err, errNotIssuer):
+		writeErrorResponse(w, r, http.StatusForbidden, "not_issuer", "only the issuer can mint an asset")
+		return
+	case errors.Is(err, errNotMintable):
+		writeErrorResponse(w, r, http.StatusConflict, "fixed_supply", "asset has a fixed supply")
+		return
+	case err != nil:
+		log.Println("minting asset failed:", err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	annotate(r, slog.String("asset", id), slog.Int64("transactionId", txID))
+
+	response := map[string]interface{}{"ok": true, "transaction": txID, "asset": id, "amount": req.Amount}
+	writeResponse(w, r, response, MintResult{TransactionID: txID, Asset: id, Amount: req.Amount})
+}
+
+func getAssets(w http.ResponseWriter, r *http.Request) {
+	assets, err := queryAssets(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve assets")
+		return
+	}
+
+	result := AssetListResponse{Assets: make([]AssetResponse, 0, len(assets))}
+	for i := range assets {
+		result.Assets = append(result.Assets, assetResponse(&assets[i]))
+	}
+
+	writeResponse(w, r, map[string]interface{}{"ok": true, "assets": result.Assets}, result)
+}
+
+// queryAssetSupply reports the supply of a token. Every unit issued is held
+// by some address, so the issued and circulating supplies should match.
+func queryAssetSupply(db dbtx, id string) (*Supply, error) {
+	asset, err := queryAsset(db, id)
+	if err != nil || asset == nil {
+		return nil, err
+	}
+
+	circulating, err := queryAssetCirculation(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Supply{
+		Asset:             asset.ID,
+		TotalSupply:       asset.Supply,
+		CirculatingSupply: circulating,
+		Mintable:          asset.Mintable,
+	}, nil
+}
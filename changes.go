@@ -0,0 +1,147 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultChangesLimit = 100
+	maxChangesLimit     = 1000
+	maxChangesWait      = 60
+)
+
+var (
+	changesMu sync.Mutex
+	// changesSignal is closed and replaced whenever new changes are
+	// committed, waking every long-poll waiting on it.
+	changesSignal = make(chan struct{})
+	// longPollStop is closed when the server shuts down so long-polls
+	// return straight away instead of holding up the drain.
+	longPollStop = make(chan struct{})
+)
+
+func notifyChanges() {
+	changesMu.Lock()
+	defer changesMu.Unlock()
+
+	close(changesSignal)
+	changesSignal = make(chan struct{})
+}
+
+func changesWaiter() <-chan struct{} {
+	changesMu.Lock()
+	defer changesMu.Unlock()
+
+	return changesSignal
+}
+
+func stopLongPolls() {
+	close(longPollStop)
+}
+
+// getChanges returns the ledger changes after the cursor since. With wait
+// set to a number of seconds it holds the request open until a change
+// arrives or the wait runs out, so clients can follow the feed without
+// polling in a tight loop.
+func getChanges(w http.ResponseWriter, r *http.Request) {
+	since, limit, wait, ok := parseFeedQuery(w, r)
+	if !ok {
+		return
+	}
+
+	changes, cursor, more, ok := pollChanges(w, r, since, limit, wait)
+	if !ok {
+		return
+	}
+
+	response := map[string]interface{}{"ok": true, "changes": changes, "cursor": cursor, "more": more}
+	writeResponse(w, r, response, changesPage(changes, cursor, more))
+}
+
+// parseFeedQuery reads the since, limit and wait parameters of a change
+// feed, writing an error response if one is invalid.
+func parseFeedQuery(w http.ResponseWriter, r *http.Request) (int64, int, int64, bool) {
+	query := r.URL.Query()
+
+	since, err := queryInt64(query.Get("since"), 0)
+	if err != nil || since < 0 {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_cursor", "since must be a cursor returned by this endpoint")
+		return 0, 0, 0, false
+	}
+
+	limit, err := queryInt64(query.Get("limit"), defaultChangesLimit)
+	if err != nil || limit < 1 || limit > maxChangesLimit {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_limit", "limit must be between 1 and "+strconv.Itoa(maxChangesLimit))
+		return 0, 0, 0, false
+	}
+
+	wait, err := queryInt64(query.Get("wait"), 0)
+	if err != nil || wait < 0 || wait > maxChangesWait {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_wait", "wait must be between 0 and "+strconv.Itoa(maxChangesWait)+" seconds")
+		return 0, 0, 0, false
+	}
+
+	return since, int(limit), wait, true
+}
+
+// pollChanges returns up to limit changes after since, the cursor to pass
+// as since next time and whether more changes are already waiting. If there
+// are none it waits up to wait seconds for one to be committed. It returns
+// false if it wrote an error response or the client went away.
+func pollChanges(w http.ResponseWriter, r *http.Request, since int64, limit int, wait int64) ([]Change, int64, bool, bool) {
+	deadline := time.Now().Add(time.Duration(wait) * time.Second)
+	if wait > 0 {
+		// Allow the response to be written after a full wait even if it is
+		// longer than writeTimeout.
+		http.NewResponseController(w).SetWriteDeadline(deadline.Add(serverConfig.WriteTimeout.Duration))
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	for {
+		// Take the signal before querying so a change committed in between
+		// still wakes us.
+		signal := changesWaiter()
+
+		changes, err := queryChanges(sqliteDatabase, since, limit+1)
+		if err != nil {
+			writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve changes")
+			return nil, 0, false, false
+		}
+
+		if len(changes) > 0 || wait == 0 {
+			more := len(changes) > limit
+			if more {
+				changes = changes[:limit]
+			}
+
+			cursor := since
+			if len(changes) > 0 {
+				cursor = changes[len(changes)-1].Cursor
+			}
+
+			return changes, cursor, more, true
+		}
+
+		select {
+		case <-signal:
+		case <-timer.C:
+			wait = 0
+		case <-r.Context().Done():
+			return nil, 0, false, false
+		case <-longPollStop:
+			wait = 0
+		}
+	}
+}
+
+func queryInt64(value string, fallback int64) (int64, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// The admin methods need a token set with WithAdminToken. They return
+// ErrUnauthorized without one and ErrForbidden if its role is too low.
+
+// AdminConfig returns the server's effective configuration with secrets
+// redacted.
+func (c *Client) AdminConfig(ctx context.Context) (json.RawMessage, error) {
+	var resp struct {
+		Config json.RawMessage `json:"config"`
+	}
+	err := c.get(ctx, "/admin/config", nil, &resp)
+	return resp.Config, err
+}
+
+// AuditLog returns every recorded admin action.
+func (c *Client) AuditLog(ctx context.Context) ([]AuditEntry, error) {
+	var resp struct {
+		Entries []AuditEntry `json:"entries"`
+	}
+	err := c.get(ctx, "/admin/audit", nil, &resp)
+	return resp.Entries, err
+}
+
+// PeerConflicts returns the peer entries the server refused because they
+// conflict with its ledger.
+func (c *Client) PeerConflicts(ctx context.Context) ([]PeerConflict, error) {
+	var resp struct {
+		Conflicts []PeerConflict `json:"conflicts"`
+	}
+	err := c.get(ctx, "/admin/conflicts", nil, &resp)
+	return resp.Conflicts, err
+}
+
+// FreezeAddress stops an address from sending funds.
+func (c *Client) FreezeAddress(ctx context.Context, address string) error {
+	return c.post(ctx, "/admin/address/"+url.PathEscape(address)+"/freeze", nil, nil)
+}
+
+func (c *Client) UnfreezeAddress(ctx context.Context, address string) error {
+	return c.post(ctx, "/admin/address/"+url.PathEscape(address)+"/unfreeze", nil, nil)
+}
+
+// Verify checks the chain links and that every balance matches its
+// transactions.
+func (c *Client) Verify(ctx context.Context) (*VerificationReport, error) {
+	var resp struct {
+		Report VerificationReport `json:"report"`
+	}
+	if err := c.post(ctx, "/admin/verify", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Report, nil
+}
+
+// Adjust mints or burns funds and returns the recorded transaction.
+func (c *Client) Adjust(ctx context.Context, req AdjustmentRequest) (int64, error) {
+	var resp struct {
+		Transaction int64 `json:"transaction"`
+	}
+	err := c.post(ctx, "/admin/adjustment", req, &resp)
+	return resp.Transaction, err
+}
@@ -0,0 +1,261 @@
+// Package client is a Go client for the go-cash server API.
+//
+//	c, err := client.New("http://localhost:8080")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	addr, err := c.Address(ctx, "1a2b3c4d5e6f")
+//
+// Every method takes a context. Requests that are safe to repeat are
+// retried on network errors and 502, 503 and 504 responses, and every
+// request is retried when the server rate limits it. Errors returned by the
+// server are *APIError values that match the Err* variables with errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+	defaultRetries = 2
+	defaultBackoff = 250 * time.Millisecond
+)
+
+// Client talks to one go-cash server. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	timeout    time.Duration
+	retries    int
+	backoff    time.Duration
+	adminToken string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used for requests, for example one
+// built by NewHTTPClient to trust a private CA.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithTimeout limits how long each attempt of a request may take. Zero
+// disables the limit. The default is 30 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithRetries sets how many times a failed request is retried and the delay
+// before the first retry, which doubles for each later one. The default is
+// 2 retries starting at 250ms.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithAdminToken sets the bearer token sent with requests to the admin API.
+func WithAdminToken(token string) Option {
+	return func(c *Client) {
+		c.adminToken = token
+	}
+}
+
+// New returns a client for the server at baseURL, such as
+// "https://node.example.com:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: must be an http or https URL", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		timeout:    defaultTimeout,
+		retries:    defaultRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// BaseURL returns the server URL the client was created with.
+func (c *Client) BaseURL() string {
+	return c.baseURL.String()
+}
+
+// request describes one API call.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+	// idempotent requests can be retried after network errors and gateway
+	// errors without risking doing the same thing twice.
+	idempotent bool
+	// timeout, if set, replaces the client's timeout for this request.
+	timeout time.Duration
+}
+
+// do sends req, retrying as configured, and returns the response with its
+// body unread. The caller must close the body.
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		if payload, err = json.Marshal(req.body); err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+	}
+
+	u := *c.baseURL
+	u.Path += req.path
+	u.RawQuery = req.query.Encode()
+
+	timeout := c.timeout
+	if req.timeout > 0 {
+		timeout = req.timeout
+	}
+
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+
+		httpReq, err := http.NewRequestWithContext(attemptCtx, req.method, u.String(), bytes.NewReader(payload))
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		if payload != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+		httpReq.Header.Set("Accept", "application/json")
+		if c.adminToken != "" && strings.HasPrefix(req.path, "/admin/") {
+			httpReq.Header.Set("Authorization", "Bearer "+c.adminToken)
+		}
+
+		resp, err := c.httpClient.Do(httpReq)
+		if err != nil {
+			cancel()
+			if req.idempotent && attempt < c.retries && ctx.Err() == nil {
+				if err := sleep(ctx, c.backoff<<attempt); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, err
+		}
+
+		if attempt < c.retries && retryable(resp.StatusCode, req.idempotent) {
+			wait := c.backoff << attempt
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				wait = time.Duration(seconds) * time.Second
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			cancel()
+			if err := sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	}
+}
+
+// call sends req and decodes a successful JSON response into result, which
+// may be nil. Other responses are returned as *APIError.
+func (c *Client) call(ctx context.Context, req request, result interface{}) error {
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp.StatusCode, body)
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, result interface{}) error {
+	return c.call(ctx, request{method: http.MethodGet, path: path, query: query, idempotent: true}, result)
+}
+
+func (c *Client) post(ctx context.Context, path string, body, result interface{}) error {
+	return c.call(ctx, request{method: http.MethodPost, path: path, body: body}, result)
+}
+
+// retryable reports whether a response means the request can be tried
+// again. A 429 means the server didn't act on the request at all.
+func retryable(status int, idempotent bool) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// cancelBody releases the attempt's context once the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Errors reported by the server. Use errors.Is to check an error returned
+// by a Client method against them.
+var (
+	// Status classes, matched by any error with that HTTP status.
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrRateLimited  = errors.New("rate limit exceeded")
+	ErrServer       = errors.New("server error")
+
+	// Specific failures, matched by the server's error message.
+	ErrInvalidAddress      = errors.New("invalid address")
+	ErrInvalidAmount       = errors.New("amount must be positive")
+	ErrInvalidMemo         = errors.New("invalid memo")
+	ErrFeeTooLow           = errors.New("fee below minimum")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrFrozen              = errors.New("address frozen")
+	ErrMultisigSender      = errors.New("multisig addresses can only be spent from with POST /multisig/transaction")
+	ErrUnknownAsset        = errors.New("unknown asset")
+	ErrAssetExists         = errors.New("asset already exists")
+	ErrFixedSupply         = errors.New("asset has a fixed supply")
+	ErrNotIssuer           = errors.New("only the issuer can mint an asset")
+	ErrInvalidBlock        = errors.New("invalid block")
+	ErrPrevBlockMismatch   = errors.New("previous block mismatch")
+	ErrInsufficientWork    = errors.New("block does not meet difficulty")
+	ErrBanned              = errors.New("temporarily banned")
+	ErrInvalidNonce        = errors.New("invalid nonce")
+	ErrNotEnoughSignatures = errors.New("not enough valid signatures")
+	ErrEscrowSettled       = errors.New("escrow already settled")
+	ErrNotEscrowParty      = errors.New("only the buyer, seller or arbiter can vote on an escrow")
+	ErrNotScheduleSender   = errors.New("only the sender can cancel a scheduled transfer")
+	ErrNotCancellable      = errors.New("only active recurring transfers can be cancelled")
+	ErrBodyTooLarge        = errors.New("request body too large")
+	ErrPrimaryUnavailable  = errors.New("primary unavailable")
+)
+
+var messageErrors = map[string]error{}
+
+func init() {
+	for _, err := range []error{
+		ErrInvalidAddress, ErrInvalidAmount, ErrInsufficientFunds, ErrFrozen, ErrMultisigSender, ErrUnknownAsset,
+		ErrAssetExists, ErrFixedSupply, ErrNotIssuer, ErrInvalidBlock, ErrPrevBlockMismatch,
+		ErrInsufficientWork, ErrBanned, ErrInvalidNonce, ErrNotEnoughSignatures, ErrEscrowSettled,
+		ErrNotEscrowParty, ErrNotScheduleSender, ErrNotCancellable, ErrBodyTooLarge, ErrPrimaryUnavailable,
+	} {
+		messageErrors[err.Error()] = err
+	}
+}
+
+// APIError is a non-200 response from the server.
+type APIError struct {
+	StatusCode int
+	Message    string
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("go-cash: %s (status %d, request %s)", e.Message, e.StatusCode, e.RequestID)
+	}
+	return fmt.Sprintf("go-cash: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// Unwrap returns the Err* variables the error matches: its status class
+// and, if the message is recognised, the specific failure.
+func (e *APIError) Unwrap() []error {
+	var errs []error
+
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		errs = append(errs, ErrBadRequest)
+	case e.StatusCode == http.StatusUnauthorized:
+		errs = append(errs, ErrUnauthorized)
+	case e.StatusCode == http.StatusForbidden:
+		errs = append(errs, ErrForbidden)
+	case e.StatusCode == http.StatusNotFound:
+		errs = append(errs, ErrNotFound)
+	case e.StatusCode == http.StatusConflict:
+		errs = append(errs, ErrConflict)
+	case e.StatusCode == http.StatusTooManyRequests:
+		errs = append(errs, ErrRateLimited)
+	case e.StatusCode >= 500:
+		errs = append(errs, ErrServer)
+	}
+
+	switch {
+	case messageErrors[e.Message] != nil:
+		errs = append(errs, messageErrors[e.Message])
+	case strings.HasPrefix(e.Message, "fee below minimum"):
+		errs = append(errs, ErrFeeTooLow)
+	case strings.HasPrefix(e.Message, "memo must be"):
+		errs = append(errs, ErrInvalidMemo)
+	}
+
+	return errs
+}
+
+func newAPIError(status int, body []byte) *APIError {
+	var resp struct {
+		Error     string `json:"error"`
+		RequestID string `json:"requestId"`
+	}
+
+	e := &APIError{StatusCode: status}
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Error != "" {
+		e.Message = resp.Error
+		e.RequestID = resp.RequestID
+	} else if text := strings.TrimSpace(string(body)); text != "" && len(text) < 200 {
+		e.Message = text
+	} else {
+		e.Message = http.StatusText(status)
+	}
+
+	return e
+}
@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Address returns an address with its native balance, locked funds and
+// token holdings. Unknown addresses have a zero balance.
+func (c *Client) Address(ctx context.Context, address string) (*Address, error) {
+	var resp struct {
+		Addresses []Address `json:"addresses"`
+	}
+	if err := c.get(ctx, "/address/"+url.PathEscape(address), nil, &resp); err != nil {
+		return nil, err
+	}
+	return firstOf(resp.Addresses, "address")
+}
+
+// Addresses returns every known address with its native balance.
+func (c *Client) Addresses(ctx context.Context) ([]Address, error) {
+	var resp struct {
+		Addresses []Address `json:"addresses"`
+	}
+	err := c.get(ctx, "/addresses", nil, &resp)
+	return resp.Addresses, err
+}
+
+// SendTransaction submits a transfer. It is not retried after network
+// errors, since the server may have recorded it.
+func (c *Client) SendTransaction(ctx context.Context, req TransactionRequest) (*TransactionResult, error) {
+	var result TransactionResult
+	if err := c.post(ctx, "/transaction", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Transaction returns a transaction by ID.
+func (c *Client) Transaction(ctx context.Context, id int64) (*Transaction, error) {
+	var resp struct {
+		Transactions []Transaction `json:"transactions"`
+	}
+	if err := c.get(ctx, "/transaction/"+strconv.FormatInt(id, 10), nil, &resp); err != nil {
+		return nil, err
+	}
+	return firstOf(resp.Transactions, "transaction")
+}
+
+// Transactions returns every transaction.
+func (c *Client) Transactions(ctx context.Context) ([]Transaction, error) {
+	var resp struct {
+		Transactions []Transaction `json:"transactions"`
+	}
+	err := c.get(ctx, "/transactions", nil, &resp)
+	return resp.Transactions, err
+}
+
+// AddressTransactions returns the transactions sent or received by an
+// address. A non-empty reference only returns those with that memo.
+func (c *Client) AddressTransactions(ctx context.Context, address, reference string) ([]Transaction, error) {
+	var query url.Values
+	if reference != "" {
+		query = url.Values{"reference": {reference}}
+	}
+
+	var resp struct {
+		Transactions []Transaction `json:"transactions"`
+	}
+	err := c.get(ctx, "/transactions/"+url.PathEscape(address), query, &resp)
+	return resp.Transactions, err
+}
+
+// SubmitBlock submits a mined block. Submitting the same block twice is
+// harmless, so it is retried like a read.
+func (c *Client) SubmitBlock(ctx context.Context, blk BlockSubmission) (*BlockResult, error) {
+	var result BlockResult
+	err := c.call(ctx, request{method: http.MethodPost, path: "/block", body: blk, idempotent: true}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Tip returns the hash of the last block on the main chain.
+func (c *Client) Tip(ctx context.Context) (string, error) {
+	var resp struct {
+		Block string `json:"block"`
+	}
+	err := c.get(ctx, "/block", nil, &resp)
+	return resp.Block, err
+}
+
+// Block returns a block on the main chain or a side branch by hash.
+func (c *Client) Block(ctx context.Context, hash string) (*BlockDetail, error) {
+	var resp struct {
+		Blocks []BlockDetail `json:"blocks"`
+	}
+	if err := c.get(ctx, "/block/"+url.PathEscape(hash), nil, &resp); err != nil {
+		return nil, err
+	}
+	return firstOf(resp.Blocks, "block")
+}
+
+// BlockAtHeight returns the main chain block at a height.
+func (c *Client) BlockAtHeight(ctx context.Context, height int) (*BlockDetail, error) {
+	var resp struct {
+		Blocks []BlockDetail `json:"blocks"`
+	}
+	if err := c.get(ctx, "/block/height/"+strconv.Itoa(height), nil, &resp); err != nil {
+		return nil, err
+	}
+	return firstOf(resp.Blocks, "block")
+}
+
+// BlockTransactions returns the transactions whose fees a block collected,
+// followed by its reward.
+func (c *Client) BlockTransactions(ctx context.Context, hash string) ([]Transaction, error) {
+	var resp struct {
+		Transactions []Transaction `json:"transactions"`
+	}
+	err := c.get(ctx, "/block/"+url.PathEscape(hash)+"/transactions", nil, &resp)
+	return resp.Transactions, err
+}
+
+// ChainTip returns the height, hash, time and cumulative work of the last
+// main chain block.
+func (c *Client) ChainTip(ctx context.Context) (*ChainTip, error) {
+	var tip ChainTip
+	if err := c.get(ctx, "/chain/tip", nil, &tip); err != nil {
+		return nil, err
+	}
+	return &tip, nil
+}
+
+// Blocks returns the main chain from the genesis block.
+func (c *Client) Blocks(ctx context.Context) ([]Block, error) {
+	var resp struct {
+		Blocks []Block `json:"blocks"`
+	}
+	err := c.get(ctx, "/blocks", nil, &resp)
+	return resp.Blocks, err
+}
+
+// OrphanedBlocks returns valid blocks that are not on the main chain.
+func (c *Client) OrphanedBlocks(ctx context.Context) ([]SideBlock, error) {
+	var resp struct {
+		Blocks []SideBlock `json:"blocks"`
+	}
+	err := c.get(ctx, "/blocks/orphaned", nil, &resp)
+	return resp.Blocks, err
+}
+
+// Reorgs returns past chain reorganizations.
+func (c *Client) Reorgs(ctx context.Context) ([]Reorg, error) {
+	var resp struct {
+		Reorgs []Reorg `json:"reorgs"`
+	}
+	err := c.get(ctx, "/reorgs", nil, &resp)
+	return resp.Reorgs, err
+}
+
+// Supply returns the supply of an asset, or of the native coin if asset is
+// empty.
+func (c *Client) Supply(ctx context.Context, asset string) (*Supply, error) {
+	var query url.Values
+	if asset != "" {
+		query = url.Values{"asset": {asset}}
+	}
+
+	var supply Supply
+	if err := c.get(ctx, "/supply", query, &supply); err != nil {
+		return nil, err
+	}
+	return &supply, nil
+}
+
+// Changes returns up to limit ledger changes after the cursor since. A
+// limit of zero uses the server default. If wait is positive and there are
+// no changes yet, the server holds the request for up to wait before
+// returning an empty page.
+func (c *Client) Changes(ctx context.Context, since int64, limit int, wait time.Duration) (*ChangesPage, error) {
+	query := url.Values{"since": {strconv.FormatInt(since, 10)}}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	req := request{method: http.MethodGet, path: "/changes", query: query, idempotent: true}
+	if wait > 0 {
+		query.Set("wait", strconv.Itoa(int(wait.Seconds())))
+		if c.timeout > 0 {
+			req.timeout = c.timeout + wait
+		}
+	}
+
+	var page ChangesPage
+	if err := c.call(ctx, req, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
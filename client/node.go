@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Health returns nil while the server process is up.
+func (c *Client) Health(ctx context.Context) error {
+	return c.get(ctx, "/healthz", nil, nil)
+}
+
+// Ready runs the server's readiness checks. A server that is up but not
+// ready returns a Readiness with Ready false and no error.
+func (c *Client) Ready(ctx context.Context) (*Readiness, error) {
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: "/readyz", idempotent: true})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var readiness Readiness
+	if err := json.Unmarshal(body, &readiness); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &readiness, nil
+}
+
+// Info returns the server's version, chain tip and economic settings.
+func (c *Client) Info(ctx context.Context) (*Info, error) {
+	var info Info
+	if err := c.get(ctx, "/info", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Metrics returns the server's metrics in Prometheus text format.
+func (c *Client) Metrics(ctx context.Context) (string, error) {
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: "/metrics", idempotent: true})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", newAPIError(resp.StatusCode, body)
+	}
+	return string(body), nil
+}
+
+// Snapshot writes a consistent copy of the server's SQLite database to w
+// and returns when the server took it.
+func (c *Client) Snapshot(ctx context.Context, w io.Writer) (time.Time, error) {
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: "/replication/snapshot", idempotent: true})
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return time.Time{}, newAPIError(resp.StatusCode, body)
+	}
+
+	taken, err := strconv.ParseInt(resp.Header.Get("X-GC-Snapshot-Time"), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid snapshot time: %w", err)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return time.Time{}, fmt.Errorf("reading snapshot: %w", err)
+	}
+	return time.UnixMilli(taken), nil
+}
+
+// PeerEntries returns up to limit entries of the server's peer feed after
+// the cursor since, waiting up to wait for new ones, as peers do. The
+// client must present a certificate the server trusts; see NewHTTPClient.
+func (c *Client) PeerEntries(ctx context.Context, since int64, limit int, wait time.Duration) (*PeerEntriesPage, error) {
+	query := url.Values{"since": {strconv.FormatInt(since, 10)}}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	req := request{method: http.MethodGet, path: "/p2p/entries", query: query, idempotent: true}
+	if wait > 0 {
+		query.Set("wait", strconv.Itoa(int(wait.Seconds())))
+		if c.timeout > 0 {
+			req.timeout = c.timeout + wait
+		}
+	}
+
+	var page PeerEntriesPage
+	if err := c.call(ctx, req, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+// CreateInvoice creates a payment request for an address.
+func (c *Client) CreateInvoice(ctx context.Context, req InvoiceRequest) (*Invoice, error) {
+	var resp struct {
+		Invoices []Invoice `json:"invoices"`
+	}
+	if err := c.post(ctx, "/invoice", req, &resp); err != nil {
+		return nil, err
+	}
+	return firstOf(resp.Invoices, "invoice")
+}
+
+// Invoice returns an invoice and its payment status.
+func (c *Client) Invoice(ctx context.Context, id string) (*Invoice, error) {
+	var resp struct {
+		Invoices []Invoice `json:"invoices"`
+	}
+	if err := c.get(ctx, "/invoice/"+url.PathEscape(id), nil, &resp); err != nil {
+		return nil, err
+	}
+	return firstOf(resp.Invoices, "invoice")
+}
+
+// CreateMultisig registers an address spendable by threshold of the hex
+// encoded ed25519 public keys. Registering the same keys again returns the
+// same address.
+func (c *Client) CreateMultisig(ctx context.Context, threshold int, publicKeys []string) (*Multisig, error) {
+	body := map[string]interface{}{"threshold": threshold, "pubkeys": publicKeys}
+
+	var resp struct {
+		Multisig Multisig `json:"multisig"`
+	}
+	if err := c.post(ctx, "/multisig", body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Multisig, nil
+}
+
+// Multisig describes a multisig address, including the nonce the next
+// transaction must exceed.
+func (c *Client) Multisig(ctx context.Context, address string) (*Multisig, error) {
+	var resp struct {
+		Multisig Multisig `json:"multisig"`
+	}
+	if err := c.get(ctx, "/multisig/"+url.PathEscape(address), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Multisig, nil
+}
+
+// SendMultisigTransaction broadcasts a transfer signed by enough co-signers.
+func (c *Client) SendMultisigTransaction(ctx context.Context, txn *MultisigTransaction) (*MultisigResult, error) {
+	var result MultisigResult
+	if err := c.post(ctx, "/multisig/transaction", txn, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateSchedule creates a time-locked or recurring transfer. For a locked
+// transfer it also returns the transaction that took the funds.
+func (c *Client) CreateSchedule(ctx context.Context, req ScheduleRequest) (*Schedule, int64, error) {
+	var resp struct {
+		Schedules   []Schedule `json:"schedules"`
+		Transaction int64      `json:"transaction"`
+	}
+	if err := c.post(ctx, "/schedule", req, &resp); err != nil {
+		return nil, 0, err
+	}
+	st, err := firstOf(resp.Schedules, "schedule")
+	return st, resp.Transaction, err
+}
+
+// Schedule returns a scheduled transfer.
+func (c *Client) Schedule(ctx context.Context, id int64) (*Schedule, error) {
+	var resp struct {
+		Schedules []Schedule `json:"schedules"`
+	}
+	if err := c.get(ctx, "/schedule/"+strconv.FormatInt(id, 10), nil, &resp); err != nil {
+		return nil, err
+	}
+	return firstOf(resp.Schedules, "schedule")
+}
+
+// CancelSchedule stops a recurring transfer. pkey must be the sender's.
+func (c *Client) CancelSchedule(ctx context.Context, id int64, pkey string) (*Schedule, error) {
+	var resp struct {
+		Schedules []Schedule `json:"schedules"`
+	}
+	if err := c.post(ctx, "/schedule/"+strconv.FormatInt(id, 10)+"/cancel", map[string]string{"pkey": pkey}, &resp); err != nil {
+		return nil, err
+	}
+	return firstOf(resp.Schedules, "schedule")
+}
+
+// AddressSchedules returns the scheduled transfers sent or received by an
+// address.
+func (c *Client) AddressSchedules(ctx context.Context, address string) ([]Schedule, error) {
+	var resp struct {
+		Schedules []Schedule `json:"schedules"`
+	}
+	err := c.get(ctx, "/schedules/"+url.PathEscape(address), nil, &resp)
+	return resp.Schedules, err
+}
+
+// CreateEscrow locks funds from the buyer. It also returns the transaction
+// that took them.
+func (c *Client) CreateEscrow(ctx context.Context, req EscrowRequest) (*Escrow, int64, error) {
+	var resp struct {
+		Escrows     []Escrow `json:"escrows"`
+		Transaction int64    `json:"transaction"`
+	}
+	if err := c.post(ctx, "/escrow", req, &resp); err != nil {
+		return nil, 0, err
+	}
+	e, err := firstOf(resp.Escrows, "escrow")
+	return e, resp.Transaction, err
+}
+
+// Escrow returns an escrow and its votes.
+func (c *Client) Escrow(ctx context.Context, id int64) (*Escrow, error) {
+	var resp struct {
+		Escrows []Escrow `json:"escrows"`
+	}
+	if err := c.get(ctx, "/escrow/"+strconv.FormatInt(id, 10), nil, &resp); err != nil {
+		return nil, err
+	}
+	return firstOf(resp.Escrows, "escrow")
+}
+
+// ApproveEscrow votes to release an escrow to the seller.
+func (c *Client) ApproveEscrow(ctx context.Context, id int64, pkey string) (*Escrow, error) {
+	return c.voteEscrow(ctx, id, "approve", pkey)
+}
+
+// RefundEscrow votes to return an escrow to the buyer.
+func (c *Client) RefundEscrow(ctx context.Context, id int64, pkey string) (*Escrow, error) {
+	return c.voteEscrow(ctx, id, "refund", pkey)
+}
+
+func (c *Client) voteEscrow(ctx context.Context, id int64, vote, pkey string) (*Escrow, error) {
+	var resp struct {
+		Escrows []Escrow `json:"escrows"`
+	}
+	if err := c.post(ctx, "/escrow/"+strconv.FormatInt(id, 10)+"/"+vote, map[string]string{"pkey": pkey}, &resp); err != nil {
+		return nil, err
+	}
+	return firstOf(resp.Escrows, "escrow")
+}
+
+// CreateAsset issues a token. It also returns the transaction crediting the
+// initial supply, or zero if there is none.
+func (c *Client) CreateAsset(ctx context.Context, req AssetRequest) (*Asset, int64, error) {
+	var resp struct {
+		Assets      []Asset `json:"assets"`
+		Transaction int64   `json:"transaction"`
+	}
+	if err := c.post(ctx, "/asset", req, &resp); err != nil {
+		return nil, 0, err
+	}
+	asset, err := firstOf(resp.Assets, "asset")
+	return asset, resp.Transaction, err
+}
+
+// MintAsset adds supply to a mintable asset. pkey must be the issuer's.
+func (c *Client) MintAsset(ctx context.Context, id string, req MintRequest) (*MintResult, error) {
+	var result MintResult
+	if err := c.post(ctx, "/asset/"+url.PathEscape(id)+"/mint", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Assets returns every issued asset.
+func (c *Client) Assets(ctx context.Context) ([]Asset, error) {
+	var resp struct {
+		Assets []Asset `json:"assets"`
+	}
+	err := c.get(ctx, "/assets", nil, &resp)
+	return resp.Assets, err
+}
+
+// firstOf returns the single item of a response that wraps it in a list.
+func firstOf[T any](items []T, name string) (*T, error) {
+	if len(items) == 0 {
+		return nil, errors.New("go-cash: empty " + name + " response")
+	}
+	return &items[0], nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// TLSOptions configures how NewHTTPClient trusts the server and identifies
+// itself. All fields are optional.
+type TLSOptions struct {
+	// CAFile adds a trusted root for the server certificate.
+	CAFile string
+	// Fingerprint pins the server's leaf certificate by its SHA-256 hash,
+	// in hex with or without colons, instead of verifying its chain.
+	Fingerprint string
+	// CertFile and KeyFile present a client certificate for mutual TLS.
+	CertFile string
+	KeyFile  string
+}
+
+// NewHTTPClient builds an http.Client for use with WithHTTPClient.
+func NewHTTPClient(opts TLSOptions) (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if opts.CAFile != "" {
+		caPEM, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.Fingerprint != "" {
+		pinned := strings.ToLower(strings.ReplaceAll(opts.Fingerprint, ":", ""))
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("server presented no certificate")
+			}
+			sum := sha256.Sum256(rawCerts[0])
+			if hex.EncodeToString(sum[:]) != pinned {
+				return errors.New("server certificate fingerprint mismatch")
+			}
+			return nil
+		}
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}, nil
+}
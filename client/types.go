@@ -0,0 +1,517 @@
+package client
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Holding is an address's balance of one asset.
+type Holding struct {
+	Asset   string `json:"asset"`
+	Balance int    `json:"balance"`
+}
+
+// Address is an account. Locked and Holdings are only filled in by
+// Client.Address; the first holding is always the native coin.
+type Address struct {
+	Address  string    `json:"address"`
+	Balance  int       `json:"balance"`
+	Locked   int       `json:"locked"`
+	Holdings []Holding `json:"holdings"`
+}
+
+// Transaction is a recorded transfer. Block is empty until a block collects
+// its fee. Block rewards have the sender "null".
+type Transaction struct {
+	ID        int64  `json:"ID"`
+	Sender    string `json:"Sender"`
+	Amount    int    `json:"Amount"`
+	Fee       int    `json:"Fee"`
+	Recipient string `json:"Recipient"`
+	Time      int64  `json:"Time,string"`
+	Block     string `json:"Block"`
+	Memo      string `json:"Memo"`
+	Asset     string `json:"Asset"`
+}
+
+// TransactionRequest sends Amount of Asset, or of the native coin if Asset
+// is empty, from the address of Pkey to Address.
+type TransactionRequest struct {
+	Pkey    string `json:"pkey"`
+	Address string `json:"address"`
+	Amount  int    `json:"amount"`
+	Fee     int    `json:"fee"`
+	Memo    string `json:"memo"`
+	Asset   string `json:"asset,omitempty"`
+}
+
+type TransactionResult struct {
+	Transaction int64  `json:"transaction"`
+	Hash        string `json:"hash"`
+	Asset       string `json:"asset"`
+	Amount      int    `json:"amount"`
+	Fee         int    `json:"fee"`
+	// Known is set when the server had already recorded the transaction.
+	Known bool `json:"known"`
+}
+
+// Block is a block. Height counts the genesis block as 0.
+type Block struct {
+	ID        int    `json:"id"`
+	Height    int    `json:"height"`
+	Block     string `json:"block"`
+	PrevBlock string `json:"prevBlock"`
+	Address   string `json:"address"`
+	Nonce     string `json:"nonce"`
+	Time      int    `json:"time"`
+}
+
+// SideBlock is a block on a side branch, with the status "side" or
+// "orphaned".
+type SideBlock struct {
+	Block
+	Status string `json:"status"`
+}
+
+// BlockDetail is a block with its status, "main", "side" or "orphaned".
+// NextBlock is the main chain block after it, or empty for side blocks and
+// the tip.
+type BlockDetail struct {
+	Block
+	Status    string `json:"status"`
+	NextBlock string `json:"nextBlock"`
+}
+
+// ChainTip describes the last main chain block. Work is the expected
+// number of hashes needed to mine the chain, each block at the difficulty
+// it was accepted at, as a decimal string.
+type ChainTip struct {
+	Height int    `json:"height"`
+	Hash   string `json:"hash"`
+	Time   int    `json:"time"`
+	Work   string `json:"work"`
+}
+
+// BlockSubmission is a mined block. Block must be the hex SHA-256 of
+// PrevBlock, Address and Nonce concatenated.
+type BlockSubmission struct {
+	Block     string `json:"block"`
+	PrevBlock string `json:"prevBlock"`
+	Address   string `json:"address"`
+	Nonce     string `json:"nonce"`
+}
+
+type BlockResult struct {
+	Reward int `json:"reward"`
+	// Branch is "main" or "side".
+	Branch string `json:"branch"`
+	// Reorg is set when the block made a side branch the main chain.
+	Reorg *Reorg `json:"reorg"`
+	// Known is set when the server already had the block.
+	Known bool `json:"known"`
+}
+
+type Reorg struct {
+	ID        int64  `json:"id"`
+	Time      int    `json:"time"`
+	ForkBlock string `json:"forkBlock"`
+	OldTip    string `json:"oldTip"`
+	NewTip    string `json:"newTip"`
+	Orphaned  int    `json:"orphaned"`
+	Adopted   int    `json:"adopted"`
+}
+
+// Supply describes the supply of the native coin or a token. PendingFees,
+// FeesPaid, Locked and Escrowed are only reported for the native coin, and
+// Mintable only for tokens.
+type Supply struct {
+	Asset             string `json:"asset"`
+	TotalSupply       int    `json:"totalSupply"`
+	CirculatingSupply int    `json:"circulatingSupply"`
+	PendingFees       int    `json:"pendingFees"`
+	FeesPaid          int    `json:"feesPaid"`
+	Locked            int    `json:"locked"`
+	Escrowed          int    `json:"escrowed"`
+	Mintable          bool   `json:"mintable"`
+}
+
+type InvoiceRequest struct {
+	Address   string `json:"address"`
+	Amount    int    `json:"amount"`
+	Memo      string `json:"memo"`
+	ExpiresIn int    `json:"expiresIn,omitempty"`
+}
+
+// Invoice is a payment request. URI is a go-cash: payment URI a wallet can
+// pay; the payment must carry the invoice ID as its memo.
+type Invoice struct {
+	ID              string `json:"id"`
+	Address         string `json:"address"`
+	Amount          int    `json:"amount"`
+	Memo            string `json:"memo"`
+	Created         int    `json:"created"`
+	Expires         int    `json:"expires"`
+	Status          string `json:"status"`
+	PaidTransaction int64  `json:"paidTransaction"`
+	PaidAt          int    `json:"paidAt"`
+	URI             string `json:"uri"`
+}
+
+type Multisig struct {
+	Address    string   `json:"address"`
+	Threshold  int      `json:"threshold"`
+	PublicKeys []string `json:"pubkeys"`
+	Nonce      int      `json:"nonce"`
+	Balance    int      `json:"balance"`
+}
+
+type MultisigSignature struct {
+	PublicKey string `json:"pubkey"`
+	Signature string `json:"signature"`
+}
+
+// MultisigTransaction is a transfer out of a multisig address. Nonce must
+// be one more than the address's current nonce.
+type MultisigTransaction struct {
+	From       string              `json:"from"`
+	Address    string              `json:"address"`
+	Amount     int                 `json:"amount"`
+	Fee        int                 `json:"fee"`
+	Memo       string              `json:"memo"`
+	Nonce      int                 `json:"nonce"`
+	Signatures []MultisigSignature `json:"signatures"`
+}
+
+// SigningMessage returns the bytes each co-signer signs.
+func (t *MultisigTransaction) SigningMessage() []byte {
+	return []byte(strings.Join([]string{
+		"go-cash-multisig",
+		t.From,
+		t.Address,
+		strconv.Itoa(t.Amount),
+		strconv.Itoa(t.Fee),
+		t.Memo,
+		strconv.Itoa(t.Nonce),
+	}, "\n"))
+}
+
+// Sign adds the signature of key, replacing any earlier signature by the
+// same key.
+func (t *MultisigTransaction) Sign(key ed25519.PrivateKey) {
+	pub := hex.EncodeToString(key.Public().(ed25519.PublicKey))
+	sig := MultisigSignature{PublicKey: pub, Signature: hex.EncodeToString(ed25519.Sign(key, t.SigningMessage()))}
+
+	signatures := []MultisigSignature{sig}
+	for _, existing := range t.Signatures {
+		if !strings.EqualFold(existing.PublicKey, pub) {
+			signatures = append(signatures, existing)
+		}
+	}
+	t.Signatures = signatures
+}
+
+type MultisigResult struct {
+	Transaction int64 `json:"transaction"`
+	Amount      int   `json:"amount"`
+	Fee         int   `json:"fee"`
+}
+
+// ScheduleRequest creates a transfer locked until UnlockTime and/or
+// UnlockHeight, or, with EverySeconds or EveryBlocks, a recurring one.
+type ScheduleRequest struct {
+	Pkey         string `json:"pkey"`
+	Address      string `json:"address"`
+	Amount       int    `json:"amount"`
+	Fee          int    `json:"fee"`
+	Memo         string `json:"memo"`
+	UnlockTime   int    `json:"unlockTime,omitempty"`
+	UnlockHeight int    `json:"unlockHeight,omitempty"`
+	EverySeconds int    `json:"everySeconds,omitempty"`
+	EveryBlocks  int    `json:"everyBlocks,omitempty"`
+}
+
+type Schedule struct {
+	ID           int64  `json:"id"`
+	Kind         string `json:"kind"`
+	Sender       string `json:"sender"`
+	Recipient    string `json:"recipient"`
+	Amount       int    `json:"amount"`
+	Fee          int    `json:"fee"`
+	Memo         string `json:"memo"`
+	UnlockTime   int    `json:"unlockTime"`
+	UnlockHeight int    `json:"unlockHeight"`
+	EverySeconds int    `json:"everySeconds"`
+	EveryBlocks  int    `json:"everyBlocks"`
+	Status       string `json:"status"`
+	Payments     int    `json:"payments"`
+	Missed       int    `json:"missed"`
+	Created      int    `json:"created"`
+}
+
+// EscrowRequest locks Amount from the buyer identified by Pkey until two of
+// the buyer, seller and arbiter agree to release or refund it.
+type EscrowRequest struct {
+	Pkey      string `json:"pkey"`
+	Seller    string `json:"seller"`
+	Arbiter   string `json:"arbiter"`
+	Amount    int    `json:"amount"`
+	Fee       int    `json:"fee"`
+	Memo      string `json:"memo"`
+	ExpiresIn int    `json:"expiresIn,omitempty"`
+}
+
+type EscrowVotes struct {
+	Buyer   string `json:"buyer"`
+	Seller  string `json:"seller"`
+	Arbiter string `json:"arbiter"`
+}
+
+type Escrow struct {
+	ID                 int64       `json:"id"`
+	Buyer              string      `json:"buyer"`
+	Seller             string      `json:"seller"`
+	Arbiter            string      `json:"arbiter"`
+	Amount             int         `json:"amount"`
+	Fee                int         `json:"fee"`
+	Memo               string      `json:"memo"`
+	Votes              EscrowVotes `json:"votes"`
+	Status             string      `json:"status"`
+	Created            int         `json:"created"`
+	Expires            int         `json:"expires"`
+	SettledTransaction int64       `json:"settledTransaction"`
+	SettledAt          int         `json:"settledAt"`
+}
+
+type AssetRequest struct {
+	Pkey     string `json:"pkey"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Supply   int    `json:"supply"`
+	Mintable bool   `json:"mintable"`
+}
+
+type Asset struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Issuer   string `json:"issuer"`
+	Supply   int    `json:"supply"`
+	Mintable bool   `json:"mintable"`
+	Created  int    `json:"created"`
+}
+
+// MintRequest mints Amount of an asset to Address, or to the issuer if
+// Address is empty.
+type MintRequest struct {
+	Pkey    string `json:"pkey"`
+	Address string `json:"address,omitempty"`
+	Amount  int    `json:"amount"`
+}
+
+type MintResult struct {
+	Transaction int64  `json:"transaction"`
+	Asset       string `json:"asset"`
+	Amount      int    `json:"amount"`
+}
+
+// PeerEntry is a ledger change replicated between nodes. Type is
+// "transaction", "block", "frozen", "unfrozen", "asset", "multisig",
+// "escrow" or "schedule", and the matching field is set.
+type PeerEntry struct {
+	Cursor      int64            `json:"cursor"`
+	Type        string           `json:"type"`
+	Time        int              `json:"time"`
+	Transaction *PeerTransaction `json:"transaction"`
+	Block       *PeerBlock       `json:"block"`
+	Address     string           `json:"address"`
+	Asset       *PeerAsset       `json:"asset"`
+	Multisig    *PeerMultisig    `json:"multisig"`
+	Escrow      *PeerEscrow      `json:"escrow"`
+	Schedule    *PeerSchedule    `json:"schedule"`
+}
+
+// PeerTransaction is a ledger row as recorded by the node that accepted it.
+type PeerTransaction struct {
+	Hash      string `json:"hash"`
+	Sender    string `json:"sender"`
+	Recipient string `json:"recipient"`
+	Asset     string `json:"asset"`
+	Amount    int    `json:"amount"`
+	Fee       int    `json:"fee"`
+	Memo      string `json:"memo"`
+	Time      int    `json:"time"`
+}
+
+// PeerBlock is a block with the time it was first accepted and the hashes
+// of the transactions whose fees it collects.
+type PeerBlock struct {
+	BlockSubmission
+	Time int      `json:"time"`
+	Fees []string `json:"fees"`
+}
+
+type PeerAsset struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Issuer   string `json:"issuer"`
+	Mintable bool   `json:"mintable"`
+	Created  int    `json:"created"`
+}
+
+type PeerMultisig struct {
+	Address    string   `json:"address"`
+	Threshold  int      `json:"threshold"`
+	PublicKeys []string `json:"pubkeys"`
+	Nonce      int      `json:"nonce"`
+}
+
+// PeerEscrow is an escrow with every vote and when it was cast. Funding and
+// Settlement are the hashes of the transactions that moved its funds.
+type PeerEscrow struct {
+	ID           int64  `json:"id"`
+	Buyer        string `json:"buyer"`
+	Seller       string `json:"seller"`
+	Arbiter      string `json:"arbiter"`
+	Amount       int    `json:"amount"`
+	Fee          int    `json:"fee"`
+	Memo         string `json:"memo"`
+	BuyerVote    string `json:"buyerVote"`
+	SellerVote   string `json:"sellerVote"`
+	ArbiterVote  string `json:"arbiterVote"`
+	BuyerVoted   int    `json:"buyerVoted"`
+	SellerVoted  int    `json:"sellerVoted"`
+	ArbiterVoted int    `json:"arbiterVoted"`
+	Status       string `json:"status"`
+	Created      int    `json:"created"`
+	Expires      int    `json:"expires"`
+	Funding      string `json:"funding"`
+	Settlement   string `json:"settlement"`
+	SettledAt    int    `json:"settledAt"`
+}
+
+// PeerSchedule is a scheduled transfer. Runs counts its releases and
+// payments, including missed ones.
+type PeerSchedule struct {
+	ID           int64  `json:"id"`
+	Kind         string `json:"kind"`
+	Sender       string `json:"sender"`
+	Recipient    string `json:"recipient"`
+	Amount       int    `json:"amount"`
+	Fee          int    `json:"fee"`
+	Memo         string `json:"memo"`
+	UnlockTime   int    `json:"unlockTime"`
+	UnlockHeight int    `json:"unlockHeight"`
+	EverySeconds int    `json:"everySeconds"`
+	EveryBlocks  int    `json:"everyBlocks"`
+	Status       string `json:"status"`
+	Payments     int    `json:"payments"`
+	Missed       int    `json:"missed"`
+	Runs         int    `json:"runs"`
+	Funding      string `json:"funding"`
+	Created      int    `json:"created"`
+}
+
+// PeerEntriesPage is one page of the peer feed. Genesis is the hash of the
+// server's genesis block.
+type PeerEntriesPage struct {
+	Entries []PeerEntry `json:"entries"`
+	Cursor  int64       `json:"cursor"`
+	More    bool        `json:"more"`
+	Genesis string      `json:"genesis"`
+}
+
+// Change is an entry in the ledger change feed. Block and Transaction hold
+// the current state of the referenced block or transaction, if it still
+// exists.
+type Change struct {
+	Cursor        int64        `json:"cursor"`
+	Type          string       `json:"type"`
+	Time          int          `json:"time"`
+	BlockHash     string       `json:"blockHash"`
+	Block         *Block       `json:"block"`
+	TransactionID int64        `json:"transactionId"`
+	Transaction   *Transaction `json:"transaction"`
+	Address       string       `json:"address"`
+	Asset         string       `json:"asset"`
+	EscrowID      int64        `json:"escrowId"`
+	ScheduleID    int64        `json:"scheduleId"`
+}
+
+// ChangesPage is one page of the change feed. Pass Cursor as since to get
+// the next page; More is set if it is already waiting.
+type ChangesPage struct {
+	Changes []Change `json:"changes"`
+	Cursor  int64    `json:"cursor"`
+	More    bool     `json:"more"`
+}
+
+type ReplicaInfo struct {
+	Primary    string `json:"primary"`
+	Synced     bool   `json:"synced"`
+	LastSync   int64  `json:"lastSync"`
+	LagSeconds int    `json:"lagSeconds"`
+	Error      string `json:"error"`
+}
+
+type Info struct {
+	Version     string `json:"version"`
+	Commit      string `json:"commit"`
+	Uptime      int    `json:"uptime"`
+	Height      int    `json:"height"`
+	Tip         string `json:"tip"`
+	Genesis     string `json:"genesis"`
+	Difficulty  int    `json:"difficulty"`
+	BlockReward int    `json:"blockReward"`
+	MinFee      int    `json:"minFee"`
+	FeePerByte  int    `json:"feePerByte"`
+	// Replica is set when the server is a read-only replica.
+	Replica *ReplicaInfo `json:"replica"`
+}
+
+// Readiness is the result of the server's readiness checks, keyed by check
+// name with "ok" or the reason it failed.
+type Readiness struct {
+	Ready  bool              `json:"ok"`
+	Checks map[string]string `json:"checks"`
+}
+
+type AuditEntry struct {
+	ID        int    `json:"id"`
+	Time      int    `json:"time"`
+	Actor     string `json:"actor"`
+	Role      string `json:"role"`
+	Action    string `json:"action"`
+	Target    string `json:"target"`
+	Details   string `json:"details"`
+	RequestID string `json:"requestId"`
+}
+
+// PeerConflict is an entry from a peer's feed that the server refused, such
+// as a transaction spending funds already spent on the server. Entry is the
+// entry as the peer sent it.
+type PeerConflict struct {
+	ID     int             `json:"id"`
+	Peer   string          `json:"peer"`
+	Cursor int64           `json:"cursor"`
+	Type   string          `json:"type"`
+	Entry  json.RawMessage `json:"entry"`
+	Reason string          `json:"reason"`
+	Time   int             `json:"time"`
+}
+
+// AdjustmentRequest mints a positive Amount to, or burns a negative Amount
+// from, Address.
+type AdjustmentRequest struct {
+	Address string `json:"address"`
+	Amount  int    `json:"amount"`
+	Reason  string `json:"reason"`
+}
+
+type VerificationReport struct {
+	OK       bool     `json:"ok"`
+	Blocks   int      `json:"blocks"`
+	Accounts int      `json:"accounts"`
+	Problems []string `json:"problems"`
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var serverConfig *Config
+
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+type TLSConfig struct {
+	Enabled           bool     `json:"enabled" yaml:"enabled" toml:"enabled"`
+	CertFile          string   `json:"certFile" yaml:"certFile" toml:"certFile"`
+	KeyFile           string   `json:"keyFile" yaml:"keyFile" toml:"keyFile"`
+	AutoGenerate      bool     `json:"autoGenerate" yaml:"autoGenerate" toml:"autoGenerate"`
+	CACertFile        string   `json:"caCertFile" yaml:"caCertFile" toml:"caCertFile"`
+	CAKeyFile         string   `json:"caKeyFile" yaml:"caKeyFile" toml:"caKeyFile"`
+	Hosts             []string `json:"hosts" yaml:"hosts" toml:"hosts"`
+	ClientCAFile      string   `json:"clientCAFile" yaml:"clientCAFile" toml:"clientCAFile"`
+	RequireMinerCerts bool     `json:"requireMinerCerts" yaml:"requireMinerCerts" toml:"requireMinerCerts"`
+}
+
+// PeerConfig lists the peers whose entry feeds this node follows. It
+// identifies itself to them with the client certificate in CertFile and
+// KeyFile, and retries a failed peer every SyncInterval. Entries timed more
+// than MaxClockSkew ahead of this node's clock are not applied yet.
+type PeerConfig struct {
+	Peers        []string `json:"peers" yaml:"peers" toml:"peers"`
+	SyncInterval Duration `json:"syncInterval" yaml:"syncInterval" toml:"syncInterval"`
+	CertFile     string   `json:"certFile" yaml:"certFile" toml:"certFile"`
+	KeyFile      string   `json:"keyFile" yaml:"keyFile" toml:"keyFile"`
+	MaxClockSkew Duration `json:"maxClockSkew" yaml:"maxClockSkew" toml:"maxClockSkew"`
+}
+
+// ReplicaConfig makes the server a read-only replica of Primary, which
+// follows the primary's entry feed and retries every SyncInterval after an
+// error. It identifies itself to the primary with the client certificate in
+// CertFile and KeyFile.
+type ReplicaConfig struct {
+	Primary      string   `json:"primary" yaml:"primary" toml:"primary"`
+	SyncInterval Duration `json:"syncInterval" yaml:"syncInterval" toml:"syncInterval"`
+	CertFile     string   `json:"certFile" yaml:"certFile" toml:"certFile"`
+	KeyFile      string   `json:"keyFile" yaml:"keyFile" toml:"keyFile"`
+}
+
+type RewardConfig struct {
+	BlockReward int `json:"blockReward" yaml:"blockReward" toml:"blockReward"`
+}
+
+type FeeConfig struct {
+	MinFee  int `json:"minFee" yaml:"minFee" toml:"minFee"`
+	PerByte int `json:"perByte" yaml:"perByte" toml:"perByte"`
+}
+
+type RateLimitRule struct {
+	RequestsPerMinute int `json:"requestsPerMinute" yaml:"requestsPerMinute" toml:"requestsPerMinute"`
+	Burst             int `json:"burst" yaml:"burst" toml:"burst"`
+}
+
+type RateLimitConfig struct {
+	Enabled           bool                     `json:"enabled" yaml:"enabled" toml:"enabled"`
+	RequestsPerMinute int                      `json:"requestsPerMinute" yaml:"requestsPerMinute" toml:"requestsPerMinute"`
+	Burst             int                      `json:"burst" yaml:"burst" toml:"burst"`
+	Routes            map[string]RateLimitRule `json:"routes" yaml:"routes" toml:"routes"`
+	BanThreshold      int                      `json:"banThreshold" yaml:"banThreshold" toml:"banThreshold"`
+	BanWindow         Duration                 `json:"banWindow" yaml:"banWindow" toml:"banWindow"`
+	BanDuration       Duration                 `json:"banDuration" yaml:"banDuration" toml:"banDuration"`
+}
+
+type AdminToken struct {
+	Name  string `json:"name" yaml:"name" toml:"name"`
+	Token string `json:"token" yaml:"token" toml:"token"`
+	Role  string `json:"role" yaml:"role" toml:"role"`
+}
+
+type AdminConfig struct {
+	// Token is a shorthand for a single token with the admin role.
+	Token  string       `json:"token" yaml:"token" toml:"token"`
+	Tokens []AdminToken `json:"tokens" yaml:"tokens" toml:"tokens"`
+}
+
+type Config struct {
+	Listen            string          `json:"listen" yaml:"listen" toml:"listen"`
+	GRPCListen        string          `json:"grpcListen" yaml:"grpcListen" toml:"grpcListen"`
+	Database          string          `json:"database" yaml:"database" toml:"database"`
+	TLS               TLSConfig       `json:"tls" yaml:"tls" toml:"tls"`
+	ReadTimeout       Duration        `json:"readTimeout" yaml:"readTimeout" toml:"readTimeout"`
+	ReadHeaderTimeout Duration        `json:"readHeaderTimeout" yaml:"readHeaderTimeout" toml:"readHeaderTimeout"`
+	WriteTimeout      Duration        `json:"writeTimeout" yaml:"writeTimeout" toml:"writeTimeout"`
+	IdleTimeout       Duration        `json:"idleTimeout" yaml:"idleTimeout" toml:"idleTimeout"`
+	ShutdownTimeout   Duration        `json:"shutdownTimeout" yaml:"shutdownTimeout" toml:"shutdownTimeout"`
+	MaxBodyBytes      int64           `json:"maxBodyBytes" yaml:"maxBodyBytes" toml:"maxBodyBytes"`
+	SchedulerInterval Duration        `json:"schedulerInterval" yaml:"schedulerInterval" toml:"schedulerInterval"`
+	Reward            RewardConfig    `json:"reward" yaml:"reward" toml:"reward"`
+	Difficulty        int             `json:"difficulty" yaml:"difficulty" toml:"difficulty"`
+	Fees              FeeConfig       `json:"fees" yaml:"fees" toml:"fees"`
+	RateLimit         RateLimitConfig `json:"rateLimit" yaml:"rateLimit" toml:"rateLimit"`
+	LogLevel          string          `json:"logLevel" yaml:"logLevel" toml:"logLevel"`
+	LogFormat         string          `json:"logFormat" yaml:"logFormat" toml:"logFormat"`
+	Admin             AdminConfig     `json:"admin" yaml:"admin" toml:"admin"`
+	P2P               PeerConfig      `json:"p2p" yaml:"p2p" toml:"p2p"`
+	Replica           ReplicaConfig   `json:"replica" yaml:"replica" toml:"replica"`
+
+	overwrite bool
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Listen: ":8080",
+		TLS: TLSConfig{
+			CertFile:   "server.crt",
+			KeyFile:    "server.key",
+			CACertFile: "ca.crt",
+			CAKeyFile:  "ca.key",
+			Hosts:      []string{"localhost", "127.0.0.1", "::1"},
+		},
+		ReadTimeout:       Duration{10 * time.Second},
+		ReadHeaderTimeout: Duration{5 * time.Second},
+		WriteTimeout:      Duration{10 * time.Second},
+		IdleTimeout:       Duration{60 * time.Second},
+		ShutdownTimeout:   Duration{15 * time.Second},
+		MaxBodyBytes:      1 << 20,
+		SchedulerInterval: Duration{time.Second},
+		Reward:            RewardConfig{BlockReward: 1},
+		RateLimit: RateLimitConfig{
+			RequestsPerMinute: 60,
+			Burst:             10,
+			BanThreshold:      5,
+			BanWindow:         Duration{10 * time.Minute},
+			BanDuration:       Duration{time.Hour},
+		},
+		LogLevel:  "info",
+		LogFormat: "text",
+		P2P:       PeerConfig{SyncInterval: Duration{30 * time.Second}, MaxClockSkew: Duration{2 * time.Minute}},
+		Replica:   ReplicaConfig{SyncInterval: Duration{time.Minute}},
+	}
+}
+
+// loadConfig builds the effective configuration. Values are applied in order
+// of increasing precedence: defaults, config file, GC_* environment
+// variables, then command-line flags.
+func loadConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("gc-server", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("GC_CONFIG"), "Path to a JSON, YAML or TOML config file")
+	fs.BoolVar(&cfg.overwrite, "o", false, "Overwrite the database")
+	dbLocation := fs.String("db", "", "Path to the database file")
+	listen := fs.String("listen", "", "Address to listen on")
+	grpcListen := fs.String("grpc-listen", "", "Address to serve the gRPC API on, disabled if empty")
+	logLevel := fs.String("log-level", "", "Logging level (debug, info, warn, error)")
+	logFormat := fs.String("log-format", "", "Log output format (text, json)")
+	peers := fs.String("peers", "", "Comma separated URLs of peer nodes")
+	follow := fs.String("follow", "", "URL of a primary node to mirror as a read-only replica")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "db":
+			cfg.Database = *dbLocation
+		case "listen":
+			cfg.Listen = *listen
+		case "grpc-listen":
+			cfg.GRPCListen = *grpcListen
+		case "log-level":
+			cfg.LogLevel = *logLevel
+		case "log-format":
+			cfg.LogFormat = *logFormat
+		case "peers":
+			cfg.P2P.Peers = strings.Split(*peers, ",")
+		case "follow":
+			cfg.Replica.Primary = *follow
+		}
+	})
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, c)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("unsupported config file type %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	var errs []error
+
+	setString := func(name string, dst *string) {
+		if v, ok := os.LookupEnv(name); ok {
+			*dst = v
+		}
+	}
+	setBool := func(name string, dst *bool) {
+		if v, ok := os.LookupEnv(name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			*dst = b
+		}
+	}
+	setInt := func(name string, dst *int) {
+		if v, ok := os.LookupEnv(name); ok {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			*dst = i
+		}
+	}
+	setInt64 := func(name string, dst *int64) {
+		if v, ok := os.LookupEnv(name); ok {
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			*dst = i
+		}
+	}
+	setDuration := func(name string, dst *Duration) {
+		if v, ok := os.LookupEnv(name); ok {
+			if err := dst.UnmarshalText([]byte(v)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	}
+
+	setString("GC_LISTEN", &c.Listen)
+	setString("GC_GRPC_LISTEN", &c.GRPCListen)
+	setString("GC_DB", &c.Database)
+	setBool("GC_TLS_ENABLED", &c.TLS.Enabled)
+	setString("GC_TLS_CERT_FILE", &c.TLS.CertFile)
+	setString("GC_TLS_KEY_FILE", &c.TLS.KeyFile)
+	setBool("GC_TLS_AUTO_GENERATE", &c.TLS.AutoGenerate)
+	setString("GC_TLS_CA_CERT_FILE", &c.TLS.CACertFile)
+	setString("GC_TLS_CA_KEY_FILE", &c.TLS.CAKeyFile)
+	if v, ok := os.LookupEnv("GC_TLS_HOSTS"); ok {
+		c.TLS.Hosts = strings.Split(v, ",")
+	}
+	setString("GC_TLS_CLIENT_CA_FILE", &c.TLS.ClientCAFile)
+	setBool("GC_TLS_REQUIRE_MINER_CERTS", &c.TLS.RequireMinerCerts)
+	setDuration("GC_READ_TIMEOUT", &c.ReadTimeout)
+	setDuration("GC_READ_HEADER_TIMEOUT", &c.ReadHeaderTimeout)
+	setDuration("GC_WRITE_TIMEOUT", &c.WriteTimeout)
+	setDuration("GC_IDLE_TIMEOUT", &c.IdleTimeout)
+	setDuration("GC_SHUTDOWN_TIMEOUT", &c.ShutdownTimeout)
+	setDuration("GC_SCHEDULER_INTERVAL", &c.SchedulerInterval)
+	setInt64("GC_MAX_BODY_BYTES", &c.MaxBodyBytes)
+	setInt("GC_BLOCK_REWARD", &c.Reward.BlockReward)
+	setInt("GC_DIFFICULTY", &c.Difficulty)
+	setInt("GC_MIN_FEE", &c.Fees.MinFee)
+	setInt("GC_FEE_PER_BYTE", &c.Fees.PerByte)
+	setBool("GC_RATE_LIMIT_ENABLED", &c.RateLimit.Enabled)
+	setInt("GC_RATE_LIMIT_RPM", &c.RateLimit.RequestsPerMinute)
+	setInt("GC_RATE_LIMIT_BURST", &c.RateLimit.Burst)
+	setInt("GC_BAN_THRESHOLD", &c.RateLimit.BanThreshold)
+	setDuration("GC_BAN_WINDOW", &c.RateLimit.BanWindow)
+	setDuration("GC_BAN_DURATION", &c.RateLimit.BanDuration)
+	setString("GC_LOG_LEVEL", &c.LogLevel)
+	setString("GC_LOG_FORMAT", &c.LogFormat)
+	setString("GC_ADMIN_TOKEN", &c.Admin.Token)
+	if v, ok := os.LookupEnv("GC_PEERS"); ok {
+		c.P2P.Peers = strings.Split(v, ",")
+	}
+	setDuration("GC_PEER_SYNC_INTERVAL", &c.P2P.SyncInterval)
+	setString("GC_PEER_CERT_FILE", &c.P2P.CertFile)
+	setString("GC_PEER_KEY_FILE", &c.P2P.KeyFile)
+	setDuration("GC_PEER_MAX_CLOCK_SKEW", &c.P2P.MaxClockSkew)
+	setString("GC_FOLLOW", &c.Replica.Primary)
+	setDuration("GC_REPLICA_SYNC_INTERVAL", &c.Replica.SyncInterval)
+	setString("GC_REPLICA_CERT_FILE", &c.Replica.CertFile)
+	setString("GC_REPLICA_KEY_FILE", &c.Replica.KeyFile)
+
+	return errors.Join(errs...)
+}
+
+func (c *Config) validate() error {
+	var errs []error
+
+	if c.Database == "" {
+		errs = append(errs, errors.New("database file name must be specified using the -db flag, GC_DB or the config file"))
+	}
+	if c.Listen == "" {
+		errs = append(errs, errors.New("listen address must not be empty"))
+	}
+	if c.GRPCListen != "" && c.GRPCListen == c.Listen {
+		errs = append(errs, errors.New("grpcListen must differ from listen"))
+	}
+	if c.TLS.Enabled && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls.certFile and tls.keyFile are required when TLS is enabled"))
+	}
+	if c.TLS.AutoGenerate && (c.TLS.CACertFile == "" || c.TLS.CAKeyFile == "") {
+		errs = append(errs, errors.New("tls.caCertFile and tls.caKeyFile are required when tls.autoGenerate is set"))
+	}
+	if c.TLS.RequireMinerCerts && (!c.TLS.Enabled || c.TLS.ClientCAFile == "") {
+		errs = append(errs, errors.New("tls.requireMinerCerts needs TLS enabled and tls.clientCAFile set"))
+	}
+	if c.ReadTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("readTimeout must be positive"))
+	}
+	if c.ReadHeaderTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("readHeaderTimeout must be positive"))
+	}
+	if c.WriteTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("writeTimeout must be positive"))
+	}
+	if c.IdleTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("idleTimeout must be positive"))
+	}
+	if c.ShutdownTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("shutdownTimeout must be positive"))
+	}
+	if c.MaxBodyBytes <= 0 {
+		errs = append(errs, errors.New("maxBodyBytes must be positive"))
+	}
+	if c.SchedulerInterval.Duration <= 0 {
+		errs = append(errs, errors.New("schedulerInterval must be positive"))
+	}
+	if c.Reward.BlockReward < 0 {
+		errs = append(errs, errors.New("reward.blockReward must not be negative"))
+	}
+	if c.Difficulty < 0 || c.Difficulty > 64 {
+		errs = append(errs, errors.New("difficulty must be between 0 and 64"))
+	}
+	if c.Fees.MinFee < 0 || c.Fees.PerByte < 0 {
+		errs = append(errs, errors.New("fees.minFee and fees.perByte must not be negative"))
+	}
+	if c.RateLimit.Enabled && (c.RateLimit.RequestsPerMinute <= 0 || c.RateLimit.Burst <= 0) {
+		errs = append(errs, errors.New("rateLimit.requestsPerMinute and rateLimit.burst must be positive when rate limiting is enabled"))
+	}
+	for route, rule := range c.RateLimit.Routes {
+		if rule.RequestsPerMinute < 0 || rule.Burst <= 0 {
+			errs = append(errs, fmt.Errorf("rateLimit.routes[%q]: requestsPerMinute must not be negative and burst must be positive", route))
+		}
+	}
+	if c.RateLimit.BanThreshold > 0 && (c.RateLimit.BanWindow.Duration <= 0 || c.RateLimit.BanDuration.Duration <= 0) {
+		errs = append(errs, errors.New("rateLimit.banWindow and rateLimit.banDuration must be positive when rateLimit.banThreshold is set"))
+	}
+	for i, t := range c.Admin.Tokens {
+		if t.Token == "" {
+			errs = append(errs, fmt.Errorf("admin.tokens[%d]: token must not be empty", i))
+		}
+		if _, ok := roleLevels[t.Role]; !ok {
+			errs = append(errs, fmt.Errorf("admin.tokens[%d]: invalid role %q, must be viewer, operator or admin", i, t.Role))
+		}
+	}
+	for i, peer := range c.P2P.Peers {
+		if u, err := url.Parse(peer); err != nil || u.Scheme != "https" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("p2p.peers[%d]: %q is not an https URL", i, peer))
+		}
+	}
+	if len(c.P2P.Peers) > 0 && (c.P2P.CertFile == "" || c.P2P.KeyFile == "") {
+		errs = append(errs, errors.New("p2p.certFile and p2p.keyFile are required to connect to peers"))
+	}
+	if c.P2P.SyncInterval.Duration <= 0 {
+		errs = append(errs, errors.New("p2p.syncInterval must be positive"))
+	}
+	if c.P2P.MaxClockSkew.Duration < 0 {
+		errs = append(errs, errors.New("p2p.maxClockSkew must not be negative"))
+	}
+	if c.Replica.Primary != "" {
+		if u, err := url.Parse(c.Replica.Primary); err != nil || u.Scheme != "https" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("replica.primary: %q is not an https URL", c.Replica.Primary))
+		}
+		if c.Replica.CertFile == "" || c.Replica.KeyFile == "" {
+			errs = append(errs, errors.New("replica.certFile and replica.keyFile are required to follow a primary"))
+		}
+		if len(c.P2P.Peers) > 0 {
+			errs = append(errs, errors.New("a replica cannot also have p2p.peers"))
+		}
+	}
+	if c.Replica.SyncInterval.Duration <= 0 {
+		errs = append(errs, errors.New("replica.syncInterval must be positive"))
+	}
+	if _, err := parseLogLevel(c.LogLevel); err != nil {
+		errs = append(errs, err)
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("invalid logFormat %q, must be text or json", c.LogFormat))
+	}
+
+	return errors.Join(errs...)
+}
+
+func parseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("invalid logLevel %q", level)
+	}
+	return l, nil
+}
+
+// redacted returns a copy of the configuration with secrets removed.
+func (c *Config) redacted() Config {
+	redacted := *c
+	if redacted.Admin.Token != "" {
+		redacted.Admin.Token = "<redacted>"
+	}
+
+	redacted.Admin.Tokens = make([]AdminToken, len(c.Admin.Tokens))
+	for i, t := range c.Admin.Tokens {
+		t.Token = "<redacted>"
+		redacted.Admin.Tokens[i] = t
+	}
+
+	return redacted
+}
+
+// print writes the effective configuration as JSON with secrets redacted.
+func (c *Config) print(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c.redacted())
+}
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log"
+	"math/big"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -11,29 +18,62 @@ import (
 
 var sqliteDatabase *sql.DB
 
+// ledgerMu serializes writes that read and then update balances.
+var ledgerMu sync.Mutex
+
+// dbtx is implemented by both *sql.DB and *sql.Tx so query helpers can run
+// inside or outside a database transaction.
+type dbtx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 type Address struct {
 	ID      int
 	Address string
 	Balance int
 }
 
+// Transaction is a row of the transactions table. Time is in Unix seconds
+// and is encoded as a JSON string, as the original API always returned it.
 type Transaction struct {
 	ID        int
 	Sender    string
 	Amount    int
+	Fee       int
 	Recipient string
-	Time      string
+	Time      int `json:",string"`
+	Block     string
+	Memo      string
+	Asset     string
 }
 
+// Block is a block on the main chain or a side branch. Height counts the
+// genesis block as 0, while ID is only the row's insertion order.
 type Block struct {
 	ID           int    `json:"id"`
+	Height       int    `json:"height"`
 	BlockContent string `json:"block"`
 	PrevBlock    string `json:"prevBlock"`
 	Address      string `json:"address"`
 	Nonce        string `json:"nonce"`
 	Time         int    `json:"time"`
+	// Fees lists the hashes of the transactions whose fees the block
+	// collects. Every node that connects the block collects the same ones.
+	Fees []string `json:"-"`
+	// Difficulty is the difficulty the block was checked against when it
+	// was accepted. Blocks accepted before it was recorded have
+	// unknownDifficulty: a hash can have more leading zeros than were
+	// required, so it doesn't tell what the difficulty was.
+	Difficulty int `json:"-"`
 }
 
+// unknownDifficulty marks blocks whose difficulty wasn't recorded. Their
+// work is counted at the configured difficulty.
+const unknownDifficulty = -1
+
 func initDatabase(databaseName string) {
 	err := os.Remove(databaseName)
 	if err != nil && !os.IsNotExist(err) {
@@ -61,12 +101,38 @@ func initDatabase(databaseName string) {
 	log.Println("Database initialization done.")
 }
 
+// withLedgerTx runs fn in a database transaction while holding ledgerMu,
+// committing if fn returns nil and rolling back otherwise.
+func withLedgerTx(fn func(tx *sql.Tx) error) error {
+	ledgerMu.Lock()
+	defer ledgerMu.Unlock()
+
+	tx, err := sqliteDatabase.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	notifyChanges()
+	return nil
+}
+
 func loadDatabase(databaseName string) {
 	var err error
 	sqliteDatabase, err = sql.Open("sqlite3", databaseName)
 	if err != nil {
 		log.Fatal("Error opening database:", err)
 	}
+
+	migrateDatabase(sqliteDatabase)
 }
 
 func createAddressesTable(db *sql.DB) {
@@ -135,7 +201,9 @@ func createBlocksTable(db *sql.DB) {
 	log.Println("Created genesis block")
 }
 
-func insertAddress(db *sql.DB, address string, balance int) {
+func insertAddress(db dbtx, address string, balance int) {
+	defer observeQuery("insertAddress", time.Now())
+
 	insertSQL := `INSERT INTO addresses(address, balance) VALUES (?, ?)`
 	statement, err := db.Prepare(insertSQL)
 
@@ -146,9 +214,13 @@ func insertAddress(db *sql.DB, address string, balance int) {
 	if err != nil {
 		log.Fatalln(err.Error())
 	}
+
+	insertChange(db, "address", int(time.Now().Unix()), "", 0, address)
 }
 
-func queryAddress(db *sql.DB, address string) int {
+func queryAddress(db dbtx, address string) int {
+	defer observeQuery("queryAddress", time.Now())
+
 	querySQL := "SELECT id, address, balance FROM addresses WHERE address = ?"
 	row := db.QueryRow(querySQL, address)
 
@@ -166,7 +238,9 @@ func queryAddress(db *sql.DB, address string) int {
 	return balance
 }
 
-func queryAddresses(db *sql.DB) ([]Address, error) {
+func queryAddresses(db dbtx) ([]Address, error) {
+	defer observeQuery("queryAddresses", time.Now())
+
 	querySQL := "SELECT id, address, balance FROM addresses"
 	rows, err := db.Query(querySQL)
 	if err != nil {
@@ -191,7 +265,23 @@ func queryAddresses(db *sql.DB) ([]Address, error) {
 	return addresses, nil
 }
 
-func updateAddress(db *sql.DB, address string, newBalance int) {
+func queryAddressCount(db dbtx) (int, error) {
+	defer observeQuery("queryAddressCount", time.Now())
+
+	querySQL := "SELECT COUNT(*) FROM addresses"
+	var count int
+
+	err := db.QueryRow(querySQL).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func updateAddress(db dbtx, address string, newBalance int) {
+	defer observeQuery("updateAddress", time.Now())
+
 	updateSQL := `UPDATE addresses SET balance = ? WHERE address = ?`
 	statement, err := db.Prepare(updateSQL)
 
@@ -205,25 +295,65 @@ func updateAddress(db *sql.DB, address string, newBalance int) {
 	}
 }
 
-func insertTransaction(db *sql.DB, sender string, amount int, recipient string, time int) {
-	insertSQL := `INSERT INTO transactions(sender, amount, recipient, time) VALUES (?, ?, ?, ?)`
+// creditAddress adds amount (which may be negative) to an address's balance,
+// creating the address if it doesn't exist yet.
+func creditAddress(db dbtx, address string, amount int) {
+	defer observeQuery("creditAddress", time.Now())
+
+	result, err := db.Exec(`UPDATE addresses SET balance = balance + ? WHERE address = ?`, amount, address)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	if n, _ := result.RowsAffected(); n > 0 {
+		return
+	}
+
+	insertAddress(db, address, amount)
+}
+
+func insertTransaction(db dbtx, sender string, amount int, fee int, recipient string, memo string, timestamp int) int64 {
+	return insertAssetTransaction(db, sender, nativeAsset, amount, fee, recipient, memo, timestamp)
+}
+
+// insertAssetTransaction records a transfer of amount units of asset. The
+// fee is always in the native coin. The transaction gets a new random hash
+// that identifies it on peers.
+func insertAssetTransaction(db dbtx, sender string, asset string, amount int, fee int, recipient string, memo string, timestamp int) int64 {
+	return insertHashedTransaction(db, newTransactionHash(), sender, asset, amount, fee, recipient, memo, timestamp)
+}
+
+func insertHashedTransaction(db dbtx, hash string, sender string, asset string, amount int, fee int, recipient string, memo string, timestamp int) int64 {
+	defer observeQuery("insertTransaction", time.Now())
+
+	insertSQL := `INSERT INTO transactions(hash, sender, asset, amount, fee, recipient, memo, time) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 	statement, err := db.Prepare(insertSQL)
 
 	if err != nil {
 		log.Fatalln(err.Error())
 	}
-	_, err = statement.Exec(sender, amount, recipient, time)
+	result, err := statement.Exec(hash, sender, asset, amount, fee, recipient, memo, timestamp)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	id, err := result.LastInsertId()
 	if err != nil {
 		log.Fatalln(err.Error())
 	}
+
+	insertChange(db, transactionChangeType(sender), timestamp, "", id, "")
+	return id
 }
 
-func queryTransaction(db *sql.DB, id string) (*Transaction, error) {
-	querySQL := "SELECT id, sender, amount, recipient, time FROM transactions WHERE id = ?"
+func queryTransaction(db dbtx, id string) (*Transaction, error) {
+	defer observeQuery("queryTransaction", time.Now())
+
+	querySQL := "SELECT id, sender, amount, fee, recipient, time, COALESCE(block, ''), memo, asset FROM transactions WHERE id = ?"
 	row := db.QueryRow(querySQL, id)
 
 	var txn Transaction
-	err := row.Scan(&txn.ID, &txn.Sender, &txn.Amount, &txn.Recipient, &txn.Time)
+	err := row.Scan(&txn.ID, &txn.Sender, &txn.Amount, &txn.Fee, &txn.Recipient, &txn.Time, &txn.Block, &txn.Memo, &txn.Asset)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -235,8 +365,10 @@ func queryTransaction(db *sql.DB, id string) (*Transaction, error) {
 	return &txn, nil
 }
 
-func queryTransactions(db *sql.DB) ([]Transaction, error) {
-	querySQL := "SELECT id, sender, amount, recipient, time FROM transactions"
+func queryTransactions(db dbtx) ([]Transaction, error) {
+	defer observeQuery("queryTransactions", time.Now())
+
+	querySQL := "SELECT id, sender, amount, fee, recipient, time, COALESCE(block, ''), memo, asset FROM transactions"
 	rows, err := db.Query(querySQL)
 	if err != nil {
 		return nil, err
@@ -247,7 +379,7 @@ func queryTransactions(db *sql.DB) ([]Transaction, error) {
 
 	for rows.Next() {
 		var addr Transaction
-		if err := rows.Scan(&addr.ID, &addr.Sender, &addr.Amount, &addr.Recipient, &addr.Time); err != nil {
+		if err := rows.Scan(&addr.ID, &addr.Sender, &addr.Amount, &addr.Fee, &addr.Recipient, &addr.Time, &addr.Block, &addr.Memo, &addr.Asset); err != nil {
 			return nil, err
 		}
 		transactions = append(transactions, addr)
@@ -260,9 +392,13 @@ func queryTransactions(db *sql.DB) ([]Transaction, error) {
 	return transactions, nil
 }
 
-func queryAddressTransactions(db *sql.DB, address string) ([]Transaction, error) {
-	querySQL := "SELECT id, sender, amount, recipient, time FROM transactions WHERE sender = ? OR recipient = ?"
-	rows, err := db.Query(querySQL, address, address)
+// queryAddressTransactions returns the transactions sent or received by
+// address. A non-empty reference only returns transactions with that memo.
+func queryAddressTransactions(db dbtx, address string, reference string) ([]Transaction, error) {
+	defer observeQuery("queryAddressTransactions", time.Now())
+
+	querySQL := "SELECT id, sender, amount, fee, recipient, time, COALESCE(block, ''), memo, asset FROM transactions WHERE (sender = ? OR recipient = ?) AND (? = '' OR memo = ?)"
+	rows, err := db.Query(querySQL, address, address, reference, reference)
 	if err != nil {
 		return nil, err
 	}
@@ -272,7 +408,7 @@ func queryAddressTransactions(db *sql.DB, address string) ([]Transaction, error)
 
 	for rows.Next() {
 		var addr Transaction
-		if err := rows.Scan(&addr.ID, &addr.Sender, &addr.Amount, &addr.Recipient, &addr.Time); err != nil {
+		if err := rows.Scan(&addr.ID, &addr.Sender, &addr.Amount, &addr.Fee, &addr.Recipient, &addr.Time, &addr.Block, &addr.Memo, &addr.Asset); err != nil {
 			return nil, err
 		}
 		transactions = append(transactions, addr)
@@ -285,8 +421,28 @@ func queryAddressTransactions(db *sql.DB, address string) ([]Transaction, error)
 	return transactions, nil
 }
 
-func queryBlock(db *sql.DB) (string, error) {
-	querySQL := "SELECT block FROM blocks ORDER BY id DESC LIMIT 1"
+func queryBlock(db dbtx) (string, error) {
+	defer observeQuery("queryBlock", time.Now())
+
+	querySQL := "SELECT block FROM blocks ORDER BY height DESC LIMIT 1"
+	row := db.QueryRow(querySQL)
+
+	var block string
+	err := row.Scan(&block)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return block, nil
+}
+
+func queryGenesisBlock(db dbtx) (string, error) {
+	defer observeQuery("queryGenesisBlock", time.Now())
+
+	querySQL := "SELECT block FROM blocks ORDER BY height ASC LIMIT 1"
 	row := db.QueryRow(querySQL)
 
 	var block string
@@ -301,8 +457,10 @@ func queryBlock(db *sql.DB) (string, error) {
 	return block, nil
 }
 
-func queryBlocks(db *sql.DB) ([]Block, error) {
-	querySQL := "SELECT id, block, prevBlock, address, nonce, time FROM blocks"
+func queryBlocks(db dbtx) ([]Block, error) {
+	defer observeQuery("queryBlocks", time.Now())
+
+	querySQL := "SELECT id, height, block, prevBlock, address, nonce, time FROM blocks ORDER BY height"
 	rows, err := db.Query(querySQL)
 	if err != nil {
 		return nil, err
@@ -313,7 +471,7 @@ func queryBlocks(db *sql.DB) ([]Block, error) {
 
 	for rows.Next() {
 		var blk Block
-		if err := rows.Scan(&blk.ID, &blk.BlockContent, &blk.PrevBlock, &blk.Address, &blk.Nonce, &blk.Time); err != nil {
+		if err := rows.Scan(&blk.ID, &blk.Height, &blk.BlockContent, &blk.PrevBlock, &blk.Address, &blk.Nonce, &blk.Time); err != nil {
 			return nil, err
 		}
 		blocks = append(blocks, blk)
@@ -326,21 +484,169 @@ func queryBlocks(db *sql.DB) ([]Block, error) {
 	return blocks, nil
 }
 
-func insertBlock(db *sql.DB, block string, prevBlock string, address string, nonce string, time int) {
-	insertSQL := `INSERT INTO blocks(block, prevBlock, address, nonce, time) VALUES (?, ?, ?, ?, ?)`
+func queryChainHeight(db dbtx) (int, error) {
+	defer observeQuery("queryChainHeight", time.Now())
+
+	querySQL := "SELECT COALESCE(MAX(height), -1) FROM blocks"
+	var height int
+
+	err := db.QueryRow(querySQL).Scan(&height)
+	if err != nil {
+		return 0, err
+	}
+
+	return height, nil
+}
+
+// queryChainWork returns the expected number of hashes needed to mine the
+// main chain above the genesis block, counting each block at the
+// difficulty it was accepted at, as blockWork does.
+func queryChainWork(db dbtx) (*big.Int, error) {
+	defer observeQuery("queryChainWork", time.Now())
+
+	rows, err := db.Query("SELECT difficulty, COUNT(*) FROM blocks WHERE height > 0 GROUP BY difficulty")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	work := new(big.Int)
+	for rows.Next() {
+		var difficulty, count int
+		if err := rows.Scan(&difficulty, &count); err != nil {
+			return nil, err
+		}
+		work.Add(work, blockWork(difficulty, count))
+	}
+
+	return work, rows.Err()
+}
+
+func insertBlock(db dbtx, block string, prevBlock string, address string, nonce string, timestamp int, fees []string, difficulty int) {
+	defer observeQuery("insertBlock", time.Now())
+
+	insertSQL := `INSERT INTO blocks(block, prevBlock, address, nonce, time, fees, difficulty, height)
+		VALUES (?, ?, ?, ?, ?, ?, ?, (SELECT height + 1 FROM blocks WHERE block = ?))`
 	statement, err := db.Prepare(insertSQL)
 
 	if err != nil {
 		log.Fatalln(err.Error())
 	}
-	_, err = statement.Exec(block, prevBlock, address, nonce, time)
+	_, err = statement.Exec(block, prevBlock, address, nonce, timestamp, encodeFees(fees), difficulty, prevBlock)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+// insertRewardTransaction records a block reward, including any fees
+// collected by the block, as a transaction belonging to that block.
+func insertRewardTransaction(db dbtx, miner string, amount int, block string, timestamp int) int64 {
+	defer observeQuery("insertRewardTransaction", time.Now())
+
+	insertSQL := `INSERT INTO transactions(sender, amount, fee, recipient, time, block) VALUES ('null', ?, 0, ?, ?, ?)`
+	result, err := db.Exec(insertSQL, amount, miner, timestamp, block)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	insertChange(db, "mint", timestamp, "", id, "")
+	return id
+}
+
+// queryPendingFees returns the hashes of the transactions with an unpaid
+// fee.
+func queryPendingFees(db dbtx) ([]string, error) {
+	defer observeQuery("queryPendingFees", time.Now())
+
+	rows, err := db.Query(`SELECT hash FROM transactions WHERE block IS NULL AND fee > 0 ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
+
+// collectFees assigns the pending transactions listed in hashes to block
+// and returns the total of their fees. It returns false if any of them is
+// unknown or already paid to another block.
+func collectFees(db dbtx, block string, hashes []string) (int, bool) {
+	defer observeQuery("collectFees", time.Now())
+
+	updateSQL := `UPDATE transactions SET block = ? WHERE block IS NULL AND fee > 0 AND hash IN (SELECT value FROM json_each(?))`
+	result, err := db.Exec(updateSQL, block, encodeFees(hashes))
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	if n, _ := result.RowsAffected(); n != int64(len(hashes)) {
+		return 0, false
+	}
+
+	var fees int
+	err = db.QueryRow(`SELECT COALESCE(SUM(fee), 0) FROM transactions WHERE block = ? AND fee > 0`, block).Scan(&fees)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	return fees, true
+}
+
+func encodeFees(hashes []string) string {
+	if hashes == nil {
+		hashes = []string{}
+	}
+	data, err := json.Marshal(hashes)
 	if err != nil {
 		log.Fatalln(err.Error())
 	}
+	return string(data)
+}
+
+func decodeFees(data string) []string {
+	hashes := []string{}
+	if err := json.Unmarshal([]byte(data), &hashes); err != nil {
+		log.Fatalln(err.Error())
+	}
+	return hashes
 }
 
-func getSupply(db *sql.DB) (int, error) {
-	querySQL := "SELECT SUM(balance) FROM addresses"
+// queryFees returns the fees waiting for the next block and the fees
+// already paid out to miners.
+func queryFees(db dbtx) (int, int, error) {
+	defer observeQuery("queryFees", time.Now())
+
+	querySQL := `SELECT
+		COALESCE(SUM(CASE WHEN block IS NULL THEN fee ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN block IS NOT NULL THEN fee ELSE 0 END), 0)
+		FROM transactions WHERE fee > 0`
+	var pending, paid int
+
+	err := db.QueryRow(querySQL).Scan(&pending, &paid)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return pending, paid, nil
+}
+
+func getSupply(db dbtx) (int, error) {
+	defer observeQuery("getSupply", time.Now())
+
+	querySQL := "SELECT COALESCE(SUM(balance), 0) FROM addresses"
 	var totalBalance int
 
 	err := db.QueryRow(querySQL).Scan(&totalBalance)
@@ -353,3 +659,1373 @@ func getSupply(db *sql.DB) (int, error) {
 
 	return totalBalance, nil
 }
+
+func queryAddressFrozen(db dbtx, address string) bool {
+	defer observeQuery("queryAddressFrozen", time.Now())
+
+	querySQL := "SELECT frozen FROM addresses WHERE address = ?"
+	var frozen bool
+
+	err := db.QueryRow(querySQL, address).Scan(&frozen)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false
+		}
+		log.Fatal(err)
+	}
+
+	return frozen
+}
+
+// setAddressFrozen freezes or unfreezes an existing address. It returns
+// false, and changes nothing, if the address has never been seen.
+func setAddressFrozen(db dbtx, address string, frozen bool, timestamp int) bool {
+	defer observeQuery("setAddressFrozen", time.Now())
+
+	result, err := db.Exec(`UPDATE addresses SET frozen = ? WHERE address = ?`, frozen, address)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false
+	}
+
+	changeType := "unfrozen"
+	if frozen {
+		changeType = "frozen"
+	}
+	insertChange(db, changeType, timestamp, "", 0, address)
+	return true
+}
+
+func insertAdjustment(db dbtx, address string, amount int, reason string, actor string, transaction int64, timestamp int) {
+	defer observeQuery("insertAdjustment", time.Now())
+
+	insertSQL := `INSERT INTO adjustments(address, amount, reason, actor, "transaction", time) VALUES (?, ?, ?, ?, ?, ?)`
+	statement, err := db.Prepare(insertSQL)
+
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	_, err = statement.Exec(address, amount, reason, actor, transaction, timestamp)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+type AuditEntry struct {
+	ID        int    `json:"id"`
+	Time      int    `json:"time"`
+	Actor     string `json:"actor"`
+	Role      string `json:"role"`
+	Action    string `json:"action"`
+	Target    string `json:"target"`
+	Details   string `json:"details"`
+	RequestID string `json:"requestId"`
+}
+
+func insertAuditLog(db dbtx, entry AuditEntry) {
+	defer observeQuery("insertAuditLog", time.Now())
+
+	insertSQL := `INSERT INTO audit_log(time, actor, role, action, target, details, requestId) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	statement, err := db.Prepare(insertSQL)
+
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	_, err = statement.Exec(entry.Time, entry.Actor, entry.Role, entry.Action, entry.Target, entry.Details, entry.RequestID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+func queryAuditLog(db dbtx) ([]AuditEntry, error) {
+	defer observeQuery("queryAuditLog", time.Now())
+
+	querySQL := "SELECT id, time, actor, role, action, target, details, requestId FROM audit_log ORDER BY id"
+	rows, err := db.Query(querySQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Time, &e.Actor, &e.Role, &e.Action, &e.Target, &e.Details, &e.RequestID); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+type Invoice struct {
+	ID              string
+	Address         string
+	Amount          int
+	Memo            string
+	Created         int
+	Expires         int
+	PaidTransaction int64
+	PaidAt          int
+}
+
+func insertInvoice(db dbtx, inv *Invoice) {
+	defer observeQuery("insertInvoice", time.Now())
+
+	insertSQL := `INSERT INTO invoices(id, address, amount, memo, created, expires) VALUES (?, ?, ?, ?, ?, ?)`
+	statement, err := db.Prepare(insertSQL)
+
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	_, err = statement.Exec(inv.ID, inv.Address, inv.Amount, inv.Memo, inv.Created, inv.Expires)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+func queryInvoice(db dbtx, id string) (*Invoice, error) {
+	defer observeQuery("queryInvoice", time.Now())
+
+	querySQL := "SELECT id, address, amount, memo, created, expires, COALESCE(paidTransaction, 0), COALESCE(paidAt, 0) FROM invoices WHERE id = ?"
+	row := db.QueryRow(querySQL, id)
+
+	var inv Invoice
+	err := row.Scan(&inv.ID, &inv.Address, &inv.Amount, &inv.Memo, &inv.Created, &inv.Expires, &inv.PaidTransaction, &inv.PaidAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &inv, nil
+}
+
+// markInvoicePaid settles the open invoice whose ID matches the reference
+// if the payment went to the invoice address and covers its amount.
+func markInvoicePaid(db dbtx, reference string, recipient string, amount int, transaction int64, timestamp int) {
+	defer observeQuery("markInvoicePaid", time.Now())
+
+	updateSQL := `UPDATE invoices SET paidTransaction = ?, paidAt = ?
+		WHERE id = ? AND address = ? AND amount <= ? AND paidTransaction IS NULL AND expires > ?`
+	_, err := db.Exec(updateSQL, transaction, timestamp, reference, recipient, amount, timestamp)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+type MultisigAddress struct {
+	Address    string
+	Threshold  int
+	PublicKeys []string
+	Nonce      int
+}
+
+// insertMultisigAddress registers a multisig address unless it already
+// exists.
+func insertMultisigAddress(db dbtx, ms *MultisigAddress, timestamp int) {
+	defer observeQuery("insertMultisigAddress", time.Now())
+
+	insertSQL := `INSERT OR IGNORE INTO multisig_addresses(address, threshold, pubkeys, nonce, created) VALUES (?, ?, ?, ?, ?)`
+	statement, err := db.Prepare(insertSQL)
+
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	result, err := statement.Exec(ms.Address, ms.Threshold, strings.Join(ms.PublicKeys, ","), ms.Nonce, timestamp)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	if n, _ := result.RowsAffected(); n > 0 {
+		insertChange(db, "multisig", timestamp, "", 0, ms.Address)
+	}
+}
+
+func queryMultisigAddress(db dbtx, address string) (*MultisigAddress, error) {
+	defer observeQuery("queryMultisigAddress", time.Now())
+
+	querySQL := "SELECT address, threshold, pubkeys, nonce FROM multisig_addresses WHERE address = ?"
+	row := db.QueryRow(querySQL, address)
+
+	var ms MultisigAddress
+	var pubkeys string
+	err := row.Scan(&ms.Address, &ms.Threshold, &pubkeys, &ms.Nonce)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ms.PublicKeys = strings.Split(pubkeys, ",")
+
+	return &ms, nil
+}
+
+func setMultisigNonce(db dbtx, address string, nonce int, timestamp int) {
+	defer observeQuery("setMultisigNonce", time.Now())
+
+	_, err := db.Exec(`UPDATE multisig_addresses SET nonce = ? WHERE address = ?`, nonce, address)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	insertChange(db, "multisig", timestamp, "", 0, address)
+}
+
+// ScheduledTransfer is either a one-off transfer locked until UnlockTime
+// and/or UnlockHeight, or a recurring transfer whose next payment is due at
+// UnlockTime or UnlockHeight and advances by EverySeconds or EveryBlocks.
+// Funding is the transaction that locked a one-off transfer's funds. Runs
+// counts releases, payments and missed payments, and numbers the
+// transactions the scheduler creates.
+type ScheduledTransfer struct {
+	ID           int64
+	Kind         string
+	Sender       string
+	Recipient    string
+	Amount       int
+	Fee          int
+	Memo         string
+	UnlockTime   int
+	UnlockHeight int
+	EverySeconds int
+	EveryBlocks  int
+	Status       string
+	Payments     int
+	Missed       int
+	Runs         int
+	Funding      int64
+	Created      int
+}
+
+const scheduledTransferColumns = `id, kind, sender, recipient, amount, fee, memo, unlockTime, unlockHeight,
+	everySeconds, everyBlocks, status, payments, missed, runs, COALESCE(funding, 0), created`
+
+func scanScheduledTransfer(row interface{ Scan(...interface{}) error }) (*ScheduledTransfer, error) {
+	var st ScheduledTransfer
+	err := row.Scan(&st.ID, &st.Kind, &st.Sender, &st.Recipient, &st.Amount, &st.Fee, &st.Memo, &st.UnlockTime, &st.UnlockHeight,
+		&st.EverySeconds, &st.EveryBlocks, &st.Status, &st.Payments, &st.Missed, &st.Runs, &st.Funding, &st.Created)
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// insertScheduledTransfer stores a scheduled transfer under its ID, which
+// the caller chooses so it is the same on every node.
+func insertScheduledTransfer(db dbtx, st *ScheduledTransfer, timestamp int) {
+	defer observeQuery("insertScheduledTransfer", time.Now())
+
+	insertSQL := `INSERT INTO scheduled_transfers(id, kind, sender, recipient, amount, fee, memo, unlockTime, unlockHeight,
+		everySeconds, everyBlocks, status, payments, missed, runs, funding, created) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), ?)`
+	statement, err := db.Prepare(insertSQL)
+
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	_, err = statement.Exec(st.ID, st.Kind, st.Sender, st.Recipient, st.Amount, st.Fee, st.Memo, st.UnlockTime, st.UnlockHeight,
+		st.EverySeconds, st.EveryBlocks, st.Status, st.Payments, st.Missed, st.Runs, st.Funding, st.Created)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	insertRecordChange(db, "schedule", timestamp, st.ID)
+}
+
+func queryScheduledTransfer(db dbtx, id int64) (*ScheduledTransfer, error) {
+	defer observeQuery("queryScheduledTransfer", time.Now())
+
+	st, err := scanScheduledTransfer(db.QueryRow("SELECT "+scheduledTransferColumns+" FROM scheduled_transfers WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return st, err
+}
+
+func queryScheduledTransfersWhere(db dbtx, where string, args ...interface{}) ([]ScheduledTransfer, error) {
+	rows, err := db.Query("SELECT "+scheduledTransferColumns+" FROM scheduled_transfers WHERE "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := []ScheduledTransfer{}
+	for rows.Next() {
+		st, err := scanScheduledTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, *st)
+	}
+
+	return transfers, rows.Err()
+}
+
+// queryAddressScheduledTransfers returns the scheduled transfers sent or
+// received by an address.
+func queryAddressScheduledTransfers(db dbtx, address string) ([]ScheduledTransfer, error) {
+	defer observeQuery("queryAddressScheduledTransfers", time.Now())
+
+	return queryScheduledTransfersWhere(db, "sender = ? OR recipient = ?", address, address)
+}
+
+// queryDueScheduledTransfers returns the active scheduled transfers whose
+// time and height conditions are both met. A zero condition always holds.
+func queryDueScheduledTransfers(db dbtx, now, height int) ([]ScheduledTransfer, error) {
+	defer observeQuery("queryDueScheduledTransfers", time.Now())
+
+	return queryScheduledTransfersWhere(db, "status = 'active' AND unlockTime <= ? AND unlockHeight <= ?", now, height)
+}
+
+// queryReleasedLocksAbove returns the time-locked transfers that were
+// released on reaching a height above height.
+func queryReleasedLocksAbove(db dbtx, height int) ([]ScheduledTransfer, error) {
+	defer observeQuery("queryReleasedLocksAbove", time.Now())
+
+	return queryScheduledTransfersWhere(db, "kind = 'locked' AND status = 'released' AND unlockHeight > ?", height)
+}
+
+func updateScheduledTransfer(db dbtx, st *ScheduledTransfer, timestamp int) {
+	defer observeQuery("updateScheduledTransfer", time.Now())
+
+	updateSQL := `UPDATE scheduled_transfers SET unlockTime = ?, unlockHeight = ?, status = ?, payments = ?, missed = ?, runs = ? WHERE id = ?`
+	_, err := db.Exec(updateSQL, st.UnlockTime, st.UnlockHeight, st.Status, st.Payments, st.Missed, st.Runs, st.ID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	insertRecordChange(db, "schedule", timestamp, st.ID)
+}
+
+// queryLocked returns the total held in active time-locked transfers,
+// optionally limited to those paying recipient.
+func queryLocked(db dbtx, recipient string) (int, error) {
+	defer observeQuery("queryLocked", time.Now())
+
+	querySQL := "SELECT COALESCE(SUM(amount), 0) FROM scheduled_transfers WHERE kind = 'locked' AND status = 'active'"
+	args := []interface{}{}
+	if recipient != "" {
+		querySQL += " AND recipient = ?"
+		args = append(args, recipient)
+	}
+
+	var locked int
+	err := db.QueryRow(querySQL, args...).Scan(&locked)
+	return locked, err
+}
+
+// Escrow holds a buyer's funds until two of buyer, seller and arbiter vote
+// to release them to the seller or refund them to the buyer. Each vote has
+// the time it was cast, so nodes agree on a party's latest vote. Funding is
+// the transaction that moved the buyer's funds.
+type Escrow struct {
+	ID                 int64
+	Buyer              string
+	Seller             string
+	Arbiter            string
+	Amount             int
+	Fee                int
+	Memo               string
+	BuyerVote          string
+	SellerVote         string
+	ArbiterVote        string
+	BuyerVoted         int
+	SellerVoted        int
+	ArbiterVoted       int
+	Status             string
+	Created            int
+	Expires            int
+	Funding            int64
+	SettledTransaction int64
+	SettledAt          int
+}
+
+const escrowColumns = `id, buyer, seller, arbiter, amount, fee, memo, buyerVote, sellerVote, arbiterVote,
+	buyerVoted, sellerVoted, arbiterVoted, status, created, expires, COALESCE(funding, 0),
+	COALESCE(settledTransaction, 0), COALESCE(settledAt, 0)`
+
+func scanEscrow(row interface{ Scan(...interface{}) error }) (*Escrow, error) {
+	var e Escrow
+	err := row.Scan(&e.ID, &e.Buyer, &e.Seller, &e.Arbiter, &e.Amount, &e.Fee, &e.Memo, &e.BuyerVote, &e.SellerVote, &e.ArbiterVote,
+		&e.BuyerVoted, &e.SellerVoted, &e.ArbiterVoted, &e.Status, &e.Created, &e.Expires, &e.Funding,
+		&e.SettledTransaction, &e.SettledAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// insertEscrow stores an escrow under its ID, which the caller chooses so
+// it is the same on every node.
+func insertEscrow(db dbtx, e *Escrow, timestamp int) {
+	defer observeQuery("insertEscrow", time.Now())
+
+	insertSQL := `INSERT INTO escrows(id, buyer, seller, arbiter, amount, fee, memo, buyerVote, sellerVote, arbiterVote,
+		buyerVoted, sellerVoted, arbiterVoted, status, created, expires, funding, settledTransaction, settledAt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), NULLIF(?, 0), NULLIF(?, 0))`
+	statement, err := db.Prepare(insertSQL)
+
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+	_, err = statement.Exec(e.ID, e.Buyer, e.Seller, e.Arbiter, e.Amount, e.Fee, e.Memo, e.BuyerVote, e.SellerVote, e.ArbiterVote,
+		e.BuyerVoted, e.SellerVoted, e.ArbiterVoted, e.Status, e.Created, e.Expires, e.Funding, e.SettledTransaction, e.SettledAt)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	insertRecordChange(db, "escrow", timestamp, e.ID)
+}
+
+func queryEscrow(db dbtx, id int64) (*Escrow, error) {
+	defer observeQuery("queryEscrow", time.Now())
+
+	e, err := scanEscrow(db.QueryRow("SELECT "+escrowColumns+" FROM escrows WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+// queryExpiredEscrows returns the IDs of open escrows past their expiry.
+func queryExpiredEscrows(db dbtx, now int) ([]int64, error) {
+	defer observeQuery("queryExpiredEscrows", time.Now())
+
+	rows, err := db.Query("SELECT id FROM escrows WHERE status = 'open' AND expires <= ? ORDER BY id", now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func updateEscrow(db dbtx, e *Escrow, timestamp int) {
+	defer observeQuery("updateEscrow", time.Now())
+
+	updateSQL := `UPDATE escrows SET buyerVote = ?, sellerVote = ?, arbiterVote = ?, buyerVoted = ?, sellerVoted = ?, arbiterVoted = ?,
+		status = ?, settledTransaction = NULLIF(?, 0), settledAt = NULLIF(?, 0) WHERE id = ?`
+	_, err := db.Exec(updateSQL, e.BuyerVote, e.SellerVote, e.ArbiterVote, e.BuyerVoted, e.SellerVoted, e.ArbiterVoted,
+		e.Status, e.SettledTransaction, e.SettledAt, e.ID)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	insertRecordChange(db, "escrow", timestamp, e.ID)
+}
+
+// queryEscrowed returns the total held in open escrows.
+func queryEscrowed(db dbtx) (int, error) {
+	defer observeQuery("queryEscrowed", time.Now())
+
+	var escrowed int
+	err := db.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM escrows WHERE status = 'open'").Scan(&escrowed)
+	return escrowed, err
+}
+
+type Asset struct {
+	ID       string
+	Name     string
+	Issuer   string
+	Supply   int
+	Mintable bool
+	Created  int
+}
+
+func insertAsset(db dbtx, asset *Asset) error {
+	defer observeQuery("insertAsset", time.Now())
+
+	insertSQL := `INSERT INTO assets(id, name, issuer, supply, mintable, created) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := db.Exec(insertSQL, asset.ID, asset.Name, asset.Issuer, asset.Supply, asset.Mintable, asset.Created)
+	return err
+}
+
+func queryAsset(db dbtx, id string) (*Asset, error) {
+	defer observeQuery("queryAsset", time.Now())
+
+	querySQL := "SELECT id, name, issuer, supply, mintable, created FROM assets WHERE id = ?"
+	row := db.QueryRow(querySQL, id)
+
+	var asset Asset
+	err := row.Scan(&asset.ID, &asset.Name, &asset.Issuer, &asset.Supply, &asset.Mintable, &asset.Created)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &asset, nil
+}
+
+func queryAssets(db dbtx) ([]Asset, error) {
+	defer observeQuery("queryAssets", time.Now())
+
+	rows, err := db.Query("SELECT id, name, issuer, supply, mintable, created FROM assets ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := []Asset{}
+	for rows.Next() {
+		var asset Asset
+		if err := rows.Scan(&asset.ID, &asset.Name, &asset.Issuer, &asset.Supply, &asset.Mintable, &asset.Created); err != nil {
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+
+	return assets, rows.Err()
+}
+
+func addAssetSupply(db dbtx, id string, amount int) {
+	defer observeQuery("addAssetSupply", time.Now())
+
+	_, err := db.Exec(`UPDATE assets SET supply = supply + ? WHERE id = ?`, amount, id)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+// queryBalance returns an address's balance of asset. Native coin balances
+// live in the addresses table and token balances in asset_balances.
+func queryBalance(db dbtx, address, asset string) int {
+	if asset == nativeAsset {
+		return queryAddress(db, address)
+	}
+
+	defer observeQuery("queryBalance", time.Now())
+
+	var balance int
+	err := db.QueryRow("SELECT balance FROM asset_balances WHERE address = ? AND asset = ?", address, asset).Scan(&balance)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0
+		}
+		log.Fatal(err)
+	}
+
+	return balance
+}
+
+func creditBalance(db dbtx, address, asset string, amount int) {
+	if asset == nativeAsset {
+		creditAddress(db, address, amount)
+		return
+	}
+
+	defer observeQuery("creditBalance", time.Now())
+
+	upsertSQL := `INSERT INTO asset_balances(address, asset, balance) VALUES (?, ?, ?)
+		ON CONFLICT(address, asset) DO UPDATE SET balance = balance + excluded.balance`
+	_, err := db.Exec(upsertSQL, address, asset, amount)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+type Holding struct {
+	Asset   string
+	Balance int
+}
+
+// queryHoldings returns the token balances held by an address.
+func queryHoldings(db dbtx, address string) ([]Holding, error) {
+	defer observeQuery("queryHoldings", time.Now())
+
+	rows, err := db.Query("SELECT asset, balance FROM asset_balances WHERE address = ? AND balance != 0 ORDER BY asset", address)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holdings := []Holding{}
+	for rows.Next() {
+		var h Holding
+		if err := rows.Scan(&h.Asset, &h.Balance); err != nil {
+			return nil, err
+		}
+		holdings = append(holdings, h)
+	}
+
+	return holdings, rows.Err()
+}
+
+func queryAssetCirculation(db dbtx, asset string) (int, error) {
+	defer observeQuery("queryAssetCirculation", time.Now())
+
+	var total int
+	err := db.QueryRow("SELECT COALESCE(SUM(balance), 0) FROM asset_balances WHERE asset = ?", asset).Scan(&total)
+	return total, err
+}
+
+func setTransactionHash(db dbtx, id int64, hash string) {
+	defer observeQuery("setTransactionHash", time.Now())
+
+	_, err := db.Exec(`UPDATE transactions SET hash = ? WHERE id = ?`, hash, id)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+func transactionHashExists(db dbtx, hash string) bool {
+	defer observeQuery("transactionHashExists", time.Now())
+
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM transactions WHERE hash = ?)`, hash).Scan(&exists)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return exists
+}
+
+// queryPeerTransaction returns a transaction as it is sent to peers.
+func queryPeerTransaction(db dbtx, id int64) (*PeerTransaction, error) {
+	defer observeQuery("queryPeerTransaction", time.Now())
+
+	querySQL := "SELECT COALESCE(hash, ''), sender, recipient, asset, amount, fee, memo, time FROM transactions WHERE id = ?"
+
+	var t PeerTransaction
+	err := db.QueryRow(querySQL, id).Scan(&t.Hash, &t.Sender, &t.Recipient, &t.Asset, &t.Amount, &t.Fee, &t.Memo, &t.Time)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// queryPeerCursor returns how far this node has read a peer's entry feed.
+func queryPeerCursor(db dbtx, peer string) (int64, error) {
+	defer observeQuery("queryPeerCursor", time.Now())
+
+	var cursor int64
+	err := db.QueryRow(`SELECT cursor FROM peer_cursors WHERE peer = ?`, peer).Scan(&cursor)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return cursor, err
+}
+
+func setPeerCursor(db dbtx, peer string, cursor int64) {
+	defer observeQuery("setPeerCursor", time.Now())
+
+	upsertSQL := `INSERT INTO peer_cursors(peer, cursor) VALUES (?, ?) ON CONFLICT(peer) DO UPDATE SET cursor = excluded.cursor`
+	if _, err := db.Exec(upsertSQL, peer, cursor); err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+// PeerConflict is a peer entry this node refused because applying it would
+// contradict its own ledger, such as a transaction spending funds that were
+// already spent here. Entry is the entry as the peer sent it.
+type PeerConflict struct {
+	ID     int             `json:"id"`
+	Peer   string          `json:"peer"`
+	Cursor int64           `json:"cursor"`
+	Type   string          `json:"type"`
+	Entry  json.RawMessage `json:"entry"`
+	Reason string          `json:"reason"`
+	Time   int             `json:"time"`
+}
+
+func insertPeerConflict(db dbtx, c PeerConflict) {
+	defer observeQuery("insertPeerConflict", time.Now())
+
+	insertSQL := `INSERT INTO peer_conflicts(peer, cursor, type, entry, reason, time) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := db.Exec(insertSQL, c.Peer, c.Cursor, c.Type, string(c.Entry), c.Reason, c.Time); err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+func queryPeerConflicts(db dbtx) ([]PeerConflict, error) {
+	defer observeQuery("queryPeerConflicts", time.Now())
+
+	rows, err := db.Query("SELECT id, peer, cursor, type, entry, reason, time FROM peer_conflicts ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conflicts []PeerConflict
+
+	for rows.Next() {
+		var c PeerConflict
+		var entry string
+		if err := rows.Scan(&c.ID, &c.Peer, &c.Cursor, &c.Type, &entry, &c.Reason, &c.Time); err != nil {
+			return nil, err
+		}
+		c.Entry = json.RawMessage(entry)
+		conflicts = append(conflicts, c)
+	}
+
+	return conflicts, rows.Err()
+}
+
+// queryHashedTransaction returns the transaction with the given hash, or
+// nil if there is none.
+func queryHashedTransaction(db dbtx, hash string) (*Transaction, error) {
+	defer observeQuery("queryHashedTransaction", time.Now())
+
+	querySQL := "SELECT id, sender, amount, fee, recipient, time, COALESCE(block, ''), memo, asset FROM transactions WHERE hash = ?"
+
+	var txn Transaction
+	err := db.QueryRow(querySQL, hash).Scan(&txn.ID, &txn.Sender, &txn.Amount, &txn.Fee, &txn.Recipient, &txn.Time, &txn.Block, &txn.Memo, &txn.Asset)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &txn, nil
+}
+
+// queryTransactionHash returns the hash of the transaction id, or "" if
+// there is none.
+func queryTransactionHash(db dbtx, id int64) (string, error) {
+	defer observeQuery("queryTransactionHash", time.Now())
+
+	var hash string
+	err := db.QueryRow("SELECT COALESCE(hash, '') FROM transactions WHERE id = ?", id).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return hash, err
+}
+
+// queryLastFreeze returns the type, "frozen" or "unfrozen", and time of the
+// latest freeze change for an address. The type is empty if there is none.
+func queryLastFreeze(db dbtx, address string) (string, int, error) {
+	defer observeQuery("queryLastFreeze", time.Now())
+
+	querySQL := `SELECT type, time FROM changes WHERE address = ? AND type IN ('frozen', 'unfrozen') ORDER BY id DESC LIMIT 1`
+
+	var changeType string
+	var timestamp int
+	err := db.QueryRow(querySQL, address).Scan(&changeType, &timestamp)
+	if err == sql.ErrNoRows {
+		return "", 0, nil
+	}
+	return changeType, timestamp, err
+}
+
+// blockExists reports whether a block is on the main chain or a side
+// branch.
+func blockExists(db dbtx, block string) bool {
+	defer observeQuery("blockExists", time.Now())
+
+	var exists bool
+	querySQL := `SELECT EXISTS(SELECT 1 FROM blocks WHERE block = ?) OR EXISTS(SELECT 1 FROM side_blocks WHERE block = ?)`
+	err := db.QueryRow(querySQL, block, block).Scan(&exists)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return exists
+}
+
+// SideBlock is a valid block that is not on the main chain, either because
+// it extends a shorter branch ("side") or because a reorg replaced it
+// ("orphaned").
+type SideBlock struct {
+	Block
+	Status string `json:"status"`
+}
+
+// BlockDetail is a block with its status, "main", "side" or "orphaned",
+// and the main chain block after it, if any.
+type BlockDetail struct {
+	Block
+	Status    string `json:"status"`
+	NextBlock string `json:"nextBlock"`
+}
+
+func insertSideBlock(db dbtx, blk Block, status string) {
+	defer observeQuery("insertSideBlock", time.Now())
+
+	insertSQL := `INSERT INTO side_blocks(block, prevBlock, address, nonce, time, fees, difficulty, status, height)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, (SELECT height + 1 FROM blocks WHERE block = ? UNION ALL SELECT height + 1 FROM side_blocks WHERE block = ?))`
+	_, err := db.Exec(insertSQL, blk.BlockContent, blk.PrevBlock, blk.Address, blk.Nonce, blk.Time, encodeFees(blk.Fees), blk.Difficulty, status, blk.PrevBlock, blk.PrevBlock)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+func querySideBlock(db dbtx, block string) (*Block, error) {
+	defer observeQuery("querySideBlock", time.Now())
+
+	querySQL := "SELECT height, block, prevBlock, address, nonce, time, fees, difficulty FROM side_blocks WHERE block = ?"
+
+	var blk Block
+	var fees string
+	err := db.QueryRow(querySQL, block).Scan(&blk.Height, &blk.BlockContent, &blk.PrevBlock, &blk.Address, &blk.Nonce, &blk.Time, &fees, &blk.Difficulty)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	blk.Fees = decodeFees(fees)
+
+	return &blk, nil
+}
+
+func querySideBlocks(db dbtx) ([]SideBlock, error) {
+	defer observeQuery("querySideBlocks", time.Now())
+
+	rows, err := db.Query("SELECT height, block, prevBlock, address, nonce, time, status FROM side_blocks ORDER BY time, block")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocks := []SideBlock{}
+	for rows.Next() {
+		var blk SideBlock
+		if err := rows.Scan(&blk.Height, &blk.BlockContent, &blk.PrevBlock, &blk.Address, &blk.Nonce, &blk.Time, &blk.Status); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, blk)
+	}
+
+	return blocks, rows.Err()
+}
+
+func deleteSideBlock(db dbtx, block string) {
+	defer observeQuery("deleteSideBlock", time.Now())
+
+	if _, err := db.Exec(`DELETE FROM side_blocks WHERE block = ?`, block); err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+// queryBlocksAfter returns the main chain blocks above block, oldest first.
+func queryBlocksAfter(db dbtx, block string) ([]Block, error) {
+	defer observeQuery("queryBlocksAfter", time.Now())
+
+	querySQL := "SELECT id, height, block, prevBlock, address, nonce, time, fees, difficulty FROM blocks WHERE height > (SELECT height FROM blocks WHERE block = ?) ORDER BY height"
+	rows, err := db.Query(querySQL, block)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []Block
+	for rows.Next() {
+		var blk Block
+		var fees string
+		if err := rows.Scan(&blk.ID, &blk.Height, &blk.BlockContent, &blk.PrevBlock, &blk.Address, &blk.Nonce, &blk.Time, &fees, &blk.Difficulty); err != nil {
+			return nil, err
+		}
+		blk.Fees = decodeFees(fees)
+		blocks = append(blocks, blk)
+	}
+
+	return blocks, rows.Err()
+}
+
+func deleteBlock(db dbtx, block string) {
+	defer observeQuery("deleteBlock", time.Now())
+
+	if _, err := db.Exec(`DELETE FROM blocks WHERE block = ?`, block); err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+// reverseRewardTransactions pays back every reward block paid that hasn't
+// been reversed yet. Each reversal is a transaction from the miner to
+// "null" belonging to the same block, so the history keeps both the reward
+// and its reversal. It returns how much each miner must be debited.
+func reverseRewardTransactions(db dbtx, block string, timestamp int) (map[string]int, error) {
+	defer observeQuery("reverseRewardTransactions", time.Now())
+
+	querySQL := `SELECT miner, SUM(amount) FROM (
+			SELECT recipient AS miner, amount FROM transactions WHERE sender = 'null' AND block = ?
+			UNION ALL SELECT sender, -amount FROM transactions WHERE recipient = 'null' AND block = ?)
+		GROUP BY miner HAVING SUM(amount) > 0 ORDER BY miner`
+	rows, err := db.Query(querySQL, block, block)
+	if err != nil {
+		return nil, err
+	}
+
+	rewards := map[string]int{}
+	var miners []string
+	for rows.Next() {
+		var miner string
+		var amount int
+		if err := rows.Scan(&miner, &amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rewards[miner] = amount
+		miners = append(miners, miner)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	insertSQL := `INSERT INTO transactions(sender, amount, fee, recipient, time, block) VALUES (?, ?, 0, 'null', ?, ?)`
+	for _, miner := range miners {
+		result, err := db.Exec(insertSQL, miner, rewards[miner], timestamp, block)
+		if err != nil {
+			return nil, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		insertChange(db, "transfer", timestamp, "", id, "")
+	}
+
+	return rewards, nil
+}
+
+// releaseFees returns the fee-paying transactions collected by a block to
+// the pending pool so the next block can collect them again. Rewards and
+// their reversals stay with the block.
+func releaseFees(db dbtx, block string) {
+	defer observeQuery("releaseFees", time.Now())
+
+	_, err := db.Exec(`UPDATE transactions SET block = NULL WHERE block = ? AND sender != 'null' AND recipient != 'null'`, block)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+type ReorgEvent struct {
+	ID        int64  `json:"id"`
+	Time      int    `json:"time"`
+	ForkBlock string `json:"forkBlock"`
+	OldTip    string `json:"oldTip"`
+	NewTip    string `json:"newTip"`
+	Orphaned  int    `json:"orphaned"`
+	Adopted   int    `json:"adopted"`
+}
+
+func insertReorg(db dbtx, ev *ReorgEvent) int64 {
+	defer observeQuery("insertReorg", time.Now())
+
+	insertSQL := `INSERT INTO reorgs(time, forkBlock, oldTip, newTip, orphaned, adopted) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := db.Exec(insertSQL, ev.Time, ev.ForkBlock, ev.OldTip, ev.NewTip, ev.Orphaned, ev.Adopted)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	return id
+}
+
+func queryReorgs(db dbtx) ([]ReorgEvent, error) {
+	defer observeQuery("queryReorgs", time.Now())
+
+	rows, err := db.Query("SELECT id, time, forkBlock, oldTip, newTip, orphaned, adopted FROM reorgs ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []ReorgEvent{}
+	for rows.Next() {
+		var ev ReorgEvent
+		if err := rows.Scan(&ev.ID, &ev.Time, &ev.ForkBlock, &ev.OldTip, &ev.NewTip, &ev.Orphaned, &ev.Adopted); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+
+	return events, rows.Err()
+}
+
+// Change is an entry in the ledger change feed. It refers to the block,
+// transaction, address, asset, escrow or scheduled transfer it is about.
+type Change struct {
+	Cursor        int64        `json:"cursor"`
+	Type          string       `json:"type"`
+	Time          int          `json:"time"`
+	BlockHash     string       `json:"blockHash,omitempty"`
+	Block         *Block       `json:"block,omitempty"`
+	TransactionID int64        `json:"transactionId,omitempty"`
+	Transaction   *Transaction `json:"transaction,omitempty"`
+	Address       string       `json:"address,omitempty"`
+	Asset         string       `json:"asset,omitempty"`
+	EscrowID      int64        `json:"escrowId,omitempty"`
+	ScheduleID    int64        `json:"scheduleId,omitempty"`
+}
+
+// insertChange appends an event to the change feed. It runs in the same
+// database transaction as the change it describes.
+func insertChange(db dbtx, changeType string, timestamp int, block string, transaction int64, address string) {
+	defer observeQuery("insertChange", time.Now())
+
+	insertSQL := `INSERT INTO changes(type, time, block, "transaction", address) VALUES (?, ?, NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''))`
+	if _, err := db.Exec(insertSQL, changeType, timestamp, block, transaction, address); err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+// insertAssetChange records that an asset was issued.
+func insertAssetChange(db dbtx, timestamp int, asset string) {
+	defer observeQuery("insertChange", time.Now())
+
+	if _, err := db.Exec(`INSERT INTO changes(type, time, asset) VALUES ('asset', ?, ?)`, timestamp, asset); err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+// insertRecordChange records that the escrow or scheduled transfer id was
+// created or changed. changeType is "escrow" or "schedule", which is also
+// the column the ID goes in.
+func insertRecordChange(db dbtx, changeType string, timestamp int, id int64) {
+	defer observeQuery("insertChange", time.Now())
+
+	if _, err := db.Exec(`INSERT INTO changes(type, time, `+changeType+`) VALUES (?, ?, ?)`, changeType, timestamp, id); err != nil {
+		log.Fatalln(err.Error())
+	}
+}
+
+// transactionChangeType is "mint" for transactions that create coins or
+// tokens and "transfer" for the rest.
+func transactionChangeType(sender string) string {
+	switch sender {
+	case "null", issuanceAccount, "adjustment":
+		return "mint"
+	}
+	return "transfer"
+}
+
+// queryChanges returns up to limit changes after the cursor since, oldest
+// first.
+func queryChanges(db dbtx, since int64, limit int) ([]Change, error) {
+	defer observeQuery("queryChanges", time.Now())
+
+	querySQL := `SELECT id, type, COALESCE(time, 0), COALESCE(block, ''), COALESCE("transaction", 0), COALESCE(address, ''), COALESCE(asset, ''),
+		COALESCE(escrow, 0), COALESCE(schedule, 0) FROM changes WHERE id > ? ORDER BY id LIMIT ?`
+	rows, err := db.Query(querySQL, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	for rows.Next() {
+		var c Change
+		if err := rows.Scan(&c.Cursor, &c.Type, &c.Time, &c.BlockHash, &c.TransactionID, &c.Address, &c.Asset, &c.EscrowID, &c.ScheduleID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range changes {
+		c := &changes[i]
+		if c.BlockHash != "" {
+			if c.Block, err = queryAnyBlock(db, c.BlockHash); err != nil {
+				return nil, err
+			}
+		}
+		if c.TransactionID != 0 {
+			if c.Transaction, err = queryTransaction(db, strconv.FormatInt(c.TransactionID, 10)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// queryLatestChange returns the cursor of the newest change, or zero if
+// there are none.
+func queryLatestChange(db dbtx) (int64, error) {
+	defer observeQuery("queryLatestChange", time.Now())
+
+	var cursor int64
+	err := db.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM changes`).Scan(&cursor)
+	return cursor, err
+}
+
+// replicaSkippedTables are not copied from a primary's snapshot. The audit
+// log is removed from snapshots and the schema version is checked instead.
+// The primary's cursors in its own peers' feeds mean nothing to a replica.
+var replicaSkippedTables = map[string]bool{
+	"audit_log":         true,
+	"schema_migrations": true,
+	"peer_cursors":      true,
+}
+
+// snapshotDatabase writes a consistent copy of the database to path, which
+// must not exist, without the admin audit log.
+func snapshotDatabase(path string) error {
+	defer observeQuery("snapshotDatabase", time.Now())
+
+	if _, err := sqliteDatabase.Exec("VACUUM INTO ?", path); err != nil {
+		return err
+	}
+
+	snapshot, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+
+	_, err = snapshot.Exec("DROP TABLE IF EXISTS audit_log")
+	return err
+}
+
+// restoreSnapshot replaces the contents of every replicated table with the
+// snapshot at path in a single transaction, so readers see either the old
+// or the new state. The snapshot must have the same schema version. The
+// last change in the snapshot becomes the cursor in primary's entry feed,
+// so the replica goes on from there.
+func restoreSnapshot(ctx context.Context, path, primary string) error {
+	defer observeQuery("restoreSnapshot", time.Now())
+
+	conn, err := sqliteDatabase.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS snapshot", path); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "DETACH DATABASE snapshot")
+
+	var version int
+	if err := conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM snapshot.schema_migrations").Scan(&version); err != nil {
+		return err
+	}
+	if version != latestSchemaVersion() {
+		return fmt.Errorf("primary has schema version %d, want %d", version, latestSchemaVersion())
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT name FROM main.sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		if !replicaSkippedTables[name] {
+			tables = append(tables, name)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range tables {
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM main."%s"`, table)); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`INSERT INTO main."%s" SELECT * FROM snapshot."%s"`, table, table)); err != nil {
+			return fmt.Errorf("copying %s: %w", table, err)
+		}
+	}
+
+	var cursor int64
+	if err := tx.QueryRow("SELECT COALESCE(MAX(id), 0) FROM snapshot.changes").Scan(&cursor); err != nil {
+		return err
+	}
+	setPeerCursor(tx, primary, cursor)
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	notifyChanges()
+	return nil
+}
+
+// queryAnyBlock finds a block on the main chain or a side branch.
+func queryAnyBlock(db dbtx, hash string) (*Block, error) {
+	defer observeQuery("queryAnyBlock", time.Now())
+
+	querySQL := `SELECT id, height, block, prevBlock, address, nonce, time, fees FROM blocks WHERE block = ?
+		UNION ALL SELECT 0, height, block, prevBlock, address, nonce, time, fees FROM side_blocks WHERE block = ?`
+
+	var blk Block
+	var fees string
+	err := db.QueryRow(querySQL, hash, hash).Scan(&blk.ID, &blk.Height, &blk.BlockContent, &blk.PrevBlock, &blk.Address, &blk.Nonce, &blk.Time, &fees)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	blk.Fees = decodeFees(fees)
+
+	return &blk, nil
+}
+
+// queryChainBlock finds a block on the main chain or a side branch with its
+// status, "main", "side" or "orphaned", and the main chain block after it.
+// NextBlock is empty for side blocks and the tip.
+func queryChainBlock(db dbtx, hash string) (*BlockDetail, error) {
+	defer observeQuery("queryChainBlock", time.Now())
+
+	querySQL := `SELECT id, height, block, prevBlock, address, nonce, time, 'main',
+			COALESCE((SELECT n.block FROM blocks n WHERE n.height = blocks.height + 1), '') FROM blocks WHERE block = ?
+		UNION ALL SELECT 0, height, block, prevBlock, address, nonce, time, status, '' FROM side_blocks WHERE block = ?`
+
+	var blk BlockDetail
+	err := db.QueryRow(querySQL, hash, hash).Scan(&blk.ID, &blk.Height, &blk.BlockContent, &blk.PrevBlock, &blk.Address, &blk.Nonce, &blk.Time,
+		&blk.Status, &blk.NextBlock)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &blk, nil
+}
+
+// queryBlockAtHeight returns the main chain block at height, or nil above
+// the tip.
+func queryBlockAtHeight(db dbtx, height int) (*BlockDetail, error) {
+	defer observeQuery("queryBlockAtHeight", time.Now())
+
+	var hash string
+	err := db.QueryRow("SELECT block FROM blocks WHERE height = ?", height).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return queryChainBlock(db, hash)
+}
+
+// queryTipBlock returns the last block on the main chain.
+func queryTipBlock(db dbtx) (*Block, error) {
+	defer observeQuery("queryTipBlock", time.Now())
+
+	querySQL := "SELECT id, height, block, prevBlock, address, nonce, time FROM blocks ORDER BY height DESC LIMIT 1"
+
+	var blk Block
+	err := db.QueryRow(querySQL).Scan(&blk.ID, &blk.Height, &blk.BlockContent, &blk.PrevBlock, &blk.Address, &blk.Nonce, &blk.Time)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &blk, nil
+}
+
+// queryBlockTransactions returns the transactions a block collected fees
+// from, followed by its reward.
+func queryBlockTransactions(db dbtx, block string) ([]Transaction, error) {
+	defer observeQuery("queryBlockTransactions", time.Now())
+
+	querySQL := "SELECT id, sender, amount, fee, recipient, time, COALESCE(block, ''), memo, asset FROM transactions WHERE block = ? ORDER BY sender = 'null', id"
+	rows, err := db.Query(querySQL, block)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+
+	for rows.Next() {
+		var txn Transaction
+		if err := rows.Scan(&txn.ID, &txn.Sender, &txn.Amount, &txn.Fee, &txn.Recipient, &txn.Time, &txn.Block, &txn.Memo, &txn.Asset); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txn)
+	}
+
+	return transactions, rows.Err()
+}
+
+// queryRecentBlocks returns up to limit main chain blocks, newest first.
+func queryRecentBlocks(db dbtx, limit int) ([]Block, error) {
+	defer observeQuery("queryRecentBlocks", time.Now())
+
+	querySQL := "SELECT id, height, block, prevBlock, address, nonce, time FROM blocks ORDER BY height DESC LIMIT ?"
+	rows, err := db.Query(querySQL, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []Block
+	for rows.Next() {
+		var blk Block
+		if err := rows.Scan(&blk.ID, &blk.Height, &blk.BlockContent, &blk.PrevBlock, &blk.Address, &blk.Nonce, &blk.Time); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, blk)
+	}
+
+	return blocks, rows.Err()
+}
+
+// queryRecentTransactions returns up to limit transactions, newest first.
+func queryRecentTransactions(db dbtx, limit int) ([]Transaction, error) {
+	defer observeQuery("queryRecentTransactions", time.Now())
+
+	querySQL := "SELECT id, sender, amount, fee, recipient, time, COALESCE(block, ''), memo, asset FROM transactions ORDER BY id DESC LIMIT ?"
+	rows, err := db.Query(querySQL, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		var txn Transaction
+		if err := rows.Scan(&txn.ID, &txn.Sender, &txn.Amount, &txn.Fee, &txn.Recipient, &txn.Time, &txn.Block, &txn.Memo, &txn.Asset); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txn)
+	}
+
+	return transactions, rows.Err()
+}
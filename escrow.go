@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultEscrowExpiry = 7 * 24 * 60 * 60
+	maxEscrowExpiry     = 90 * 24 * 60 * 60
+)
+
+// EscrowRequest opens an escrow funded by the buyer identified by pkey.
+type EscrowRequest struct {
+	Pkey      string `json:"pkey"`
+	Seller    string `json:"seller"`
+	Arbiter   string `json:"arbiter"`
+	Amount    int    `json:"amount"`
+	Fee       int    `json:"fee"`
+	Memo      string `json:"memo"`
+	ExpiresIn int    `json:"expiresIn"`
+}
+
+type EscrowVoteRequest struct {
+	Pkey string `json:"pkey"`
+}
+
+func escrowResponse(e *Escrow) EscrowResponse {
+	return EscrowResponse{
+		ID:      e.ID,
+		Buyer:   e.Buyer,
+		Seller:  e.Seller,
+		Arbiter: e.Arbiter,
+		Amount:  e.Amount,
+		Fee:     e.Fee,
+		Memo:    e.Memo,
+		Votes: EscrowVotes{
+			Buyer:   e.BuyerVote,
+			Seller:  e.SellerVote,
+			Arbiter: e.ArbiterVote,
+		},
+		Status:             e.Status,
+		Created:            e.Created,
+		Expires:            e.Expires,
+		SettledTransaction: e.SettledTransaction,
+		SettledAt:          e.SettledAt,
+	}
+}
+
+func createEscrow(w http.ResponseWriter, r *http.Request) {
+	var req EscrowRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	buyer := generateAddress(req.Pkey)
+
+	if !validateAddress(req.Seller) || !validateAddress(req.Arbiter) {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_address", "invalid address")
+		return
+	}
+
+	if buyer == req.Seller || buyer == req.Arbiter || req.Seller == req.Arbiter {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_parties", "buyer, seller and arbiter must be different addresses")
+		return
+	}
+
+	if req.Amount <= 0 {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_amount", "amount must be positive")
+		return
+	}
+
+	if !checkMemoAndFee(w, r, req.Memo, req.Fee, len(body)) {
+		return
+	}
+
+	if req.ExpiresIn == 0 {
+		req.ExpiresIn = defaultEscrowExpiry
+	}
+	if req.ExpiresIn < 0 || req.ExpiresIn > maxEscrowExpiry {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_expiry", fmt.Sprintf("expiresIn must be between 1 and %d seconds", maxEscrowExpiry))
+		return
+	}
+
+	now := int(time.Now().Unix())
+	e := &Escrow{
+		Buyer:   buyer,
+		Seller:  req.Seller,
+		Arbiter: req.Arbiter,
+		Amount:  req.Amount,
+		Fee:     req.Fee,
+		Memo:    req.Memo,
+		Status:  "open",
+		Created: now,
+		Expires: now + req.ExpiresIn,
+	}
+
+	txID, err := openEscrow(e)
+	switch {
+	case errors.Is(err, errFrozen):
+		writeErrorResponse(w, r, http.StatusForbidden, "frozen", "address frozen")
+		return
+	case errors.Is(err, errInsufficientFunds):
+		writeErrorResponse(w, r, http.StatusBadRequest, "insufficient_funds", "insufficient funds")
+		return
+	case errors.Is(err, errMultisigSender):
+		writeErrorResponse(w, r, http.StatusForbidden, "multisig_sender", "multisig addresses can only be spent from with POST /multisig/transaction")
+		return
+	case err != nil:
+		log.Println("opening escrow failed:", err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	annotate(r, slog.Int64("escrowId", e.ID))
+
+	response := escrowResponse(e)
+	writeResponse(w, r, map[string]interface{}{"ok": true, "transaction": txID, "escrows": []EscrowResponse{response}}, EscrowResult{TransactionID: txID, Escrow: response})
+}
+
+func getEscrow(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "escrow_not_found", "escrow not found")
+		return
+	}
+
+	e, err := queryEscrow(sqliteDatabase, id)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+	if e == nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "escrow_not_found", "escrow not found")
+		return
+	}
+
+	response := escrowResponse(e)
+	writeResponse(w, r, map[string]interface{}{"ok": true, "escrows": []EscrowResponse{response}}, response)
+}
+
+func approveEscrow(w http.ResponseWriter, r *http.Request) {
+	handleEscrowVote(w, r, escrowRelease)
+}
+
+func refundEscrow(w http.ResponseWriter, r *http.Request) {
+	handleEscrowVote(w, r, escrowRefund)
+}
+
+func handleEscrowVote(w http.ResponseWriter, r *http.Request, vote string) {
+	var req EscrowVoteRequest
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "escrow_not_found", "escrow not found")
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	e, err := voteEscrow(id, generateAddress(req.Pkey), vote, int(time.Now().Unix()))
+	switch {
+	case errors.Is(err, errEscrowNotFound):
+		writeErrorResponse(w, r, http.StatusNotFound, "escrow_not_found", "escrow not found")
+		return
+	case errors.Is(err, errNotEscrowParty):
+		writeErrorResponse(w, r, http.StatusForbidden, "not_party", "only the buyer, seller or arbiter can vote on an escrow")
+		return
+	case errors.Is(err, errEscrowSettled):
+		writeErrorResponse(w, r, http.StatusConflict, "escrow_settled", "escrow already settled")
+		return
+	case err != nil:
+		log.Println("escrow vote failed:", err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	annotate(r, slog.Int64("escrowId", e.ID), slog.String("vote", vote))
+
+	response := escrowResponse(e)
+	writeResponse(w, r, map[string]interface{}{"ok": true, "escrows": []EscrowResponse{response}}, response)
+}
+
+// processExpiredEscrows refunds open escrows that have passed their expiry.
+func processExpiredEscrows(now int) {
+	expired, err := queryExpiredEscrows(sqliteDatabase, now)
+	if err != nil {
+		log.Println("scheduler: expired escrows:", err)
+		return
+	}
+
+	for _, id := range expired {
+		if err := expireEscrow(id, now); err != nil {
+			log.Printf("scheduler: escrow %d: %v\n", id, err)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The block explorer is server-rendered from the templates and stylesheet
+// embedded below, so it works offline and needs no JavaScript.
+//
+//go:embed explorer
+var explorerFiles embed.FS
+
+const (
+	explorerBlocks       = 10
+	explorerTransactions = 20
+)
+
+var explorerTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"formatTime": formatExplorerTime,
+	"short":      shortHash,
+	"isAddress":  validateAddress,
+	"pathEscape": url.PathEscape,
+}).ParseFS(explorerFiles, "explorer/templates/*.html"))
+
+// explorerStatic serves the stylesheet under /explorer/static/, without
+// directory listings.
+func explorerStatic() http.Handler {
+	static, err := fs.Sub(explorerFiles, "explorer/static")
+	if err != nil {
+		log.Fatal(err)
+	}
+	files := http.StripPrefix("/explorer/static/", http.FileServerFS(static))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// explorerPage holds what every page's header needs. Query fills the
+// search box.
+type explorerPage struct {
+	Title string
+	Query string
+}
+
+type explorerHome struct {
+	explorerPage
+	Tip          *Block
+	Work         string
+	Supply       *Supply
+	Addresses    int
+	Assets       []Asset
+	Blocks       []Block
+	Transactions []TransactionResponse
+}
+
+type explorerBlock struct {
+	explorerPage
+	Block         *BlockDetail
+	Confirmations int
+	Transactions  []TransactionResponse
+}
+
+type explorerAddress struct {
+	explorerPage
+	Address      string
+	Balance      int
+	Locked       int
+	Frozen       bool
+	Holdings     []Holding
+	Transactions []TransactionResponse
+}
+
+type explorerTransaction struct {
+	explorerPage
+	Transaction   TransactionResponse
+	Height        int
+	Confirmations int
+}
+
+type explorerError struct {
+	explorerPage
+	Status  int
+	Message string
+}
+
+// formatExplorerTime formats Unix seconds, which blocks store as an int and
+// transactions as an int64.
+func formatExplorerTime(t interface{}) string {
+	var seconds int64
+	switch t := t.(type) {
+	case int:
+		seconds = int64(t)
+	case int64:
+		seconds = t
+	}
+	return time.Unix(seconds, 0).UTC().Format("2006-01-02 15:04:05 UTC")
+}
+
+func shortHash(hash string) string {
+	if len(hash) <= 16 {
+		return hash
+	}
+	return hash[:16] + "…"
+}
+
+// explorerTransactionList converts transactions for display, optionally
+// reversing them so the newest comes first.
+func explorerTransactionList(transactions []Transaction, reverse bool) []TransactionResponse {
+	result := transactionListResponse(transactions).Transactions
+	if reverse {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+	return result
+}
+
+// renderExplorer executes a page template into a buffer first, so a
+// template error becomes a 500 instead of a half-written page.
+func renderExplorer(w http.ResponseWriter, status int, name string, data interface{}) {
+	var buf bytes.Buffer
+	if err := explorerTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+		log.Println("rendering explorer page failed:", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+func renderExplorerError(w http.ResponseWriter, status int, query, message string) {
+	page := explorerError{
+		explorerPage: explorerPage{Title: http.StatusText(status), Query: query},
+		Status:       status,
+		Message:      message,
+	}
+	renderExplorer(w, status, "error", page)
+}
+
+// getExplorerHome shows the chain tip, supply statistics and the latest
+// blocks and transactions.
+func getExplorerHome(w http.ResponseWriter, r *http.Request) {
+	tip, err := queryTipBlock(sqliteDatabase)
+	if err != nil || tip == nil {
+		renderExplorerError(w, http.StatusInternalServerError, "", "The chain could not be read.")
+		return
+	}
+
+	supply, err := querySupply(sqliteDatabase, "")
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, "", "The supply could not be read.")
+		return
+	}
+
+	addresses, err := queryAddressCount(sqliteDatabase)
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, "", "The addresses could not be read.")
+		return
+	}
+
+	assets, err := queryAssets(sqliteDatabase)
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, "", "The assets could not be read.")
+		return
+	}
+
+	blocks, err := queryRecentBlocks(sqliteDatabase, explorerBlocks)
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, "", "The blocks could not be read.")
+		return
+	}
+
+	transactions, err := queryRecentTransactions(sqliteDatabase, explorerTransactions)
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, "", "The transactions could not be read.")
+		return
+	}
+
+	work, err := queryChainWork(sqliteDatabase)
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, "", "The chain work could not be read.")
+		return
+	}
+
+	renderExplorer(w, http.StatusOK, "home", explorerHome{
+		explorerPage: explorerPage{Title: "Explorer"},
+		Tip:          tip,
+		Work:         work.String(),
+		Supply:       supply,
+		Addresses:    addresses,
+		Assets:       assets,
+		Blocks:       blocks,
+		Transactions: explorerTransactionList(transactions, false),
+	})
+}
+
+func getExplorerBlock(w http.ResponseWriter, r *http.Request) {
+	hash := r.PathValue("hash")
+
+	blk, err := queryChainBlock(sqliteDatabase, hash)
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, hash, "The block could not be read.")
+		return
+	}
+	if blk == nil {
+		renderExplorerError(w, http.StatusNotFound, hash, "No block has this hash.")
+		return
+	}
+
+	transactions, err := queryBlockTransactions(sqliteDatabase, hash)
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, hash, "The block's transactions could not be read.")
+		return
+	}
+
+	page := explorerBlock{
+		explorerPage: explorerPage{Title: "Block " + strconv.Itoa(blk.Height)},
+		Block:        blk,
+		Transactions: explorerTransactionList(transactions, false),
+	}
+	if blk.Status == "main" {
+		height, err := queryChainHeight(sqliteDatabase)
+		if err != nil {
+			renderExplorerError(w, http.StatusInternalServerError, hash, "The chain could not be read.")
+			return
+		}
+		page.Confirmations = height - blk.Height + 1
+	}
+
+	renderExplorer(w, http.StatusOK, "block", page)
+}
+
+func getExplorerAddress(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	if !validateAddress(address) {
+		renderExplorerError(w, http.StatusBadRequest, address, "This is not a valid address.")
+		return
+	}
+
+	locked, err := queryLocked(sqliteDatabase, address)
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, address, "The address could not be read.")
+		return
+	}
+
+	holdings, err := queryHoldings(sqliteDatabase, address)
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, address, "The address could not be read.")
+		return
+	}
+
+	transactions, err := queryAddressTransactions(sqliteDatabase, address, "")
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, address, "The address's transactions could not be read.")
+		return
+	}
+
+	renderExplorer(w, http.StatusOK, "address", explorerAddress{
+		explorerPage: explorerPage{Title: "Address " + address},
+		Address:      address,
+		Balance:      queryAddress(sqliteDatabase, address),
+		Locked:       locked,
+		Frozen:       queryAddressFrozen(sqliteDatabase, address),
+		Holdings:     holdings,
+		Transactions: explorerTransactionList(transactions, true),
+	})
+}
+
+func getExplorerTransaction(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		renderExplorerError(w, http.StatusBadRequest, id, "Transaction IDs are whole numbers.")
+		return
+	}
+
+	txn, err := queryTransaction(sqliteDatabase, id)
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, id, "The transaction could not be read.")
+		return
+	}
+	if txn == nil {
+		renderExplorerError(w, http.StatusNotFound, id, "No transaction has this ID.")
+		return
+	}
+
+	page := explorerTransaction{
+		explorerPage: explorerPage{Title: "Transaction " + id},
+		Transaction:  transactionResponse(txn),
+		Height:       -1,
+	}
+	if txn.Block != "" {
+		blk, err := queryChainBlock(sqliteDatabase, txn.Block)
+		if err != nil {
+			renderExplorerError(w, http.StatusInternalServerError, id, "The transaction's block could not be read.")
+			return
+		}
+		height, err := queryChainHeight(sqliteDatabase)
+		if err != nil {
+			renderExplorerError(w, http.StatusInternalServerError, id, "The chain could not be read.")
+			return
+		}
+		if blk != nil {
+			page.Height = blk.Height
+			page.Confirmations = height - blk.Height + 1
+		}
+	}
+
+	renderExplorer(w, http.StatusOK, "transaction", page)
+}
+
+// searchExplorer redirects to the address, transaction or block matching
+// q. Addresses are 12 hex digits, or 16 for multisig addresses, and
+// transaction IDs are decimal, so a query is tried as an address, then a
+// transaction ID, then a block hash.
+func searchExplorer(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+
+	if (len(q) == 12 || len(q) == multisigAddressLength) && validateAddress(q) {
+		http.Redirect(w, r, "/explorer/address/"+url.PathEscape(strings.ToLower(q)), http.StatusFound)
+		return
+	}
+
+	if _, err := strconv.ParseInt(q, 10, 64); err == nil {
+		txn, err := queryTransaction(sqliteDatabase, q)
+		if err != nil {
+			renderExplorerError(w, http.StatusInternalServerError, q, "The transaction could not be read.")
+			return
+		}
+		if txn != nil {
+			http.Redirect(w, r, "/explorer/transaction/"+q, http.StatusFound)
+			return
+		}
+	}
+
+	blk, err := queryAnyBlock(sqliteDatabase, q)
+	if err != nil {
+		renderExplorerError(w, http.StatusInternalServerError, q, "The block could not be read.")
+		return
+	}
+	if blk != nil {
+		http.Redirect(w, r, "/explorer/block/"+url.PathEscape(q), http.StatusFound)
+		return
+	}
+
+	renderExplorerError(w, http.StatusNotFound, q, "No address, transaction or block matches your search.")
+}
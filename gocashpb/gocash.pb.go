@@ -0,0 +1,1749 @@
+// The go-cash gRPC API. It offers the same ledger and block operations as
+// the JSON HTTP API, backed by the same storage and validation, plus
+// server-streaming subscriptions built on the ledger change feed.
+//
+// Regenerate the Go code after editing with `go generate` in the
+// repository root.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: gocash.proto
+
+package gocashpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Holding struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// GC for the native coin, otherwise an asset ID.
+	Asset         string `protobuf:"bytes,1,opt,name=asset,proto3" json:"asset,omitempty"`
+	Balance       int64  `protobuf:"varint,2,opt,name=balance,proto3" json:"balance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Holding) Reset() {
+	*x = Holding{}
+	mi := &file_gocash_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Holding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Holding) ProtoMessage() {}
+
+func (x *Holding) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Holding.ProtoReflect.Descriptor instead.
+func (*Holding) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Holding) GetAsset() string {
+	if x != nil {
+		return x.Asset
+	}
+	return ""
+}
+
+func (x *Holding) GetBalance() int64 {
+	if x != nil {
+		return x.Balance
+	}
+	return 0
+}
+
+type Address struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Address string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Balance int64                  `protobuf:"varint,2,opt,name=balance,proto3" json:"balance,omitempty"`
+	// Native coin held in time-locked transfers to this address. Only set by
+	// GetAddress.
+	Locked int64 `protobuf:"varint,3,opt,name=locked,proto3" json:"locked,omitempty"`
+	// Balances by asset, starting with the native coin. Only set by
+	// GetAddress.
+	Holdings      []*Holding `protobuf:"bytes,4,rep,name=holdings,proto3" json:"holdings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Address) Reset() {
+	*x = Address{}
+	mi := &file_gocash_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Address) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Address) ProtoMessage() {}
+
+func (x *Address) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Address.ProtoReflect.Descriptor instead.
+func (*Address) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Address) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Address) GetBalance() int64 {
+	if x != nil {
+		return x.Balance
+	}
+	return 0
+}
+
+func (x *Address) GetLocked() int64 {
+	if x != nil {
+		return x.Locked
+	}
+	return 0
+}
+
+func (x *Address) GetHoldings() []*Holding {
+	if x != nil {
+		return x.Holdings
+	}
+	return nil
+}
+
+type Transaction struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// An address, or null for block rewards, or one of the pseudo-accounts
+	// adjustment, locked, escrow and issuance.
+	Sender    string `protobuf:"bytes,2,opt,name=sender,proto3" json:"sender,omitempty"`
+	Recipient string `protobuf:"bytes,3,opt,name=recipient,proto3" json:"recipient,omitempty"`
+	Amount    int64  `protobuf:"varint,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	Fee       int64  `protobuf:"varint,5,opt,name=fee,proto3" json:"fee,omitempty"`
+	// Unix time in seconds.
+	Time int64 `protobuf:"varint,6,opt,name=time,proto3" json:"time,omitempty"`
+	// The block that collected the fee, or empty while pending.
+	Block         string `protobuf:"bytes,7,opt,name=block,proto3" json:"block,omitempty"`
+	Memo          string `protobuf:"bytes,8,opt,name=memo,proto3" json:"memo,omitempty"`
+	Asset         string `protobuf:"bytes,9,opt,name=asset,proto3" json:"asset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_gocash_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Transaction) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Transaction) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *Transaction) GetRecipient() string {
+	if x != nil {
+		return x.Recipient
+	}
+	return ""
+}
+
+func (x *Transaction) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Transaction) GetFee() int64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *Transaction) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *Transaction) GetBlock() string {
+	if x != nil {
+		return x.Block
+	}
+	return ""
+}
+
+func (x *Transaction) GetMemo() string {
+	if x != nil {
+		return x.Memo
+	}
+	return ""
+}
+
+func (x *Transaction) GetAsset() string {
+	if x != nil {
+		return x.Asset
+	}
+	return ""
+}
+
+type Block struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Hash      string                 `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	PrevBlock string                 `protobuf:"bytes,3,opt,name=prev_block,json=prevBlock,proto3" json:"prev_block,omitempty"`
+	// The miner paid the reward.
+	Address string `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	Nonce   string `protobuf:"bytes,5,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	// Unix time in seconds.
+	Time int64 `protobuf:"varint,6,opt,name=time,proto3" json:"time,omitempty"`
+	// The genesis block is at height 0.
+	Height        int64 `protobuf:"varint,7,opt,name=height,proto3" json:"height,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Block) Reset() {
+	*x = Block{}
+	mi := &file_gocash_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Block) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Block) ProtoMessage() {}
+
+func (x *Block) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Block.ProtoReflect.Descriptor instead.
+func (*Block) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Block) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Block) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Block) GetPrevBlock() string {
+	if x != nil {
+		return x.PrevBlock
+	}
+	return ""
+}
+
+func (x *Block) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Block) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+func (x *Block) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *Block) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+type Reorg struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Time          int64                  `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	ForkBlock     string                 `protobuf:"bytes,3,opt,name=fork_block,json=forkBlock,proto3" json:"fork_block,omitempty"`
+	OldTip        string                 `protobuf:"bytes,4,opt,name=old_tip,json=oldTip,proto3" json:"old_tip,omitempty"`
+	NewTip        string                 `protobuf:"bytes,5,opt,name=new_tip,json=newTip,proto3" json:"new_tip,omitempty"`
+	Orphaned      int64                  `protobuf:"varint,6,opt,name=orphaned,proto3" json:"orphaned,omitempty"`
+	Adopted       int64                  `protobuf:"varint,7,opt,name=adopted,proto3" json:"adopted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Reorg) Reset() {
+	*x = Reorg{}
+	mi := &file_gocash_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Reorg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reorg) ProtoMessage() {}
+
+func (x *Reorg) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reorg.ProtoReflect.Descriptor instead.
+func (*Reorg) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Reorg) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Reorg) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *Reorg) GetForkBlock() string {
+	if x != nil {
+		return x.ForkBlock
+	}
+	return ""
+}
+
+func (x *Reorg) GetOldTip() string {
+	if x != nil {
+		return x.OldTip
+	}
+	return ""
+}
+
+func (x *Reorg) GetNewTip() string {
+	if x != nil {
+		return x.NewTip
+	}
+	return ""
+}
+
+func (x *Reorg) GetOrphaned() int64 {
+	if x != nil {
+		return x.Orphaned
+	}
+	return 0
+}
+
+func (x *Reorg) GetAdopted() int64 {
+	if x != nil {
+		return x.Adopted
+	}
+	return 0
+}
+
+type Supply struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Asset             string                 `protobuf:"bytes,1,opt,name=asset,proto3" json:"asset,omitempty"`
+	TotalSupply       int64                  `protobuf:"varint,2,opt,name=total_supply,json=totalSupply,proto3" json:"total_supply,omitempty"`
+	CirculatingSupply int64                  `protobuf:"varint,3,opt,name=circulating_supply,json=circulatingSupply,proto3" json:"circulating_supply,omitempty"`
+	// Native coin only.
+	PendingFees int64 `protobuf:"varint,4,opt,name=pending_fees,json=pendingFees,proto3" json:"pending_fees,omitempty"`
+	FeesPaid    int64 `protobuf:"varint,5,opt,name=fees_paid,json=feesPaid,proto3" json:"fees_paid,omitempty"`
+	Locked      int64 `protobuf:"varint,6,opt,name=locked,proto3" json:"locked,omitempty"`
+	Escrowed    int64 `protobuf:"varint,7,opt,name=escrowed,proto3" json:"escrowed,omitempty"`
+	// Assets only.
+	Mintable      bool `protobuf:"varint,8,opt,name=mintable,proto3" json:"mintable,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Supply) Reset() {
+	*x = Supply{}
+	mi := &file_gocash_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Supply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Supply) ProtoMessage() {}
+
+func (x *Supply) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Supply.ProtoReflect.Descriptor instead.
+func (*Supply) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Supply) GetAsset() string {
+	if x != nil {
+		return x.Asset
+	}
+	return ""
+}
+
+func (x *Supply) GetTotalSupply() int64 {
+	if x != nil {
+		return x.TotalSupply
+	}
+	return 0
+}
+
+func (x *Supply) GetCirculatingSupply() int64 {
+	if x != nil {
+		return x.CirculatingSupply
+	}
+	return 0
+}
+
+func (x *Supply) GetPendingFees() int64 {
+	if x != nil {
+		return x.PendingFees
+	}
+	return 0
+}
+
+func (x *Supply) GetFeesPaid() int64 {
+	if x != nil {
+		return x.FeesPaid
+	}
+	return 0
+}
+
+func (x *Supply) GetLocked() int64 {
+	if x != nil {
+		return x.Locked
+	}
+	return 0
+}
+
+func (x *Supply) GetEscrowed() int64 {
+	if x != nil {
+		return x.Escrowed
+	}
+	return 0
+}
+
+func (x *Supply) GetMintable() bool {
+	if x != nil {
+		return x.Mintable
+	}
+	return false
+}
+
+type GetAddressRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetAddressRequest) Reset() {
+	*x = GetAddressRequest{}
+	mi := &file_gocash_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetAddressRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetAddressRequest) ProtoMessage() {}
+
+func (x *GetAddressRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetAddressRequest.ProtoReflect.Descriptor instead.
+func (*GetAddressRequest) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetAddressRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+type ListAddressesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAddressesRequest) Reset() {
+	*x = ListAddressesRequest{}
+	mi := &file_gocash_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAddressesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAddressesRequest) ProtoMessage() {}
+
+func (x *ListAddressesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAddressesRequest.ProtoReflect.Descriptor instead.
+func (*ListAddressesRequest) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{7}
+}
+
+type ListAddressesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Addresses     []*Address             `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAddressesResponse) Reset() {
+	*x = ListAddressesResponse{}
+	mi := &file_gocash_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAddressesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAddressesResponse) ProtoMessage() {}
+
+func (x *ListAddressesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAddressesResponse.ProtoReflect.Descriptor instead.
+func (*ListAddressesResponse) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListAddressesResponse) GetAddresses() []*Address {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+type SendTransactionRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The sender's private key.
+	Pkey string `protobuf:"bytes,1,opt,name=pkey,proto3" json:"pkey,omitempty"`
+	// The recipient.
+	Address string `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Amount  int64  `protobuf:"varint,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	Fee     int64  `protobuf:"varint,4,opt,name=fee,proto3" json:"fee,omitempty"`
+	Memo    string `protobuf:"bytes,5,opt,name=memo,proto3" json:"memo,omitempty"`
+	// The asset to send. Empty sends the native coin.
+	Asset         string `protobuf:"bytes,6,opt,name=asset,proto3" json:"asset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendTransactionRequest) Reset() {
+	*x = SendTransactionRequest{}
+	mi := &file_gocash_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendTransactionRequest) ProtoMessage() {}
+
+func (x *SendTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendTransactionRequest.ProtoReflect.Descriptor instead.
+func (*SendTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SendTransactionRequest) GetPkey() string {
+	if x != nil {
+		return x.Pkey
+	}
+	return ""
+}
+
+func (x *SendTransactionRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *SendTransactionRequest) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *SendTransactionRequest) GetFee() int64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+func (x *SendTransactionRequest) GetMemo() string {
+	if x != nil {
+		return x.Memo
+	}
+	return ""
+}
+
+func (x *SendTransactionRequest) GetAsset() string {
+	if x != nil {
+		return x.Asset
+	}
+	return ""
+}
+
+type SendTransactionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TransactionId int64                  `protobuf:"varint,1,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	// Identifies the transaction across nodes.
+	Hash          string `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	Asset         string `protobuf:"bytes,3,opt,name=asset,proto3" json:"asset,omitempty"`
+	Amount        int64  `protobuf:"varint,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	Fee           int64  `protobuf:"varint,5,opt,name=fee,proto3" json:"fee,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendTransactionResponse) Reset() {
+	*x = SendTransactionResponse{}
+	mi := &file_gocash_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendTransactionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendTransactionResponse) ProtoMessage() {}
+
+func (x *SendTransactionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendTransactionResponse.ProtoReflect.Descriptor instead.
+func (*SendTransactionResponse) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SendTransactionResponse) GetTransactionId() int64 {
+	if x != nil {
+		return x.TransactionId
+	}
+	return 0
+}
+
+func (x *SendTransactionResponse) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *SendTransactionResponse) GetAsset() string {
+	if x != nil {
+		return x.Asset
+	}
+	return ""
+}
+
+func (x *SendTransactionResponse) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *SendTransactionResponse) GetFee() int64 {
+	if x != nil {
+		return x.Fee
+	}
+	return 0
+}
+
+type GetTransactionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTransactionRequest) Reset() {
+	*x = GetTransactionRequest{}
+	mi := &file_gocash_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTransactionRequest) ProtoMessage() {}
+
+func (x *GetTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTransactionRequest.ProtoReflect.Descriptor instead.
+func (*GetTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetTransactionRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListTransactionsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only return transactions sent or received by this address.
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// With address, only return transactions with this memo.
+	Reference     string `protobuf:"bytes,2,opt,name=reference,proto3" json:"reference,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTransactionsRequest) Reset() {
+	*x = ListTransactionsRequest{}
+	mi := &file_gocash_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTransactionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTransactionsRequest) ProtoMessage() {}
+
+func (x *ListTransactionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTransactionsRequest.ProtoReflect.Descriptor instead.
+func (*ListTransactionsRequest) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListTransactionsRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *ListTransactionsRequest) GetReference() string {
+	if x != nil {
+		return x.Reference
+	}
+	return ""
+}
+
+type ListTransactionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transactions  []*Transaction         `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTransactionsResponse) Reset() {
+	*x = ListTransactionsResponse{}
+	mi := &file_gocash_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTransactionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTransactionsResponse) ProtoMessage() {}
+
+func (x *ListTransactionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTransactionsResponse.ProtoReflect.Descriptor instead.
+func (*ListTransactionsResponse) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListTransactionsResponse) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+type GetSupplyRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// An asset ID. Empty returns the native coin's supply.
+	Asset         string `protobuf:"bytes,1,opt,name=asset,proto3" json:"asset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSupplyRequest) Reset() {
+	*x = GetSupplyRequest{}
+	mi := &file_gocash_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSupplyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSupplyRequest) ProtoMessage() {}
+
+func (x *GetSupplyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSupplyRequest.ProtoReflect.Descriptor instead.
+func (*GetSupplyRequest) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetSupplyRequest) GetAsset() string {
+	if x != nil {
+		return x.Asset
+	}
+	return ""
+}
+
+type SubmitBlockRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The hex SHA-256 of prev_block, address and nonce concatenated.
+	Hash          string `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	PrevBlock     string `protobuf:"bytes,2,opt,name=prev_block,json=prevBlock,proto3" json:"prev_block,omitempty"`
+	Address       string `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	Nonce         string `protobuf:"bytes,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitBlockRequest) Reset() {
+	*x = SubmitBlockRequest{}
+	mi := &file_gocash_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitBlockRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitBlockRequest) ProtoMessage() {}
+
+func (x *SubmitBlockRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitBlockRequest.ProtoReflect.Descriptor instead.
+func (*SubmitBlockRequest) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SubmitBlockRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *SubmitBlockRequest) GetPrevBlock() string {
+	if x != nil {
+		return x.PrevBlock
+	}
+	return ""
+}
+
+func (x *SubmitBlockRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *SubmitBlockRequest) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+type SubmitBlockResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The block reward plus collected fees. Zero for side branch blocks.
+	Reward int64 `protobuf:"varint,1,opt,name=reward,proto3" json:"reward,omitempty"`
+	// "main" or "side".
+	Branch string `protobuf:"bytes,2,opt,name=branch,proto3" json:"branch,omitempty"`
+	// Set when the block made a side branch the main chain.
+	Reorg *Reorg `protobuf:"bytes,3,opt,name=reorg,proto3" json:"reorg,omitempty"`
+	// Set, alone, when the block had already been accepted.
+	Known         bool `protobuf:"varint,4,opt,name=known,proto3" json:"known,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitBlockResponse) Reset() {
+	*x = SubmitBlockResponse{}
+	mi := &file_gocash_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitBlockResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitBlockResponse) ProtoMessage() {}
+
+func (x *SubmitBlockResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitBlockResponse.ProtoReflect.Descriptor instead.
+func (*SubmitBlockResponse) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SubmitBlockResponse) GetReward() int64 {
+	if x != nil {
+		return x.Reward
+	}
+	return 0
+}
+
+func (x *SubmitBlockResponse) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+func (x *SubmitBlockResponse) GetReorg() *Reorg {
+	if x != nil {
+		return x.Reorg
+	}
+	return nil
+}
+
+func (x *SubmitBlockResponse) GetKnown() bool {
+	if x != nil {
+		return x.Known
+	}
+	return false
+}
+
+type GetTipRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTipRequest) Reset() {
+	*x = GetTipRequest{}
+	mi := &file_gocash_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTipRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTipRequest) ProtoMessage() {}
+
+func (x *GetTipRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTipRequest.ProtoReflect.Descriptor instead.
+func (*GetTipRequest) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{17}
+}
+
+type ListBlocksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBlocksRequest) Reset() {
+	*x = ListBlocksRequest{}
+	mi := &file_gocash_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBlocksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBlocksRequest) ProtoMessage() {}
+
+func (x *ListBlocksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBlocksRequest.ProtoReflect.Descriptor instead.
+func (*ListBlocksRequest) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{18}
+}
+
+type ListBlocksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Blocks        []*Block               `protobuf:"bytes,1,rep,name=blocks,proto3" json:"blocks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBlocksResponse) Reset() {
+	*x = ListBlocksResponse{}
+	mi := &file_gocash_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBlocksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBlocksResponse) ProtoMessage() {}
+
+func (x *ListBlocksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBlocksResponse.ProtoReflect.Descriptor instead.
+func (*ListBlocksResponse) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListBlocksResponse) GetBlocks() []*Block {
+	if x != nil {
+		return x.Blocks
+	}
+	return nil
+}
+
+type SubscribeBlocksRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Resume after this change feed cursor. Zero starts with the next change.
+	Since         int64 `protobuf:"varint,1,opt,name=since,proto3" json:"since,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeBlocksRequest) Reset() {
+	*x = SubscribeBlocksRequest{}
+	mi := &file_gocash_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeBlocksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeBlocksRequest) ProtoMessage() {}
+
+func (x *SubscribeBlocksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeBlocksRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeBlocksRequest) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *SubscribeBlocksRequest) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+type BlockEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The change feed cursor, to resume from after a disconnect.
+	Cursor int64 `protobuf:"varint,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// "block" or "block_disconnected".
+	Type          string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Time          int64  `protobuf:"varint,3,opt,name=time,proto3" json:"time,omitempty"`
+	Block         *Block `protobuf:"bytes,4,opt,name=block,proto3" json:"block,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlockEvent) Reset() {
+	*x = BlockEvent{}
+	mi := &file_gocash_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlockEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockEvent) ProtoMessage() {}
+
+func (x *BlockEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockEvent.ProtoReflect.Descriptor instead.
+func (*BlockEvent) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *BlockEvent) GetCursor() int64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+func (x *BlockEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *BlockEvent) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *BlockEvent) GetBlock() *Block {
+	if x != nil {
+		return x.Block
+	}
+	return nil
+}
+
+type SubscribeAddressRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Address string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// Resume after this change feed cursor. Zero starts with the next change.
+	Since         int64 `protobuf:"varint,2,opt,name=since,proto3" json:"since,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeAddressRequest) Reset() {
+	*x = SubscribeAddressRequest{}
+	mi := &file_gocash_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeAddressRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeAddressRequest) ProtoMessage() {}
+
+func (x *SubscribeAddressRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeAddressRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeAddressRequest) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *SubscribeAddressRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *SubscribeAddressRequest) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+type AddressEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The change feed cursor, to resume from after a disconnect.
+	Cursor int64 `protobuf:"varint,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// "transfer", "mint" or "address".
+	Type string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Time int64  `protobuf:"varint,3,opt,name=time,proto3" json:"time,omitempty"`
+	// Set for transfer and mint events.
+	Transaction   *Transaction `protobuf:"bytes,4,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddressEvent) Reset() {
+	*x = AddressEvent{}
+	mi := &file_gocash_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddressEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddressEvent) ProtoMessage() {}
+
+func (x *AddressEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_gocash_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddressEvent.ProtoReflect.Descriptor instead.
+func (*AddressEvent) Descriptor() ([]byte, []int) {
+	return file_gocash_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *AddressEvent) GetCursor() int64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+func (x *AddressEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *AddressEvent) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *AddressEvent) GetTransaction() *Transaction {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+var File_gocash_proto protoreflect.FileDescriptor
+
+const file_gocash_proto_rawDesc = "" +
+	"\n" +
+	"\fgocash.proto\x12\tgocash.v1\"9\n" +
+	"\aHolding\x12\x14\n" +
+	"\x05asset\x18\x01 \x01(\tR\x05asset\x12\x18\n" +
+	"\abalance\x18\x02 \x01(\x03R\abalance\"\x85\x01\n" +
+	"\aAddress\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x18\n" +
+	"\abalance\x18\x02 \x01(\x03R\abalance\x12\x16\n" +
+	"\x06locked\x18\x03 \x01(\x03R\x06locked\x12.\n" +
+	"\bholdings\x18\x04 \x03(\v2\x12.gocash.v1.HoldingR\bholdings\"\xd1\x01\n" +
+	"\vTransaction\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x16\n" +
+	"\x06sender\x18\x02 \x01(\tR\x06sender\x12\x1c\n" +
+	"\trecipient\x18\x03 \x01(\tR\trecipient\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\x03R\x06amount\x12\x10\n" +
+	"\x03fee\x18\x05 \x01(\x03R\x03fee\x12\x12\n" +
+	"\x04time\x18\x06 \x01(\x03R\x04time\x12\x14\n" +
+	"\x05block\x18\a \x01(\tR\x05block\x12\x12\n" +
+	"\x04memo\x18\b \x01(\tR\x04memo\x12\x14\n" +
+	"\x05asset\x18\t \x01(\tR\x05asset\"\xa6\x01\n" +
+	"\x05Block\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04hash\x18\x02 \x01(\tR\x04hash\x12\x1d\n" +
+	"\n" +
+	"prev_block\x18\x03 \x01(\tR\tprevBlock\x12\x18\n" +
+	"\aaddress\x18\x04 \x01(\tR\aaddress\x12\x14\n" +
+	"\x05nonce\x18\x05 \x01(\tR\x05nonce\x12\x12\n" +
+	"\x04time\x18\x06 \x01(\x03R\x04time\x12\x16\n" +
+	"\x06height\x18\a \x01(\x03R\x06height\"\xb2\x01\n" +
+	"\x05Reorg\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04time\x18\x02 \x01(\x03R\x04time\x12\x1d\n" +
+	"\n" +
+	"fork_block\x18\x03 \x01(\tR\tforkBlock\x12\x17\n" +
+	"\aold_tip\x18\x04 \x01(\tR\x06oldTip\x12\x17\n" +
+	"\anew_tip\x18\x05 \x01(\tR\x06newTip\x12\x1a\n" +
+	"\borphaned\x18\x06 \x01(\x03R\borphaned\x12\x18\n" +
+	"\aadopted\x18\a \x01(\x03R\aadopted\"\x80\x02\n" +
+	"\x06Supply\x12\x14\n" +
+	"\x05asset\x18\x01 \x01(\tR\x05asset\x12!\n" +
+	"\ftotal_supply\x18\x02 \x01(\x03R\vtotalSupply\x12-\n" +
+	"\x12circulating_supply\x18\x03 \x01(\x03R\x11circulatingSupply\x12!\n" +
+	"\fpending_fees\x18\x04 \x01(\x03R\vpendingFees\x12\x1b\n" +
+	"\tfees_paid\x18\x05 \x01(\x03R\bfeesPaid\x12\x16\n" +
+	"\x06locked\x18\x06 \x01(\x03R\x06locked\x12\x1a\n" +
+	"\bescrowed\x18\a \x01(\x03R\bescrowed\x12\x1a\n" +
+	"\bmintable\x18\b \x01(\bR\bmintable\"-\n" +
+	"\x11GetAddressRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\"\x16\n" +
+	"\x14ListAddressesRequest\"I\n" +
+	"\x15ListAddressesResponse\x120\n" +
+	"\taddresses\x18\x01 \x03(\v2\x12.gocash.v1.AddressR\taddresses\"\x9a\x01\n" +
+	"\x16SendTransactionRequest\x12\x12\n" +
+	"\x04pkey\x18\x01 \x01(\tR\x04pkey\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\x03R\x06amount\x12\x10\n" +
+	"\x03fee\x18\x04 \x01(\x03R\x03fee\x12\x12\n" +
+	"\x04memo\x18\x05 \x01(\tR\x04memo\x12\x14\n" +
+	"\x05asset\x18\x06 \x01(\tR\x05asset\"\x94\x01\n" +
+	"\x17SendTransactionResponse\x12%\n" +
+	"\x0etransaction_id\x18\x01 \x01(\x03R\rtransactionId\x12\x12\n" +
+	"\x04hash\x18\x02 \x01(\tR\x04hash\x12\x14\n" +
+	"\x05asset\x18\x03 \x01(\tR\x05asset\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\x03R\x06amount\x12\x10\n" +
+	"\x03fee\x18\x05 \x01(\x03R\x03fee\"'\n" +
+	"\x15GetTransactionRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"Q\n" +
+	"\x17ListTransactionsRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x1c\n" +
+	"\treference\x18\x02 \x01(\tR\treference\"V\n" +
+	"\x18ListTransactionsResponse\x12:\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x16.gocash.v1.TransactionR\ftransactions\"(\n" +
+	"\x10GetSupplyRequest\x12\x14\n" +
+	"\x05asset\x18\x01 \x01(\tR\x05asset\"w\n" +
+	"\x12SubmitBlockRequest\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\tR\x04hash\x12\x1d\n" +
+	"\n" +
+	"prev_block\x18\x02 \x01(\tR\tprevBlock\x12\x18\n" +
+	"\aaddress\x18\x03 \x01(\tR\aaddress\x12\x14\n" +
+	"\x05nonce\x18\x04 \x01(\tR\x05nonce\"\x83\x01\n" +
+	"\x13SubmitBlockResponse\x12\x16\n" +
+	"\x06reward\x18\x01 \x01(\x03R\x06reward\x12\x16\n" +
+	"\x06branch\x18\x02 \x01(\tR\x06branch\x12&\n" +
+	"\x05reorg\x18\x03 \x01(\v2\x10.gocash.v1.ReorgR\x05reorg\x12\x14\n" +
+	"\x05known\x18\x04 \x01(\bR\x05known\"\x0f\n" +
+	"\rGetTipRequest\"\x13\n" +
+	"\x11ListBlocksRequest\">\n" +
+	"\x12ListBlocksResponse\x12(\n" +
+	"\x06blocks\x18\x01 \x03(\v2\x10.gocash.v1.BlockR\x06blocks\".\n" +
+	"\x16SubscribeBlocksRequest\x12\x14\n" +
+	"\x05since\x18\x01 \x01(\x03R\x05since\"t\n" +
+	"\n" +
+	"BlockEvent\x12\x16\n" +
+	"\x06cursor\x18\x01 \x01(\x03R\x06cursor\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04time\x18\x03 \x01(\x03R\x04time\x12&\n" +
+	"\x05block\x18\x04 \x01(\v2\x10.gocash.v1.BlockR\x05block\"I\n" +
+	"\x17SubscribeAddressRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x14\n" +
+	"\x05since\x18\x02 \x01(\x03R\x05since\"\x88\x01\n" +
+	"\fAddressEvent\x12\x16\n" +
+	"\x06cursor\x18\x01 \x01(\x03R\x06cursor\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04time\x18\x03 \x01(\x03R\x04time\x128\n" +
+	"\vtransaction\x18\x04 \x01(\v2\x16.gocash.v1.TransactionR\vtransaction2\xcd\x06\n" +
+	"\x06GoCash\x12>\n" +
+	"\n" +
+	"GetAddress\x12\x1c.gocash.v1.GetAddressRequest\x1a\x12.gocash.v1.Address\x12R\n" +
+	"\rListAddresses\x12\x1f.gocash.v1.ListAddressesRequest\x1a .gocash.v1.ListAddressesResponse\x12X\n" +
+	"\x0fSendTransaction\x12!.gocash.v1.SendTransactionRequest\x1a\".gocash.v1.SendTransactionResponse\x12J\n" +
+	"\x0eGetTransaction\x12 .gocash.v1.GetTransactionRequest\x1a\x16.gocash.v1.Transaction\x12[\n" +
+	"\x10ListTransactions\x12\".gocash.v1.ListTransactionsRequest\x1a#.gocash.v1.ListTransactionsResponse\x12;\n" +
+	"\tGetSupply\x12\x1b.gocash.v1.GetSupplyRequest\x1a\x11.gocash.v1.Supply\x12L\n" +
+	"\vSubmitBlock\x12\x1d.gocash.v1.SubmitBlockRequest\x1a\x1e.gocash.v1.SubmitBlockResponse\x124\n" +
+	"\x06GetTip\x12\x18.gocash.v1.GetTipRequest\x1a\x10.gocash.v1.Block\x12I\n" +
+	"\n" +
+	"ListBlocks\x12\x1c.gocash.v1.ListBlocksRequest\x1a\x1d.gocash.v1.ListBlocksResponse\x12M\n" +
+	"\x0fSubscribeBlocks\x12!.gocash.v1.SubscribeBlocksRequest\x1a\x15.gocash.v1.BlockEvent0\x01\x12Q\n" +
+	"\x10SubscribeAddress\x12\".gocash.v1.SubscribeAddressRequest\x1a\x17.gocash.v1.AddressEvent0\x01B(Z&github.com/hypnophobe/go-cash/gocashpbb\x06proto3"
+
+var (
+	file_gocash_proto_rawDescOnce sync.Once
+	file_gocash_proto_rawDescData []byte
+)
+
+func file_gocash_proto_rawDescGZIP() []byte {
+	file_gocash_proto_rawDescOnce.Do(func() {
+		file_gocash_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gocash_proto_rawDesc), len(file_gocash_proto_rawDesc)))
+	})
+	return file_gocash_proto_rawDescData
+}
+
+var file_gocash_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
+var file_gocash_proto_goTypes = []any{
+	(*Holding)(nil),                  // 0: gocash.v1.Holding
+	(*Address)(nil),                  // 1: gocash.v1.Address
+	(*Transaction)(nil),              // 2: gocash.v1.Transaction
+	(*Block)(nil),                    // 3: gocash.v1.Block
+	(*Reorg)(nil),                    // 4: gocash.v1.Reorg
+	(*Supply)(nil),                   // 5: gocash.v1.Supply
+	(*GetAddressRequest)(nil),        // 6: gocash.v1.GetAddressRequest
+	(*ListAddressesRequest)(nil),     // 7: gocash.v1.ListAddressesRequest
+	(*ListAddressesResponse)(nil),    // 8: gocash.v1.ListAddressesResponse
+	(*SendTransactionRequest)(nil),   // 9: gocash.v1.SendTransactionRequest
+	(*SendTransactionResponse)(nil),  // 10: gocash.v1.SendTransactionResponse
+	(*GetTransactionRequest)(nil),    // 11: gocash.v1.GetTransactionRequest
+	(*ListTransactionsRequest)(nil),  // 12: gocash.v1.ListTransactionsRequest
+	(*ListTransactionsResponse)(nil), // 13: gocash.v1.ListTransactionsResponse
+	(*GetSupplyRequest)(nil),         // 14: gocash.v1.GetSupplyRequest
+	(*SubmitBlockRequest)(nil),       // 15: gocash.v1.SubmitBlockRequest
+	(*SubmitBlockResponse)(nil),      // 16: gocash.v1.SubmitBlockResponse
+	(*GetTipRequest)(nil),            // 17: gocash.v1.GetTipRequest
+	(*ListBlocksRequest)(nil),        // 18: gocash.v1.ListBlocksRequest
+	(*ListBlocksResponse)(nil),       // 19: gocash.v1.ListBlocksResponse
+	(*SubscribeBlocksRequest)(nil),   // 20: gocash.v1.SubscribeBlocksRequest
+	(*BlockEvent)(nil),               // 21: gocash.v1.BlockEvent
+	(*SubscribeAddressRequest)(nil),  // 22: gocash.v1.SubscribeAddressRequest
+	(*AddressEvent)(nil),             // 23: gocash.v1.AddressEvent
+}
+var file_gocash_proto_depIdxs = []int32{
+	0,  // 0: gocash.v1.Address.holdings:type_name -> gocash.v1.Holding
+	1,  // 1: gocash.v1.ListAddressesResponse.addresses:type_name -> gocash.v1.Address
+	2,  // 2: gocash.v1.ListTransactionsResponse.transactions:type_name -> gocash.v1.Transaction
+	4,  // 3: gocash.v1.SubmitBlockResponse.reorg:type_name -> gocash.v1.Reorg
+	3,  // 4: gocash.v1.ListBlocksResponse.blocks:type_name -> gocash.v1.Block
+	3,  // 5: gocash.v1.BlockEvent.block:type_name -> gocash.v1.Block
+	2,  // 6: gocash.v1.AddressEvent.transaction:type_name -> gocash.v1.Transaction
+	6,  // 7: gocash.v1.GoCash.GetAddress:input_type -> gocash.v1.GetAddressRequest
+	7,  // 8: gocash.v1.GoCash.ListAddresses:input_type -> gocash.v1.ListAddressesRequest
+	9,  // 9: gocash.v1.GoCash.SendTransaction:input_type -> gocash.v1.SendTransactionRequest
+	11, // 10: gocash.v1.GoCash.GetTransaction:input_type -> gocash.v1.GetTransactionRequest
+	12, // 11: gocash.v1.GoCash.ListTransactions:input_type -> gocash.v1.ListTransactionsRequest
+	14, // 12: gocash.v1.GoCash.GetSupply:input_type -> gocash.v1.GetSupplyRequest
+	15, // 13: gocash.v1.GoCash.SubmitBlock:input_type -> gocash.v1.SubmitBlockRequest
+	17, // 14: gocash.v1.GoCash.GetTip:input_type -> gocash.v1.GetTipRequest
+	18, // 15: gocash.v1.GoCash.ListBlocks:input_type -> gocash.v1.ListBlocksRequest
+	20, // 16: gocash.v1.GoCash.SubscribeBlocks:input_type -> gocash.v1.SubscribeBlocksRequest
+	22, // 17: gocash.v1.GoCash.SubscribeAddress:input_type -> gocash.v1.SubscribeAddressRequest
+	1,  // 18: gocash.v1.GoCash.GetAddress:output_type -> gocash.v1.Address
+	8,  // 19: gocash.v1.GoCash.ListAddresses:output_type -> gocash.v1.ListAddressesResponse
+	10, // 20: gocash.v1.GoCash.SendTransaction:output_type -> gocash.v1.SendTransactionResponse
+	2,  // 21: gocash.v1.GoCash.GetTransaction:output_type -> gocash.v1.Transaction
+	13, // 22: gocash.v1.GoCash.ListTransactions:output_type -> gocash.v1.ListTransactionsResponse
+	5,  // 23: gocash.v1.GoCash.GetSupply:output_type -> gocash.v1.Supply
+	16, // 24: gocash.v1.GoCash.SubmitBlock:output_type -> gocash.v1.SubmitBlockResponse
+	3,  // 25: gocash.v1.GoCash.GetTip:output_type -> gocash.v1.Block
+	19, // 26: gocash.v1.GoCash.ListBlocks:output_type -> gocash.v1.ListBlocksResponse
+	21, // 27: gocash.v1.GoCash.SubscribeBlocks:output_type -> gocash.v1.BlockEvent
+	23, // 28: gocash.v1.GoCash.SubscribeAddress:output_type -> gocash.v1.AddressEvent
+	18, // [18:29] is the sub-list for method output_type
+	7,  // [7:18] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_gocash_proto_init() }
+func file_gocash_proto_init() {
+	if File_gocash_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gocash_proto_rawDesc), len(file_gocash_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   24,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_gocash_proto_goTypes,
+		DependencyIndexes: file_gocash_proto_depIdxs,
+		MessageInfos:      file_gocash_proto_msgTypes,
+	}.Build()
+	File_gocash_proto = out.File
+	file_gocash_proto_goTypes = nil
+	file_gocash_proto_depIdxs = nil
+}
@@ -0,0 +1,549 @@
+// The go-cash gRPC API. It offers the same ledger and block operations as
+// the JSON HTTP API, backed by the same storage and validation, plus
+// server-streaming subscriptions built on the ledger change feed.
+//
+// Regenerate the Go code after editing with `go generate` in the
+// repository root.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: gocash.proto
+
+package gocashpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	GoCash_GetAddress_FullMethodName       = "/gocash.v1.GoCash/GetAddress"
+	GoCash_ListAddresses_FullMethodName    = "/gocash.v1.GoCash/ListAddresses"
+	GoCash_SendTransaction_FullMethodName  = "/gocash.v1.GoCash/SendTransaction"
+	GoCash_GetTransaction_FullMethodName   = "/gocash.v1.GoCash/GetTransaction"
+	GoCash_ListTransactions_FullMethodName = "/gocash.v1.GoCash/ListTransactions"
+	GoCash_GetSupply_FullMethodName        = "/gocash.v1.GoCash/GetSupply"
+	GoCash_SubmitBlock_FullMethodName      = "/gocash.v1.GoCash/SubmitBlock"
+	GoCash_GetTip_FullMethodName           = "/gocash.v1.GoCash/GetTip"
+	GoCash_ListBlocks_FullMethodName       = "/gocash.v1.GoCash/ListBlocks"
+	GoCash_SubscribeBlocks_FullMethodName  = "/gocash.v1.GoCash/SubscribeBlocks"
+	GoCash_SubscribeAddress_FullMethodName = "/gocash.v1.GoCash/SubscribeAddress"
+)
+
+// GoCashClient is the client API for GoCash service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GoCashClient interface {
+	// GetAddress returns an address with its native balance, locked funds and
+	// token holdings. Unknown addresses have a zero balance.
+	GetAddress(ctx context.Context, in *GetAddressRequest, opts ...grpc.CallOption) (*Address, error)
+	// ListAddresses returns every known address with its native balance.
+	ListAddresses(ctx context.Context, in *ListAddressesRequest, opts ...grpc.CallOption) (*ListAddressesResponse, error)
+	// SendTransaction transfers funds, with the same checks and fees as
+	// POST /transaction. The per-byte fee is charged on the size of the
+	// encoded request.
+	SendTransaction(ctx context.Context, in *SendTransactionRequest, opts ...grpc.CallOption) (*SendTransactionResponse, error)
+	// GetTransaction returns a transaction by ID.
+	GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*Transaction, error)
+	// ListTransactions returns every transaction, or those sent or received
+	// by an address.
+	ListTransactions(ctx context.Context, in *ListTransactionsRequest, opts ...grpc.CallOption) (*ListTransactionsResponse, error)
+	// GetSupply returns the supply of the native coin or an asset.
+	GetSupply(ctx context.Context, in *GetSupplyRequest, opts ...grpc.CallOption) (*Supply, error)
+	// SubmitBlock submits a mined block, as POST /block.
+	SubmitBlock(ctx context.Context, in *SubmitBlockRequest, opts ...grpc.CallOption) (*SubmitBlockResponse, error)
+	// GetTip returns the last block on the main chain.
+	GetTip(ctx context.Context, in *GetTipRequest, opts ...grpc.CallOption) (*Block, error)
+	// ListBlocks returns the main chain from the genesis block.
+	ListBlocks(ctx context.Context, in *ListBlocksRequest, opts ...grpc.CallOption) (*ListBlocksResponse, error)
+	// SubscribeBlocks streams blocks as they are connected to or disconnected
+	// from the main chain.
+	SubscribeBlocks(ctx context.Context, in *SubscribeBlocksRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BlockEvent], error)
+	// SubscribeAddress streams transactions sent or received by an address
+	// and changes to the address itself.
+	SubscribeAddress(ctx context.Context, in *SubscribeAddressRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AddressEvent], error)
+}
+
+type goCashClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGoCashClient(cc grpc.ClientConnInterface) GoCashClient {
+	return &goCashClient{cc}
+}
+
+func (c *goCashClient) GetAddress(ctx context.Context, in *GetAddressRequest, opts ...grpc.CallOption) (*Address, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Address)
+	err := c.cc.Invoke(ctx, GoCash_GetAddress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCashClient) ListAddresses(ctx context.Context, in *ListAddressesRequest, opts ...grpc.CallOption) (*ListAddressesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAddressesResponse)
+	err := c.cc.Invoke(ctx, GoCash_ListAddresses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCashClient) SendTransaction(ctx context.Context, in *SendTransactionRequest, opts ...grpc.CallOption) (*SendTransactionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendTransactionResponse)
+	err := c.cc.Invoke(ctx, GoCash_SendTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCashClient) GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*Transaction, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Transaction)
+	err := c.cc.Invoke(ctx, GoCash_GetTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCashClient) ListTransactions(ctx context.Context, in *ListTransactionsRequest, opts ...grpc.CallOption) (*ListTransactionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTransactionsResponse)
+	err := c.cc.Invoke(ctx, GoCash_ListTransactions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCashClient) GetSupply(ctx context.Context, in *GetSupplyRequest, opts ...grpc.CallOption) (*Supply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Supply)
+	err := c.cc.Invoke(ctx, GoCash_GetSupply_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCashClient) SubmitBlock(ctx context.Context, in *SubmitBlockRequest, opts ...grpc.CallOption) (*SubmitBlockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitBlockResponse)
+	err := c.cc.Invoke(ctx, GoCash_SubmitBlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCashClient) GetTip(ctx context.Context, in *GetTipRequest, opts ...grpc.CallOption) (*Block, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Block)
+	err := c.cc.Invoke(ctx, GoCash_GetTip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCashClient) ListBlocks(ctx context.Context, in *ListBlocksRequest, opts ...grpc.CallOption) (*ListBlocksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListBlocksResponse)
+	err := c.cc.Invoke(ctx, GoCash_ListBlocks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *goCashClient) SubscribeBlocks(ctx context.Context, in *SubscribeBlocksRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BlockEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GoCash_ServiceDesc.Streams[0], GoCash_SubscribeBlocks_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeBlocksRequest, BlockEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GoCash_SubscribeBlocksClient = grpc.ServerStreamingClient[BlockEvent]
+
+func (c *goCashClient) SubscribeAddress(ctx context.Context, in *SubscribeAddressRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AddressEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GoCash_ServiceDesc.Streams[1], GoCash_SubscribeAddress_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeAddressRequest, AddressEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GoCash_SubscribeAddressClient = grpc.ServerStreamingClient[AddressEvent]
+
+// GoCashServer is the server API for GoCash service.
+// All implementations must embed UnimplementedGoCashServer
+// for forward compatibility.
+type GoCashServer interface {
+	// GetAddress returns an address with its native balance, locked funds and
+	// token holdings. Unknown addresses have a zero balance.
+	GetAddress(context.Context, *GetAddressRequest) (*Address, error)
+	// ListAddresses returns every known address with its native balance.
+	ListAddresses(context.Context, *ListAddressesRequest) (*ListAddressesResponse, error)
+	// SendTransaction transfers funds, with the same checks and fees as
+	// POST /transaction. The per-byte fee is charged on the size of the
+	// encoded request.
+	SendTransaction(context.Context, *SendTransactionRequest) (*SendTransactionResponse, error)
+	// GetTransaction returns a transaction by ID.
+	GetTransaction(context.Context, *GetTransactionRequest) (*Transaction, error)
+	// ListTransactions returns every transaction, or those sent or received
+	// by an address.
+	ListTransactions(context.Context, *ListTransactionsRequest) (*ListTransactionsResponse, error)
+	// GetSupply returns the supply of the native coin or an asset.
+	GetSupply(context.Context, *GetSupplyRequest) (*Supply, error)
+	// SubmitBlock submits a mined block, as POST /block.
+	SubmitBlock(context.Context, *SubmitBlockRequest) (*SubmitBlockResponse, error)
+	// GetTip returns the last block on the main chain.
+	GetTip(context.Context, *GetTipRequest) (*Block, error)
+	// ListBlocks returns the main chain from the genesis block.
+	ListBlocks(context.Context, *ListBlocksRequest) (*ListBlocksResponse, error)
+	// SubscribeBlocks streams blocks as they are connected to or disconnected
+	// from the main chain.
+	SubscribeBlocks(*SubscribeBlocksRequest, grpc.ServerStreamingServer[BlockEvent]) error
+	// SubscribeAddress streams transactions sent or received by an address
+	// and changes to the address itself.
+	SubscribeAddress(*SubscribeAddressRequest, grpc.ServerStreamingServer[AddressEvent]) error
+	mustEmbedUnimplementedGoCashServer()
+}
+
+// UnimplementedGoCashServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGoCashServer struct{}
+
+func (UnimplementedGoCashServer) GetAddress(context.Context, *GetAddressRequest) (*Address, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAddress not implemented")
+}
+func (UnimplementedGoCashServer) ListAddresses(context.Context, *ListAddressesRequest) (*ListAddressesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAddresses not implemented")
+}
+func (UnimplementedGoCashServer) SendTransaction(context.Context, *SendTransactionRequest) (*SendTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendTransaction not implemented")
+}
+func (UnimplementedGoCashServer) GetTransaction(context.Context, *GetTransactionRequest) (*Transaction, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransaction not implemented")
+}
+func (UnimplementedGoCashServer) ListTransactions(context.Context, *ListTransactionsRequest) (*ListTransactionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTransactions not implemented")
+}
+func (UnimplementedGoCashServer) GetSupply(context.Context, *GetSupplyRequest) (*Supply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSupply not implemented")
+}
+func (UnimplementedGoCashServer) SubmitBlock(context.Context, *SubmitBlockRequest) (*SubmitBlockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitBlock not implemented")
+}
+func (UnimplementedGoCashServer) GetTip(context.Context, *GetTipRequest) (*Block, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTip not implemented")
+}
+func (UnimplementedGoCashServer) ListBlocks(context.Context, *ListBlocksRequest) (*ListBlocksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBlocks not implemented")
+}
+func (UnimplementedGoCashServer) SubscribeBlocks(*SubscribeBlocksRequest, grpc.ServerStreamingServer[BlockEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeBlocks not implemented")
+}
+func (UnimplementedGoCashServer) SubscribeAddress(*SubscribeAddressRequest, grpc.ServerStreamingServer[AddressEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeAddress not implemented")
+}
+func (UnimplementedGoCashServer) mustEmbedUnimplementedGoCashServer() {}
+func (UnimplementedGoCashServer) testEmbeddedByValue()                {}
+
+// UnsafeGoCashServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GoCashServer will
+// result in compilation errors.
+type UnsafeGoCashServer interface {
+	mustEmbedUnimplementedGoCashServer()
+}
+
+func RegisterGoCashServer(s grpc.ServiceRegistrar, srv GoCashServer) {
+	// If the following call pancis, it indicates UnimplementedGoCashServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&GoCash_ServiceDesc, srv)
+}
+
+func _GoCash_GetAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCashServer).GetAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCash_GetAddress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCashServer).GetAddress(ctx, req.(*GetAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCash_ListAddresses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAddressesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCashServer).ListAddresses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCash_ListAddresses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCashServer).ListAddresses(ctx, req.(*ListAddressesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCash_SendTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCashServer).SendTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCash_SendTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCashServer).SendTransaction(ctx, req.(*SendTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCash_GetTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCashServer).GetTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCash_GetTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCashServer).GetTransaction(ctx, req.(*GetTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCash_ListTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTransactionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCashServer).ListTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCash_ListTransactions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCashServer).ListTransactions(ctx, req.(*ListTransactionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCash_GetSupply_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSupplyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCashServer).GetSupply(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCash_GetSupply_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCashServer).GetSupply(ctx, req.(*GetSupplyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCash_SubmitBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitBlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCashServer).SubmitBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCash_SubmitBlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCashServer).SubmitBlock(ctx, req.(*SubmitBlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCash_GetTip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCashServer).GetTip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCash_GetTip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCashServer).GetTip(ctx, req.(*GetTipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCash_ListBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBlocksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GoCashServer).ListBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GoCash_ListBlocks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GoCashServer).ListBlocks(ctx, req.(*ListBlocksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GoCash_SubscribeBlocks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeBlocksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GoCashServer).SubscribeBlocks(m, &grpc.GenericServerStream[SubscribeBlocksRequest, BlockEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GoCash_SubscribeBlocksServer = grpc.ServerStreamingServer[BlockEvent]
+
+func _GoCash_SubscribeAddress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeAddressRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GoCashServer).SubscribeAddress(m, &grpc.GenericServerStream[SubscribeAddressRequest, AddressEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GoCash_SubscribeAddressServer = grpc.ServerStreamingServer[AddressEvent]
+
+// GoCash_ServiceDesc is the grpc.ServiceDesc for GoCash service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GoCash_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gocash.v1.GoCash",
+	HandlerType: (*GoCashServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetAddress",
+			Handler:    _GoCash_GetAddress_Handler,
+		},
+		{
+			MethodName: "ListAddresses",
+			Handler:    _GoCash_ListAddresses_Handler,
+		},
+		{
+			MethodName: "SendTransaction",
+			Handler:    _GoCash_SendTransaction_Handler,
+		},
+		{
+			MethodName: "GetTransaction",
+			Handler:    _GoCash_GetTransaction_Handler,
+		},
+		{
+			MethodName: "ListTransactions",
+			Handler:    _GoCash_ListTransactions_Handler,
+		},
+		{
+			MethodName: "GetSupply",
+			Handler:    _GoCash_GetSupply_Handler,
+		},
+		{
+			MethodName: "SubmitBlock",
+			Handler:    _GoCash_SubmitBlock_Handler,
+		},
+		{
+			MethodName: "GetTip",
+			Handler:    _GoCash_GetTip_Handler,
+		},
+		{
+			MethodName: "ListBlocks",
+			Handler:    _GoCash_ListBlocks_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeBlocks",
+			Handler:       _GoCash_SubscribeBlocks_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeAddress",
+			Handler:       _GoCash_SubscribeAddress_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gocash.proto",
+}
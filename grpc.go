@@ -0,0 +1,506 @@
+package main
+
+//go:generate protoc -I proto --go_out=. --go_opt=module=github.com/hypnophobe/go-cash --go-grpc_out=. --go-grpc_opt=module=github.com/hypnophobe/go-cash proto/gocash.proto
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hypnophobe/go-cash/gocashpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// grpcWrites are the methods a replica refuses, since unlike HTTP writes
+// they are not forwarded to the primary.
+var grpcWrites = map[string]bool{
+	gocashpb.GoCash_SendTransaction_FullMethodName: true,
+	gocashpb.GoCash_SubmitBlock_FullMethodName:     true,
+}
+
+// grpcService implements the gRPC API on the same storage and validation
+// code as the HTTP handlers.
+type grpcService struct {
+	gocashpb.UnimplementedGoCashServer
+}
+
+// newGRPCServer returns the gRPC server, using the HTTP server's certificate
+// and client CA when TLS is enabled.
+func newGRPCServer(cfg *Config) (*grpc.Server, error) {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(grpcUnaryInterceptor),
+		grpc.StreamInterceptor(grpcStreamInterceptor),
+	}
+
+	if cfg.TLS.Enabled {
+		tlsConfig, err := serverTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := grpc.NewServer(opts...)
+	gocashpb.RegisterGoCashServer(server, &grpcService{})
+
+	return server, nil
+}
+
+// stopGRPC waits for in-flight calls to finish until ctx is done, then
+// cuts off the rest. Subscriptions end when stopLongPolls runs.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+func grpcUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+
+	var resp interface{}
+	err := checkGRPCCall(ctx, info.FullMethod)
+	if err == nil {
+		resp, err = handler(ctx, req)
+	}
+
+	logGRPCCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func grpcStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+
+	err := checkGRPCCall(ss.Context(), info.FullMethod)
+	if err == nil {
+		err = handler(srv, ss)
+	}
+
+	logGRPCCall(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// checkGRPCCall applies the replica, rate limit and miner certificate rules
+// of the HTTP API. Rate limits are keyed by the full method name, such as
+// /gocash.v1.GoCash/SubmitBlock.
+func checkGRPCCall(ctx context.Context, method string) error {
+	if replica != nil && grpcWrites[method] {
+		return status.Error(codes.FailedPrecondition, "read-only replica, send writes to the primary")
+	}
+
+	if limiter != nil {
+		client := grpcClientIdentity(ctx)
+		if banned, _ := limiter.banned(client); banned && method == gocashpb.GoCash_SubmitBlock_FullMethodName {
+			return status.Error(codes.PermissionDenied, "temporarily banned")
+		}
+		if ok, _ := limiter.allow(client, method); !ok {
+			return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+	}
+
+	if method == gocashpb.GoCash_SubmitBlock_FullMethodName && serverConfig.TLS.RequireMinerCerts && grpcClientCert(ctx) == "" {
+		return status.Error(codes.PermissionDenied, "client certificate required")
+	}
+
+	return nil
+}
+
+func logGRPCCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("clientIp", grpcClientIP(ctx)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unknown {
+		level = slog.LevelError
+	}
+	slog.LogAttrs(ctx, level, "grpc call", attrs...)
+}
+
+func grpcClientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// grpcClientCert returns the common name of the verified client
+// certificate, if one was presented.
+func grpcClientCert(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 {
+		return ""
+	}
+	return info.State.VerifiedChains[0][0].Subject.CommonName
+}
+
+// grpcClientIdentity matches clientIdentity, so a client has the same
+// identity over HTTP and gRPC.
+func grpcClientIdentity(ctx context.Context) string {
+	if name := grpcClientCert(ctx); name != "" {
+		return "cert:" + name
+	}
+	return "ip:" + grpcClientIP(ctx)
+}
+
+// grpcError converts an error from the shared validation code to a gRPC
+// status, keeping the HTTP API's error message.
+func grpcError(err error) error {
+	var rej *rejection
+	if !errors.As(err, &rej) {
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	switch rej.status {
+	case http.StatusBadRequest:
+		return status.Error(codes.InvalidArgument, rej.message)
+	case http.StatusForbidden:
+		return status.Error(codes.PermissionDenied, rej.message)
+	case http.StatusNotFound:
+		return status.Error(codes.NotFound, rej.message)
+	case http.StatusConflict:
+		return status.Error(codes.FailedPrecondition, rej.message)
+	default:
+		return status.Error(codes.Unknown, rej.message)
+	}
+}
+
+func (s *grpcService) GetAddress(ctx context.Context, req *gocashpb.GetAddressRequest) (*gocashpb.Address, error) {
+	if !validateAddress(req.Address) {
+		return nil, status.Error(codes.InvalidArgument, "invalid address")
+	}
+
+	balance := queryAddress(sqliteDatabase, req.Address)
+
+	locked, err := queryLocked(sqliteDatabase, req.Address)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	tokens, err := queryHoldings(sqliteDatabase, req.Address)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	holdings := []*gocashpb.Holding{{Asset: nativeAsset, Balance: int64(balance)}}
+	for _, h := range tokens {
+		holdings = append(holdings, &gocashpb.Holding{Asset: h.Asset, Balance: int64(h.Balance)})
+	}
+
+	return &gocashpb.Address{
+		Address:  req.Address,
+		Balance:  int64(balance),
+		Locked:   int64(locked),
+		Holdings: holdings,
+	}, nil
+}
+
+func (s *grpcService) ListAddresses(ctx context.Context, req *gocashpb.ListAddressesRequest) (*gocashpb.ListAddressesResponse, error) {
+	addresses, err := queryAddresses(sqliteDatabase)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &gocashpb.ListAddressesResponse{}
+	for _, addr := range addresses {
+		resp.Addresses = append(resp.Addresses, &gocashpb.Address{Address: addr.Address, Balance: int64(addr.Balance)})
+	}
+	return resp, nil
+}
+
+// SendTransaction charges the per-byte fee on the size of the encoded
+// request, as POST /transaction does on its body.
+func (s *grpcService) SendTransaction(ctx context.Context, req *gocashpb.SendTransactionRequest) (*gocashpb.SendTransactionResponse, error) {
+	transaction := TransactionRequest{
+		Pkey:    req.Pkey,
+		Address: req.Address,
+		Amount:  int(req.Amount),
+		Fee:     int(req.Fee),
+		Memo:    req.Memo,
+		Asset:   req.Asset,
+	}
+
+	timestamp := int(time.Now().Unix())
+	hash := newTransactionHash()
+
+	txn, txID, err := applyTransaction(transaction, proto.Size(req), hash, timestamp)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &gocashpb.SendTransactionResponse{
+		TransactionId: txID,
+		Hash:          hash,
+		Asset:         txn.Asset,
+		Amount:        int64(txn.Amount),
+		Fee:           int64(txn.Fee),
+	}, nil
+}
+
+func (s *grpcService) GetTransaction(ctx context.Context, req *gocashpb.GetTransactionRequest) (*gocashpb.Transaction, error) {
+	transaction, err := queryTransaction(sqliteDatabase, strconv.FormatInt(req.Id, 10))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if transaction == nil {
+		return nil, status.Error(codes.NotFound, "transaction not found")
+	}
+
+	return transactionProto(transaction), nil
+}
+
+func (s *grpcService) ListTransactions(ctx context.Context, req *gocashpb.ListTransactionsRequest) (*gocashpb.ListTransactionsResponse, error) {
+	var transactions []Transaction
+	var err error
+	if req.Address != "" {
+		transactions, err = queryAddressTransactions(sqliteDatabase, req.Address, req.Reference)
+	} else {
+		transactions, err = queryTransactions(sqliteDatabase)
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &gocashpb.ListTransactionsResponse{}
+	for i := range transactions {
+		resp.Transactions = append(resp.Transactions, transactionProto(&transactions[i]))
+	}
+	return resp, nil
+}
+
+func (s *grpcService) GetSupply(ctx context.Context, req *gocashpb.GetSupplyRequest) (*gocashpb.Supply, error) {
+	supply, err := querySupply(sqliteDatabase, req.Asset)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if supply == nil {
+		return nil, status.Error(codes.NotFound, "asset not found")
+	}
+
+	return &gocashpb.Supply{
+		Asset:             supply.Asset,
+		TotalSupply:       int64(supply.TotalSupply),
+		CirculatingSupply: int64(supply.CirculatingSupply),
+		PendingFees:       int64(supply.PendingFees),
+		FeesPaid:          int64(supply.FeesPaid),
+		Locked:            int64(supply.Locked),
+		Escrowed:          int64(supply.Escrowed),
+		Mintable:          supply.Mintable,
+	}, nil
+}
+
+func (s *grpcService) SubmitBlock(ctx context.Context, req *gocashpb.SubmitBlockRequest) (*gocashpb.SubmitBlockResponse, error) {
+	blk := submittedBlock{
+		Block:         req.Hash,
+		PreviousBlock: req.PrevBlock,
+		Address:       req.Address,
+		Nonce:         req.Nonce,
+	}
+	timestamp := int(time.Now().Unix())
+
+	outcome, err := applyBlock(blk, timestamp)
+	switch {
+	case errors.Is(err, errKnownBlock):
+		return &gocashpb.SubmitBlockResponse{Known: true}, nil
+	case errors.Is(err, errInvalidBlock) || errors.Is(err, errInsufficientWork):
+		if limiter != nil {
+			limiter.recordInvalidBlock(grpcClientIdentity(ctx))
+		}
+		return nil, grpcError(err)
+	case err != nil:
+		return nil, grpcError(err)
+	}
+
+	resp := &gocashpb.SubmitBlockResponse{Reward: int64(outcome.Payout), Branch: outcome.Branch}
+	if ev := outcome.Reorg; ev != nil {
+		resp.Reorg = &gocashpb.Reorg{
+			Id:        ev.ID,
+			Time:      int64(ev.Time),
+			ForkBlock: ev.ForkBlock,
+			OldTip:    ev.OldTip,
+			NewTip:    ev.NewTip,
+			Orphaned:  int64(ev.Orphaned),
+			Adopted:   int64(ev.Adopted),
+		}
+	}
+	return resp, nil
+}
+
+func (s *grpcService) GetTip(ctx context.Context, req *gocashpb.GetTipRequest) (*gocashpb.Block, error) {
+	hash, err := queryBlock(sqliteDatabase)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	blk, err := queryAnyBlock(sqliteDatabase, hash)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if blk == nil {
+		return nil, status.Error(codes.NotFound, "no blocks")
+	}
+
+	return blockProto(blk), nil
+}
+
+func (s *grpcService) ListBlocks(ctx context.Context, req *gocashpb.ListBlocksRequest) (*gocashpb.ListBlocksResponse, error) {
+	blocks, err := queryBlocks(sqliteDatabase)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &gocashpb.ListBlocksResponse{}
+	for i := range blocks {
+		resp.Blocks = append(resp.Blocks, blockProto(&blocks[i]))
+	}
+	return resp, nil
+}
+
+func (s *grpcService) SubscribeBlocks(req *gocashpb.SubscribeBlocksRequest, stream gocashpb.GoCash_SubscribeBlocksServer) error {
+	if req.Since < 0 {
+		return status.Error(codes.InvalidArgument, "since must be a cursor from an earlier event")
+	}
+
+	return followChanges(stream.Context(), req.Since, func(c Change) error {
+		if (c.Type != "block" && c.Type != "block_disconnected") || c.Block == nil {
+			return nil
+		}
+		return stream.Send(&gocashpb.BlockEvent{
+			Cursor: c.Cursor,
+			Type:   c.Type,
+			Time:   int64(c.Time),
+			Block:  blockProto(c.Block),
+		})
+	})
+}
+
+func (s *grpcService) SubscribeAddress(req *gocashpb.SubscribeAddressRequest, stream gocashpb.GoCash_SubscribeAddressServer) error {
+	if !validateAddress(req.Address) {
+		return status.Error(codes.InvalidArgument, "invalid address")
+	}
+	if req.Since < 0 {
+		return status.Error(codes.InvalidArgument, "since must be a cursor from an earlier event")
+	}
+
+	return followChanges(stream.Context(), req.Since, func(c Change) error {
+		event := &gocashpb.AddressEvent{Cursor: c.Cursor, Type: c.Type, Time: int64(c.Time)}
+
+		switch {
+		case c.Type == "address" && c.Address == req.Address:
+		case (c.Type == "transfer" || c.Type == "mint") && c.Transaction != nil &&
+			(c.Transaction.Sender == req.Address || c.Transaction.Recipient == req.Address):
+			event.Transaction = transactionProto(c.Transaction)
+		default:
+			return nil
+		}
+
+		return stream.Send(event)
+	})
+}
+
+// followChanges passes ledger changes after since to send as they are
+// committed, until the client goes away or the server shuts down. A since
+// of zero starts with the next change.
+func followChanges(ctx context.Context, since int64, send func(Change) error) error {
+	if since == 0 {
+		latest, err := queryLatestChange(sqliteDatabase)
+		if err != nil {
+			return grpcError(err)
+		}
+		since = latest
+	}
+
+	for {
+		// Take the signal before querying so a change committed in between
+		// still wakes us.
+		signal := changesWaiter()
+
+		changes, err := queryChanges(sqliteDatabase, since, maxChangesLimit)
+		if err != nil {
+			return grpcError(err)
+		}
+
+		for _, c := range changes {
+			if err := send(c); err != nil {
+				return err
+			}
+			since = c.Cursor
+		}
+		if len(changes) == maxChangesLimit {
+			continue
+		}
+
+		select {
+		case <-signal:
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-longPollStop:
+			return status.Error(codes.Unavailable, "server shutting down")
+		}
+	}
+}
+
+func transactionProto(t *Transaction) *gocashpb.Transaction {
+	return &gocashpb.Transaction{
+		Id:        int64(t.ID),
+		Sender:    t.Sender,
+		Recipient: t.Recipient,
+		Amount:    int64(t.Amount),
+		Fee:       int64(t.Fee),
+		Time:      int64(t.Time),
+		Block:     t.Block,
+		Memo:      t.Memo,
+		Asset:     t.Asset,
+	}
+}
+
+func blockProto(b *Block) *gocashpb.Block {
+	return &gocashpb.Block{
+		Id:        int64(b.ID),
+		Hash:      b.BlockContent,
+		PrevBlock: b.PrevBlock,
+		Address:   b.Address,
+		Nonce:     b.Nonce,
+		Time:      int64(b.Time),
+		Height:    int64(b.Height),
+	}
+}
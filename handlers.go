@@ -2,8 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -11,6 +16,9 @@ type TransactionRequest struct {
 	Pkey    string `json:"pkey"`
 	Address string `json:"address"`
 	Amount  int    `json:"amount"`
+	Fee     int    `json:"fee"`
+	Memo    string `json:"memo"`
+	Asset   string `json:"asset"`
 }
 
 type submittedBlock struct {
@@ -26,115 +34,265 @@ func writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{})
 	json.NewEncoder(w).Encode(data)
 }
 
+// writeErrorResponse reports a failed request. code is the stable error
+// code for /v2 requests, which get an ErrorResponse instead of the original
+// error shape.
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, statusCode int, code, message string) {
+	writeErrorDetails(w, r, statusCode, code, message, nil)
+}
+
+// writeErrorDetails is writeErrorResponse with extra fields for /v2
+// clients. The original error shape doesn't include them.
+func writeErrorDetails(w http.ResponseWriter, r *http.Request, statusCode int, code, message string, details map[string]interface{}) {
+	annotate(r, slog.String("error", message))
+
+	if isV2(r) {
+		if details == nil {
+			details = map[string]interface{}{}
+		}
+		writeJSONResponse(w, statusCode, ErrorResponse{
+			Error:     APIError{Code: code, Message: message, Details: details},
+			RequestID: requestID(r),
+		})
+		return
+	}
+
+	response := map[string]interface{}{"ok": false, "error": message, "requestId": requestID(r)}
+	writeJSONResponse(w, statusCode, response)
+}
+
 func getAddress(w http.ResponseWriter, r *http.Request) {
 	address := r.PathValue("address")
 
 	if !validateAddress(address) {
-		response := map[string]interface{}{"ok": false, "error": "invalid address"}
-		writeJSONResponse(w, http.StatusBadRequest, response)
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_address", "invalid address")
 		return
 	}
 
 	balance := queryAddress(sqliteDatabase, address)
 
-	response := map[string]interface{}{
-		"address": address,
-		"balance": balance,
+	locked, err := queryLocked(sqliteDatabase, address)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	tokens, err := queryHoldings(sqliteDatabase, address)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]interface{}{"ok": true, "addresses": []map[string]interface{}{response}})
+	holdings := []HoldingResponse{{Asset: nativeAsset, Balance: balance}}
+	for _, h := range tokens {
+		holdings = append(holdings, HoldingResponse{Asset: h.Asset, Balance: h.Balance})
+	}
+
+	response := AddressResponse{
+		Address:  address,
+		Balance:  balance,
+		Locked:   locked,
+		Holdings: holdings,
+	}
+
+	writeResponse(w, r, map[string]interface{}{"ok": true, "addresses": []AddressResponse{response}}, response)
 }
 
 func getAddresses(w http.ResponseWriter, r *http.Request) {
 	addresses, err := queryAddresses(sqliteDatabase)
 	if err != nil {
-		response := map[string]interface{}{
-			"ok":    false,
-			"error": "failed to retrieve addresses",
-		}
-		writeJSONResponse(w, http.StatusInternalServerError, response)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve addresses")
 		return
 	}
 
-	var result []map[string]interface{}
+	result := AddressListResponse{Addresses: make([]AddressSummary, 0, len(addresses))}
 	for _, addr := range addresses {
-		result = append(result, map[string]interface{}{
-			"address": addr.Address,
-			"balance": addr.Balance,
-		})
+		result.Addresses = append(result.Addresses, AddressSummary{Address: addr.Address, Balance: addr.Balance})
 	}
 
 	response := map[string]interface{}{
 		"ok":        true,
-		"addresses": result,
+		"addresses": result.Addresses,
 	}
-	writeJSONResponse(w, http.StatusOK, response)
+	writeResponse(w, r, response, result)
 }
 
 func createTransaction(w http.ResponseWriter, r *http.Request) {
-	var req TransactionRequest
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			transactionsTotal.inc("rejected", "body_too_large")
+			writeErrorResponse(w, r, http.StatusRequestEntityTooLarge, "body_too_large", "request body too large")
+			return
+		}
+		transactionsTotal.inc("rejected", "invalid_body")
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response := map[string]interface{}{"ok": false, "error": "invalid request body"}
-		writeJSONResponse(w, http.StatusBadRequest, response)
+	timestamp := int(time.Now().Unix())
+	hash := newTransactionHash()
+
+	req, txID, ok := processTransaction(w, r, body, hash, timestamp)
+	if !ok {
 		return
 	}
 
+	response := map[string]interface{}{"ok": true, "transaction": txID, "hash": hash, "asset": req.Asset, "amount": req.Amount, "fee": req.Fee}
+	writeResponse(w, r, response, TransactionResult{TransactionID: txID, Hash: hash, Asset: req.Asset, Amount: req.Amount, Fee: req.Fee})
+}
+
+// rejection is a request refused by validation, with the HTTP status, error
+// code and message to report. It wraps the ledger error, if there was one.
+type rejection struct {
+	status  int
+	code    string
+	message string
+	details map[string]interface{}
+	err     error
+}
+
+func (e *rejection) Error() string { return e.message }
+
+func (e *rejection) Unwrap() error { return e.err }
+
+// reject counts a rejected request in counter under reason, which is also
+// its error code.
+func reject(counter *counterVec, reason string, status int, message string, err error) *rejection {
+	counter.inc("rejected", reason)
+	return &rejection{status: status, code: reason, message: message, err: err}
+}
+
+// applyTransaction validates a transfer and applies it under the given hash.
+// size is the length of the request as received, which the per-byte fee is
+// charged on. Transactions sent over gRPC go through the same checks as
+// those submitted over HTTP. Invalid transactions are returned as a
+// *rejection and already seen ones as errKnownTransaction.
+func applyTransaction(req TransactionRequest, size int, hash string, timestamp int) (*TransactionRequest, int64, error) {
 	senderAddress := generateAddress(req.Pkey)
-	senderBalance := queryAddress(sqliteDatabase, senderAddress)
 
 	if !validateAddress(req.Address) {
-		response := map[string]interface{}{"ok": false, "error": "invalid address"}
-		writeJSONResponse(w, http.StatusBadRequest, response)
-		return
+		return nil, 0, reject(transactionsTotal, "invalid_address", http.StatusBadRequest, "invalid address", nil)
 	}
 
-	if senderBalance < req.Amount {
-		response := map[string]interface{}{"ok": false, "error": "insufficient funds"}
-		writeJSONResponse(w, http.StatusBadRequest, response)
-		return
+	if req.Amount <= 0 {
+		return nil, 0, reject(transactionsTotal, "invalid_amount", http.StatusBadRequest, "amount must be positive", nil)
 	}
 
-	updateAddress(sqliteDatabase, senderAddress, senderBalance-req.Amount)
-	recipientBalance := queryAddress(sqliteDatabase, req.Address)
+	if rej := validateMemoAndFee(req.Memo, req.Fee, size); rej != nil {
+		return nil, 0, rej
+	}
 
-	if recipientBalance > 0 {
-		updateAddress(sqliteDatabase, req.Address, recipientBalance+req.Amount)
-		insertTransaction(sqliteDatabase, senderAddress, req.Amount, req.Address, int(time.Now().Unix()))
-		response := map[string]interface{}{"ok": true}
-		writeJSONResponse(w, http.StatusOK, response)
-		return
+	if req.Asset == "" {
+		req.Asset = nativeAsset
+	}
+	if req.Asset != nativeAsset {
+		asset, err := queryAsset(sqliteDatabase, req.Asset)
+		if err != nil {
+			return nil, 0, err
+		}
+		if asset == nil {
+			return nil, 0, reject(transactionsTotal, "unknown_asset", http.StatusBadRequest, "unknown asset", nil)
+		}
+	}
+
+	txID, err := transfer(senderAddress, req.Address, req.Asset, req.Amount, req.Fee, req.Memo, hash, timestamp)
+	switch {
+	case errors.Is(err, errKnownTransaction):
+		return nil, 0, err
+	case errors.Is(err, errFrozen):
+		return nil, 0, reject(transactionsTotal, "frozen", http.StatusForbidden, "address frozen", err)
+	case errors.Is(err, errMultisigSender):
+		return nil, 0, reject(transactionsTotal, "multisig_sender", http.StatusForbidden, "multisig addresses can only be spent from with POST /multisig/transaction", err)
+	case errors.Is(err, errInsufficientFunds):
+		return nil, 0, reject(transactionsTotal, "insufficient_funds", http.StatusBadRequest, "insufficient funds", err)
+	case err != nil:
+		log.Println("transfer failed:", err)
+		return nil, 0, err
+	}
+
+	transactionsTotal.inc("accepted", "")
+
+	return &req, txID, nil
+}
+
+// validateMemoAndFee checks a transfer's memo and that its fee covers a
+// request of size bytes, counting a rejection in transactionsTotal.
+func validateMemoAndFee(memo string, fee int, size int) *rejection {
+	if !validateMemo(memo) {
+		return reject(transactionsTotal, "invalid_memo", http.StatusBadRequest, fmt.Sprintf("memo must be at most %d bytes without control characters", maxMemoLength), nil)
+	}
+
+	if minFee := requiredFee(size); fee < minFee {
+		rej := reject(transactionsTotal, "fee_too_low", http.StatusBadRequest, fmt.Sprintf("fee below minimum of %d", minFee), nil)
+		rej.details = map[string]interface{}{"minFee": minFee}
+		return rej
+	}
+
+	return nil
+}
+
+// checkMemoAndFee is validateMemoAndFee for an HTTP request. On failure it
+// writes the error response and returns false.
+func checkMemoAndFee(w http.ResponseWriter, r *http.Request, memo string, fee int, size int) bool {
+	if rej := validateMemoAndFee(memo, fee, size); rej != nil {
+		writeErrorDetails(w, r, rej.status, rej.code, rej.message, rej.details)
+		return false
 	}
 
-	insertAddress(sqliteDatabase, req.Address, req.Amount)
-	insertTransaction(sqliteDatabase, senderAddress, req.Amount, req.Address, int(time.Now().Unix()))
+	return true
+}
 
-	response := map[string]interface{}{"ok": true}
-	writeJSONResponse(w, http.StatusOK, response)
+// processTransaction applies a POST /transaction body for an HTTP request.
+// On failure it writes the error response and returns false.
+func processTransaction(w http.ResponseWriter, r *http.Request, body []byte, hash string, timestamp int) (*TransactionRequest, int64, bool) {
+	var req TransactionRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		transactionsTotal.inc("rejected", "invalid_body")
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return nil, 0, false
+	}
+
+	txn, txID, err := applyTransaction(req, len(body), hash, timestamp)
+
+	var rej *rejection
+	switch {
+	case errors.Is(err, errKnownTransaction):
+		writeResponse(w, r, map[string]interface{}{"ok": true, "known": true}, TransactionResult{Hash: hash, Known: true})
+		return nil, 0, false
+	case errors.As(err, &rej):
+		writeErrorDetails(w, r, rej.status, rej.code, rej.message, rej.details)
+		return nil, 0, false
+	case err != nil:
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return nil, 0, false
+	}
+
+	annotate(r, slog.Int64("transactionId", txID), slog.String("hash", hash))
+
+	return txn, txID, true
 }
 
 func getTransaction(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	transaction, err := queryTransaction(sqliteDatabase, id)
 	if err != nil {
-		response := map[string]interface{}{"ok": false, "error": "internal server error"}
-		writeJSONResponse(w, http.StatusInternalServerError, response)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
 		return
 	}
 	if transaction == nil {
-		response := map[string]interface{}{"ok": false, "error": "transaction not found"}
-		writeJSONResponse(w, http.StatusNotFound, response)
+		writeErrorResponse(w, r, http.StatusNotFound, "transaction_not_found", "transaction not found")
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]interface{}{"ok": true, "transactions": []interface{}{transaction}})
+	writeResponse(w, r, map[string]interface{}{"ok": true, "transactions": []interface{}{transaction}}, transactionResponse(transaction))
 }
 
 func getTransactions(w http.ResponseWriter, r *http.Request) {
 	transactions, err := queryTransactions(sqliteDatabase)
 	if err != nil {
-		response := map[string]interface{}{"ok": false, "error": "failed to retrieve addresses"}
-		writeJSONResponse(w, http.StatusInternalServerError, response)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve addresses")
 		return
 	}
 
@@ -142,14 +300,13 @@ func getTransactions(w http.ResponseWriter, r *http.Request) {
 		"ok":           true,
 		"transactions": transactions,
 	}
-	writeJSONResponse(w, http.StatusOK, response)
+	writeResponse(w, r, response, transactionListResponse(transactions))
 }
 
 func getAddressTransactions(w http.ResponseWriter, r *http.Request) {
-	transactions, err := queryAddressTransactions(sqliteDatabase, r.PathValue("address"))
+	transactions, err := queryAddressTransactions(sqliteDatabase, r.PathValue("address"), r.URL.Query().Get("reference"))
 	if err != nil {
-		response := map[string]interface{}{"ok": false, "error": "failed to retrieve addresses"}
-		writeJSONResponse(w, http.StatusInternalServerError, response)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve addresses")
 		return
 	}
 
@@ -157,25 +314,70 @@ func getAddressTransactions(w http.ResponseWriter, r *http.Request) {
 		"ok":           true,
 		"transactions": transactions,
 	}
-	writeJSONResponse(w, http.StatusOK, response)
+	writeResponse(w, r, response, transactionListResponse(transactions))
 }
 
 func getBlock(w http.ResponseWriter, r *http.Request) {
 	block, err := queryBlock(sqliteDatabase)
 	if err != nil {
-		response := map[string]interface{}{"ok": false, "error": "internal server error"}
-		writeJSONResponse(w, http.StatusInternalServerError, response)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	if !isV2(r) {
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{"ok": true, "block": block})
+		return
+	}
+
+	// Version 2 returns the whole tip block rather than its hash.
+	tip, err := queryAnyBlock(sqliteDatabase, block)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+	if tip == nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "block_not_found", "no blocks found")
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, blockResponse(tip))
+}
+
+// getOrphanedBlocks lists valid blocks that are not on the main chain.
+func getOrphanedBlocks(w http.ResponseWriter, r *http.Request) {
+	blocks, err := querySideBlocks(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve blocks")
 		return
 	}
 
-	writeJSONResponse(w, http.StatusOK, map[string]interface{}{"ok": true, "block": block})
+	result := SideBlockListResponse{Blocks: make([]SideBlockResponse, 0, len(blocks))}
+	for i := range blocks {
+		result.Blocks = append(result.Blocks, SideBlockResponse{BlockResponse: blockResponse(&blocks[i].Block), Status: blocks[i].Status})
+	}
+
+	writeResponse(w, r, map[string]interface{}{"ok": true, "blocks": blocks}, result)
+}
+
+func getReorgs(w http.ResponseWriter, r *http.Request) {
+	reorgs, err := queryReorgs(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve reorgs")
+		return
+	}
+
+	result := ReorgListResponse{Reorgs: reorgs}
+	if result.Reorgs == nil {
+		result.Reorgs = []ReorgEvent{}
+	}
+
+	writeResponse(w, r, map[string]interface{}{"ok": true, "reorgs": reorgs}, result)
 }
 
 func getBlocks(w http.ResponseWriter, r *http.Request) {
 	blocks, err := queryBlocks(sqliteDatabase)
 	if err != nil {
-		response := map[string]interface{}{"ok": false, "error": "failed to retrieve blocks"}
-		writeJSONResponse(w, http.StatusInternalServerError, response)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve blocks")
 		return
 	}
 
@@ -183,67 +385,280 @@ func getBlocks(w http.ResponseWriter, r *http.Request) {
 		"ok":     true,
 		"blocks": blocks,
 	}
-	writeJSONResponse(w, http.StatusOK, response)
+	writeResponse(w, r, response, blockListResponse(blocks))
 }
 
-func submitBlock(w http.ResponseWriter, r *http.Request) {
-	var req submittedBlock
+func writeBlockDetail(w http.ResponseWriter, r *http.Request, blk *BlockDetail) {
+	response := BlockDetailResponse{BlockResponse: blockResponse(&blk.Block), Status: blk.Status, NextBlock: blk.NextBlock}
+	writeResponse(w, r, map[string]interface{}{"ok": true, "blocks": []BlockDetail{*blk}}, response)
+}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response := map[string]interface{}{"ok": false, "error": "invalid request body"}
-		writeJSONResponse(w, http.StatusBadRequest, response)
+// getBlockByHash finds a block on the main chain or a side branch.
+func getBlockByHash(w http.ResponseWriter, r *http.Request) {
+	blk, err := queryChainBlock(sqliteDatabase, r.PathValue("hash"))
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+	if blk == nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "block_not_found", "block not found")
+		return
+	}
+
+	writeBlockDetail(w, r, blk)
+}
+
+// getBlockAtHeight returns the main chain block at a height, counting the
+// genesis block as 0. The route is registered as /block/{by}/{n} because
+// /block/height/{n} would conflict with /block/{hash}/transactions, which
+// takes /block/height/transactions.
+func getBlockAtHeight(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("by") != "height" {
+		writeErrorResponse(w, r, http.StatusNotFound, "not_found", "not found")
 		return
 	}
 
-	qBlock, err := queryBlock(sqliteDatabase)
+	height, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || height < 0 {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_height", "height must be a non-negative integer")
+		return
+	}
 
+	blk, err := queryBlockAtHeight(sqliteDatabase, height)
 	if err != nil {
-		log.Fatalln(err.Error())
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
 		return
 	}
+	if blk == nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "block_not_found", "block not found")
+		return
+	}
+
+	writeBlockDetail(w, r, blk)
+}
 
-	if qBlock != req.PreviousBlock {
-		response := map[string]interface{}{"ok": false, "error": "previous block mismatch"}
-		writeJSONResponse(w, http.StatusBadRequest, response)
+// getBlockTransactions lists the transactions whose fees a block collected,
+// followed by its reward. Side blocks have none.
+func getBlockTransactions(w http.ResponseWriter, r *http.Request) {
+	hash := r.PathValue("hash")
+	blk, err := queryAnyBlock(sqliteDatabase, hash)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+	if blk == nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "block_not_found", "block not found")
 		return
 	}
 
-	if req.Block != genBlock(req.PreviousBlock, req.Address, req.Nonce) {
-		response := map[string]interface{}{"ok": false, "error": "invalid block"}
-		writeJSONResponse(w, http.StatusBadRequest, response)
+	transactions, err := queryBlockTransactions(sqliteDatabase, hash)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve transactions")
 		return
 	}
 
-	insertBlock(sqliteDatabase, req.Block, req.PreviousBlock, req.Address, req.Nonce, int(time.Now().Unix()))
-	oldBalance := queryAddress(sqliteDatabase, req.Address)
+	response := map[string]interface{}{
+		"ok":           true,
+		"transactions": transactions,
+	}
+	writeResponse(w, r, response, transactionListResponse(transactions))
+}
+
+// getChainTip describes the last main chain block and the work behind it.
+func getChainTip(w http.ResponseWriter, r *http.Request) {
+	tip, err := queryTipBlock(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+	if tip == nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "block_not_found", "no blocks found")
+		return
+	}
 
-	if oldBalance > 0 {
-		updateAddress(sqliteDatabase, req.Address, oldBalance+1)
-		insertTransaction(sqliteDatabase, "null", 1, req.Address, int(time.Now().Unix()))
-		response := map[string]interface{}{"ok": true}
-		writeJSONResponse(w, http.StatusOK, response)
+	work, err := queryChainWork(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
 		return
 	}
 
-	insertAddress(sqliteDatabase, req.Address, oldBalance+1)
-	insertTransaction(sqliteDatabase, "null", 1, req.Address, int(time.Now().Unix()))
+	result := ChainTipResponse{
+		Height: tip.Height,
+		Hash:   tip.BlockContent,
+		Time:   tip.Time,
+		Work:   work.String(),
+	}
 
-	response := map[string]interface{}{"ok": true}
-	writeJSONResponse(w, http.StatusOK, response)
+	response := map[string]interface{}{
+		"ok":     true,
+		"height": result.Height,
+		"hash":   result.Hash,
+		"time":   result.Time,
+		"work":   result.Work,
+	}
+	writeResponse(w, r, response, result)
+}
+
+func submitBlock(w http.ResponseWriter, r *http.Request) {
+	var req submittedBlock
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			blocksTotal.inc("rejected", "body_too_large")
+			writeErrorResponse(w, r, http.StatusRequestEntityTooLarge, "body_too_large", "request body too large")
+			return
+		}
+		blocksTotal.inc("rejected", "invalid_body")
+		recordInvalidBlock(r)
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	timestamp := int(time.Now().Unix())
+
+	outcome, ok := processBlock(w, r, req, timestamp)
+	if !ok {
+		return
+	}
+
+	response := map[string]interface{}{"ok": true, "reward": outcome.Payout, "branch": outcome.Branch}
+	if outcome.Reorg != nil {
+		response["reorg"] = outcome.Reorg
+	}
+	writeResponse(w, r, response, BlockResult{Reward: outcome.Payout, Branch: outcome.Branch, Reorg: outcome.Reorg})
+}
+
+// applyBlock validates and accepts a block, whether mined against this
+// node or sent over gRPC. Invalid blocks are returned as
+// a *rejection and already accepted ones as errKnownBlock.
+func applyBlock(req submittedBlock, timestamp int) (BlockOutcome, error) {
+	outcome, err := acceptBlock(req, timestamp, nil)
+	switch {
+	case errors.Is(err, errKnownBlock):
+		return outcome, err
+	case errors.Is(err, errPrevBlockMismatch):
+		return outcome, reject(blocksTotal, "prev_block_mismatch", http.StatusBadRequest, "previous block mismatch", err)
+	case errors.Is(err, errInvalidBlock):
+		return outcome, reject(blocksTotal, "invalid_block", http.StatusBadRequest, "invalid block", err)
+	case errors.Is(err, errInsufficientWork):
+		return outcome, reject(blocksTotal, "insufficient_work", http.StatusBadRequest, "block does not meet difficulty", err)
+	case err != nil:
+		log.Println("accepting block failed:", err)
+		return outcome, err
+	}
+
+	blocksTotal.inc("accepted", "")
+
+	return outcome, nil
+}
+
+// processBlock accepts a block for an HTTP request. On failure it writes
+// the error response and returns false.
+func processBlock(w http.ResponseWriter, r *http.Request, req submittedBlock, timestamp int) (BlockOutcome, bool) {
+	outcome, err := applyBlock(req, timestamp)
+
+	var rej *rejection
+	switch {
+	case errors.Is(err, errKnownBlock):
+		writeResponse(w, r, map[string]interface{}{"ok": true, "known": true}, BlockResult{Known: true})
+		return outcome, false
+	case errors.As(err, &rej):
+		if errors.Is(err, errInvalidBlock) || errors.Is(err, errInsufficientWork) {
+			recordInvalidBlock(r)
+		}
+		writeErrorDetails(w, r, rej.status, rej.code, rej.message, rej.details)
+		return outcome, false
+	case err != nil:
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return outcome, false
+	}
+
+	annotate(r, slog.String("block", req.Block), slog.String("miner", req.Address), slog.Int("payout", outcome.Payout), slog.String("branch", outcome.Branch))
+
+	return outcome, true
+}
+
+// Supply describes the supply of the native coin or an asset. The fee,
+// locked and escrowed amounts only apply to the native coin, and Mintable
+// only to assets.
+type Supply struct {
+	Asset             string `json:"asset"`
+	TotalSupply       int    `json:"totalSupply"`
+	CirculatingSupply int    `json:"circulatingSupply"`
+	PendingFees       int    `json:"pendingFees"`
+	FeesPaid          int    `json:"feesPaid"`
+	Locked            int    `json:"locked"`
+	Escrowed          int    `json:"escrowed"`
+	Mintable          bool   `json:"mintable"`
+}
+
+// querySupply returns the supply of an asset, or of the native coin if asset
+// is empty. It returns nil for an unknown asset.
+func querySupply(db dbtx, asset string) (*Supply, error) {
+	if asset != "" && asset != nativeAsset {
+		return queryAssetSupply(db, asset)
+	}
+
+	totalBalance, err := getSupply(db)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingFees, feesPaid, err := queryFees(db)
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := queryLocked(db, "")
+	if err != nil {
+		return nil, err
+	}
+
+	escrowed, err := queryEscrowed(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pending fees, locked transfers and escrows have left their senders'
+	// balances but haven't reached a miner or recipient yet, so they count
+	// towards the supply separately.
+	return &Supply{
+		Asset:             nativeAsset,
+		TotalSupply:       totalBalance + pendingFees + locked + escrowed,
+		CirculatingSupply: totalBalance,
+		PendingFees:       pendingFees,
+		FeesPaid:          feesPaid,
+		Locked:            locked,
+		Escrowed:          escrowed,
+	}, nil
 }
 
 func getTotalSupply(w http.ResponseWriter, r *http.Request) {
-	totalBalance, err := getSupply(sqliteDatabase)
+	supply, err := querySupply(sqliteDatabase, r.URL.Query().Get("asset"))
 	if err != nil {
-		response := map[string]interface{}{"ok": false, "error": "internal server error"}
-		writeJSONResponse(w, http.StatusInternalServerError, response)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+	if supply == nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "asset_not_found", "asset not found")
 		return
 	}
 
 	response := map[string]interface{}{
-		"ok":          true,
-		"totalSupply": totalBalance,
+		"ok":                true,
+		"asset":             supply.Asset,
+		"totalSupply":       supply.TotalSupply,
+		"circulatingSupply": supply.CirculatingSupply,
+	}
+	if supply.Asset == nativeAsset {
+		response["pendingFees"] = supply.PendingFees
+		response["feesPaid"] = supply.FeesPaid
+		response["locked"] = supply.Locked
+		response["escrowed"] = supply.Escrowed
+	} else {
+		response["mintable"] = supply.Mintable
 	}
 
-	writeJSONResponse(w, http.StatusOK, response)
+	writeResponse(w, r, response, supply)
 }
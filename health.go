@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// version and commit are set at build time with
+// -ldflags "-X main.version=... -X main.commit=...".
+var (
+	version = "dev"
+	commit  = ""
+)
+
+var startTime = time.Now()
+
+func buildCommit() string {
+	if commit != "" {
+		return commit
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+
+	return "unknown"
+}
+
+func getHealth(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, map[string]interface{}{"ok": true, "status": "alive"}, HealthResponse{Status: "alive"})
+}
+
+func getReadiness(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{}
+	ready := true
+
+	fail := func(name string, err error) {
+		checks[name] = err.Error()
+		ready = false
+	}
+
+	if err := sqliteDatabase.PingContext(r.Context()); err != nil {
+		fail("database", err)
+	} else {
+		checks["database"] = "ok"
+	}
+
+	if err := checkMigrations(sqliteDatabase); err != nil {
+		fail("migrations", err)
+	} else {
+		checks["migrations"] = "ok"
+	}
+
+	if tip, err := queryBlock(sqliteDatabase); err != nil {
+		fail("chainTip", err)
+	} else if tip == "" {
+		checks["chainTip"] = "no blocks found"
+		ready = false
+	} else {
+		checks["chainTip"] = "ok"
+	}
+
+	if replica != nil {
+		if _, synced := replica.lag(); synced {
+			checks["replication"] = "ok"
+		} else {
+			checks["replication"] = "not yet synced from primary"
+			ready = false
+		}
+	}
+
+	if !ready {
+		if isV2(r) {
+			writeErrorDetails(w, r, http.StatusServiceUnavailable, "not_ready", "not ready", map[string]interface{}{"checks": checks})
+			return
+		}
+		writeJSONResponse(w, http.StatusServiceUnavailable, map[string]interface{}{"ok": false, "error": "not ready", "checks": checks, "requestId": requestID(r)})
+		return
+	}
+
+	writeResponse(w, r, map[string]interface{}{"ok": true, "checks": checks}, ReadinessResponse{Checks: checks})
+}
+
+func getInfo(w http.ResponseWriter, r *http.Request) {
+	height, err := queryChainHeight(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	tip, err := queryBlock(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	genesis, err := queryGenesisBlock(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	info := InfoResponse{
+		Version:     version,
+		Commit:      buildCommit(),
+		Uptime:      int(time.Since(startTime).Seconds()),
+		Height:      height,
+		Tip:         tip,
+		Genesis:     genesis,
+		Difficulty:  serverConfig.Difficulty,
+		BlockReward: serverConfig.Reward.BlockReward,
+		MinFee:      serverConfig.Fees.MinFee,
+		FeePerByte:  serverConfig.Fees.PerByte,
+	}
+
+	response := map[string]interface{}{
+		"ok":          true,
+		"version":     info.Version,
+		"commit":      info.Commit,
+		"uptime":      info.Uptime,
+		"height":      info.Height,
+		"tip":         info.Tip,
+		"genesis":     info.Genesis,
+		"difficulty":  info.Difficulty,
+		"blockReward": info.BlockReward,
+		"minFee":      info.MinFee,
+		"feePerByte":  info.FeePerByte,
+	}
+	if replica != nil {
+		status := replica.status()
+		info.Replica = &status
+		response["replica"] = replica.info()
+	}
+
+	writeResponse(w, r, response, info)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultInvoiceExpiry = 24 * 60 * 60
+	maxInvoiceExpiry     = 30 * 24 * 60 * 60
+)
+
+type InvoiceRequest struct {
+	Address   string `json:"address"`
+	Amount    int    `json:"amount"`
+	Memo      string `json:"memo"`
+	ExpiresIn int    `json:"expiresIn"`
+}
+
+// paymentURI builds the go-cash: URI a wallet can pay. The invoice ID is
+// passed as ref and must be sent as the transaction memo.
+func paymentURI(inv *Invoice) string {
+	query := url.Values{}
+	query.Set("amount", strconv.Itoa(inv.Amount))
+	query.Set("ref", inv.ID)
+	if inv.Memo != "" {
+		query.Set("memo", inv.Memo)
+	}
+
+	return (&url.URL{Scheme: "go-cash", Opaque: inv.Address, RawQuery: query.Encode()}).String()
+}
+
+func invoiceStatus(inv *Invoice, now int) string {
+	switch {
+	case inv.PaidTransaction != 0:
+		return "paid"
+	case now >= inv.Expires:
+		return "expired"
+	default:
+		return "open"
+	}
+}
+
+func invoiceResponse(inv *Invoice) InvoiceResponse {
+	return InvoiceResponse{
+		ID:              inv.ID,
+		Address:         inv.Address,
+		Amount:          inv.Amount,
+		Memo:            inv.Memo,
+		Created:         inv.Created,
+		Expires:         inv.Expires,
+		Status:          invoiceStatus(inv, int(time.Now().Unix())),
+		PaidTransaction: inv.PaidTransaction,
+		PaidAt:          inv.PaidAt,
+		URI:             paymentURI(inv),
+	}
+}
+
+func createInvoice(w http.ResponseWriter, r *http.Request) {
+	var req InvoiceRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	if !validateAddress(req.Address) {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_address", "invalid address")
+		return
+	}
+
+	if req.Amount <= 0 {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_amount", "amount must be positive")
+		return
+	}
+
+	if !validateMemo(req.Memo) {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_memo", fmt.Sprintf("memo must be at most %d bytes without control characters", maxMemoLength))
+		return
+	}
+
+	if req.ExpiresIn == 0 {
+		req.ExpiresIn = defaultInvoiceExpiry
+	}
+	if req.ExpiresIn < 0 || req.ExpiresIn > maxInvoiceExpiry {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_expiry", fmt.Sprintf("expiresIn must be between 1 and %d seconds", maxInvoiceExpiry))
+		return
+	}
+
+	now := int(time.Now().Unix())
+	inv := &Invoice{
+		ID:      newInvoiceID(),
+		Address: req.Address,
+		Amount:  req.Amount,
+		Memo:    req.Memo,
+		Created: now,
+		Expires: now + req.ExpiresIn,
+	}
+	insertInvoice(sqliteDatabase, inv)
+
+	response := invoiceResponse(inv)
+	writeResponse(w, r, map[string]interface{}{"ok": true, "invoices": []InvoiceResponse{response}}, response)
+}
+
+func getInvoice(w http.ResponseWriter, r *http.Request) {
+	inv, err := queryInvoice(sqliteDatabase, r.PathValue("id"))
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+	if inv == nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "invoice_not_found", "invoice not found")
+		return
+	}
+
+	response := invoiceResponse(inv)
+	writeResponse(w, r, map[string]interface{}{"ok": true, "invoices": []InvoiceResponse{response}}, response)
+}
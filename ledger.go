@@ -0,0 +1,773 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+)
+
+var (
+	errFrozen            = errors.New("address frozen")
+	errInsufficientFunds = errors.New("insufficient funds")
+	errPrevBlockMismatch = errors.New("previous block mismatch")
+	errInvalidBlock      = errors.New("invalid block")
+	errInsufficientWork  = errors.New("block does not meet difficulty")
+	errScheduleNotFound  = errors.New("scheduled transfer not found")
+	errNotScheduleSender = errors.New("not the sender of the scheduled transfer")
+	errNotCancellable    = errors.New("scheduled transfer cannot be cancelled")
+	errEscrowNotFound    = errors.New("escrow not found")
+	errEscrowSettled     = errors.New("escrow already settled")
+	errNotEscrowParty    = errors.New("not a party to the escrow")
+	errUnknownAsset      = errors.New("unknown asset")
+	errAssetExists       = errors.New("asset already exists")
+	errNotIssuer         = errors.New("not the issuer of the asset")
+	errNotMintable       = errors.New("asset has a fixed supply")
+	errKnownBlock        = errors.New("block already known")
+	errKnownTransaction  = errors.New("transaction already known")
+	errUnknownAddress    = errors.New("unknown address")
+	errReorgDeficit      = errors.New("reorganization would leave a negative balance")
+	errMissingFees       = errors.New("block collects fees of unknown or already paid transactions")
+)
+
+// lockedAccount and escrowAccount are the counterparties recorded for funds
+// moving into and out of time-locked transfers and escrows. Newly issued
+// or minted tokens come from issuanceAccount.
+const (
+	lockedAccount   = "locked"
+	escrowAccount   = "escrow"
+	issuanceAccount = "issuance"
+)
+
+// nativeAsset is the asset ID of the built-in coin. Its balances are kept
+// in the addresses table and it is the only asset fees are paid in.
+const nativeAsset = "GC"
+
+// Votes a party can cast on an escrow.
+const (
+	escrowRelease = "release"
+	escrowRefund  = "refund"
+)
+
+// requiredFee returns the minimum fee for a transaction whose request body
+// is size bytes long.
+func requiredFee(size int) int {
+	return serverConfig.Fees.MinFee + serverConfig.Fees.PerByte*size
+}
+
+// transfer atomically debits amount of asset plus the fee in the native
+// coin from the sender and credits amount to the recipient. The fee stays
+// pending until the next accepted block pays it to its miner. A native
+// payment whose memo matches an open invoice for the recipient marks that
+// invoice paid.
+//
+// The hash identifies the transaction across peers, so a transaction that
+// was already applied under the same hash is rejected with
+// errKnownTransaction.
+func transfer(sender, recipient, asset string, amount, fee int, memo, hash string, timestamp int) (int64, error) {
+	var txID int64
+
+	err := withLedgerTx(func(tx *sql.Tx) error {
+		if transactionHashExists(tx, hash) {
+			return errKnownTransaction
+		}
+
+		if err := requireKeySender(tx, sender); err != nil {
+			return err
+		}
+
+		var err error
+		txID, err = transferAssetTx(tx, sender, recipient, asset, amount, fee, memo, timestamp)
+		if err != nil {
+			return err
+		}
+
+		setTransactionHash(tx, txID, hash)
+		return nil
+	})
+
+	return txID, err
+}
+
+// requireKeySender refuses to debit a registered multisig address on the
+// strength of a single private key. Multisig addresses created before they
+// were made longer than key addresses could otherwise be spent by anyone
+// holding a key that hashes to the same address.
+func requireKeySender(db dbtx, sender string) error {
+	ms, err := queryMultisigAddress(db, sender)
+	if err != nil {
+		return err
+	}
+	if ms != nil {
+		return errMultisigSender
+	}
+	return nil
+}
+
+func transferTx(tx *sql.Tx, sender, recipient string, amount, fee int, memo string, timestamp int) (int64, error) {
+	return transferAssetTx(tx, sender, recipient, nativeAsset, amount, fee, memo, timestamp)
+}
+
+func transferAssetTx(tx *sql.Tx, sender, recipient, asset string, amount, fee int, memo string, timestamp int) (int64, error) {
+	if queryAddressFrozen(tx, sender) {
+		return 0, errFrozen
+	}
+
+	if asset == nativeAsset {
+		if queryAddress(tx, sender) < amount+fee {
+			return 0, errInsufficientFunds
+		}
+	} else if queryBalance(tx, sender, asset) < amount || queryAddress(tx, sender) < fee {
+		return 0, errInsufficientFunds
+	}
+
+	creditBalance(tx, sender, asset, -amount)
+	if fee != 0 {
+		creditAddress(tx, sender, -fee)
+	}
+	creditBalance(tx, recipient, asset, amount)
+	txID := insertAssetTransaction(tx, sender, asset, amount, fee, recipient, memo, timestamp)
+
+	if memo != "" && asset == nativeAsset {
+		markInvoicePaid(tx, memo, recipient, amount, txID, timestamp)
+	}
+
+	return txID, nil
+}
+
+// issueAsset creates an asset and credits its initial supply to the issuer.
+func issueAsset(asset *Asset) (int64, error) {
+	var txID int64
+
+	err := withLedgerTx(func(tx *sql.Tx) error {
+		existing, err := queryAsset(tx, asset.ID)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return errAssetExists
+		}
+
+		if err := insertAsset(tx, asset); err != nil {
+			return err
+		}
+		insertAssetChange(tx, asset.Created, asset.ID)
+
+		if asset.Supply > 0 {
+			creditBalance(tx, asset.Issuer, asset.ID, asset.Supply)
+			txID = insertAssetTransaction(tx, issuanceAccount, asset.ID, asset.Supply, 0, asset.Issuer, "", asset.Created)
+		}
+
+		return nil
+	})
+
+	return txID, err
+}
+
+// mintAsset lets the issuer of a mintable asset create more of it.
+func mintAsset(id, issuer, recipient string, amount, timestamp int) (int64, error) {
+	var txID int64
+
+	err := withLedgerTx(func(tx *sql.Tx) error {
+		asset, err := queryAsset(tx, id)
+		if err != nil {
+			return err
+		}
+		if asset == nil {
+			return errUnknownAsset
+		}
+		if asset.Issuer != issuer {
+			return errNotIssuer
+		}
+		if !asset.Mintable {
+			return errNotMintable
+		}
+
+		addAssetSupply(tx, id, amount)
+		creditBalance(tx, recipient, id, amount)
+		txID = insertAssetTransaction(tx, issuanceAccount, id, amount, 0, recipient, "", timestamp)
+
+		return nil
+	})
+
+	return txID, err
+}
+
+// multisigTransfer spends from a multisig address once the transaction
+// carries the next nonce and at least threshold valid signatures.
+func multisigTransfer(txn *MultisigTransaction, timestamp int) (int64, error) {
+	var txID int64
+
+	err := withLedgerTx(func(tx *sql.Tx) error {
+		ms, err := queryMultisigAddress(tx, txn.From)
+		if err != nil {
+			return err
+		}
+		if ms == nil {
+			return errUnknownMultisig
+		}
+
+		if txn.Nonce != ms.Nonce+1 {
+			return errBadNonce
+		}
+
+		if countValidSignatures(ms, txn) < ms.Threshold {
+			return errNotEnoughSigs
+		}
+
+		txID, err = transferTx(tx, txn.From, txn.Address, txn.Amount, txn.Fee, txn.Memo, timestamp)
+		if err != nil {
+			return err
+		}
+
+		setMultisigNonce(tx, txn.From, txn.Nonce, timestamp)
+		return nil
+	})
+
+	return txID, err
+}
+
+// BlockOutcome describes where an accepted block ended up. Payout is what
+// its miner was paid, or 0 for a block stored on a side branch.
+type BlockOutcome struct {
+	Payout int
+	Branch string
+	Reorg  *ReorgEvent
+}
+
+// acceptBlock validates a block and, if it extends the tip, stores it and
+// pays its miner the block reward plus all pending fees. A block extending
+// any other known block is kept on a side branch, and if that branch
+// comes to have more work than the main chain the chain is reorganized
+// onto it.
+//
+// fees lists the transactions whose fees a block from a peer collects. For
+// a new block it is nil, and the block collects the fees pending now if it
+// extends the tip and none if it starts on a side branch.
+func acceptBlock(blk submittedBlock, timestamp int, fees []string) (BlockOutcome, error) {
+	var outcome BlockOutcome
+
+	if blk.Block != genBlock(blk.PreviousBlock, blk.Address, blk.Nonce) {
+		return outcome, errInvalidBlock
+	}
+
+	if !meetsDifficulty(blk.Block, serverConfig.Difficulty) {
+		return outcome, errInsufficientWork
+	}
+
+	block := Block{BlockContent: blk.Block, PrevBlock: blk.PreviousBlock, Address: blk.Address, Nonce: blk.Nonce, Time: timestamp, Fees: fees, Difficulty: serverConfig.Difficulty}
+
+	err := withLedgerTx(func(tx *sql.Tx) error {
+		if blockExists(tx, blk.Block) {
+			return errKnownBlock
+		}
+
+		tip, err := queryBlock(tx)
+		if err != nil {
+			return err
+		}
+
+		if tip == blk.PreviousBlock {
+			if block.Fees == nil {
+				if block.Fees, err = queryPendingFees(tx); err != nil {
+					return err
+				}
+			}
+			outcome.Payout, err = connectBlock(tx, block)
+			outcome.Branch = "main"
+			return err
+		}
+
+		if !blockExists(tx, blk.PreviousBlock) {
+			return errPrevBlockMismatch
+		}
+
+		if block.Fees == nil {
+			block.Fees = []string{}
+		}
+
+		insertSideBlock(tx, block, "side")
+		outcome.Branch = "side"
+
+		outcome.Reorg, outcome.Payout, err = reorganize(tx, blk.Block, tip, timestamp)
+		if outcome.Reorg != nil {
+			outcome.Branch = "main"
+		}
+		return err
+	})
+
+	if outcome.Reorg != nil {
+		reorgsTotal.inc()
+		slog.Warn("chain reorganization", "forkBlock", outcome.Reorg.ForkBlock, "oldTip", outcome.Reorg.OldTip,
+			"newTip", outcome.Reorg.NewTip, "orphaned", outcome.Reorg.Orphaned, "adopted", outcome.Reorg.Adopted)
+	}
+
+	return outcome, err
+}
+
+// connectBlock appends a block to the main chain and pays its miner the
+// reward plus the fees of the transactions it lists. It returns the payout.
+func connectBlock(tx *sql.Tx, blk Block) (int, error) {
+	fees, ok := collectFees(tx, blk.BlockContent, blk.Fees)
+	if !ok {
+		return 0, fmt.Errorf("%w: block %s", errMissingFees, blk.BlockContent)
+	}
+
+	insertBlock(tx, blk.BlockContent, blk.PrevBlock, blk.Address, blk.Nonce, blk.Time, blk.Fees, blk.Difficulty)
+	insertChange(tx, "block", blk.Time, blk.BlockContent, 0, "")
+
+	payout := serverConfig.Reward.BlockReward + fees
+	creditAddress(tx, blk.Address, payout)
+	insertRewardTransaction(tx, blk.Address, payout, blk.BlockContent, blk.Time)
+
+	return payout, nil
+}
+
+// disconnectBlock removes the tip block from the main chain, takes back its
+// miner's reward, returns the fees it collected to the pending pool and
+// locks again any time-locked transfer released at its height. If a miner
+// or recipient has already spent what is taken back it returns
+// errReorgDeficit rather than leave a negative balance.
+func disconnectBlock(tx *sql.Tx, blk Block, ts int) error {
+	rewards, err := reverseRewardTransactions(tx, blk.BlockContent, ts)
+	if err != nil {
+		return err
+	}
+	for miner, amount := range rewards {
+		if balance := queryAddress(tx, miner); balance < amount {
+			return fmt.Errorf("%w: miner %s of block %s has %d of its %d reward left", errReorgDeficit, miner, blk.BlockContent, balance, amount)
+		}
+		creditAddress(tx, miner, -amount)
+	}
+
+	if err := relockTransfers(tx, blk.Height-1, ts); err != nil {
+		return err
+	}
+
+	releaseFees(tx, blk.BlockContent)
+	deleteBlock(tx, blk.BlockContent)
+	insertChange(tx, "block_disconnected", ts, blk.BlockContent, 0, "")
+
+	return nil
+}
+
+// relockTransfers returns the funds of time-locked transfers that unlocked
+// above height to their locks, so they are released again once the chain
+// is back at their unlock height. The recipient's credit is reversed with a
+// transfer back to the locked account. Every node makes the same reversal
+// under the same hash, so one that already arrived from a peer is not
+// taken twice.
+func relockTransfers(tx *sql.Tx, height, ts int) error {
+	released, err := queryReleasedLocksAbove(tx, height)
+	if err != nil {
+		return err
+	}
+
+	for _, st := range released {
+		hash := derivedTransactionHash("relock", strconv.FormatInt(st.ID, 10), strconv.Itoa(st.Runs))
+		if !transactionHashExists(tx, hash) {
+			if balance := queryAddress(tx, st.Recipient); balance < st.Amount {
+				return fmt.Errorf("%w: recipient %s of locked transfer %d has %d of its %d left", errReorgDeficit, st.Recipient, st.ID, balance, st.Amount)
+			}
+
+			creditAddress(tx, st.Recipient, -st.Amount)
+			insertHashedTransaction(tx, hash, st.Recipient, nativeAsset, st.Amount, 0, lockedAccount, st.Memo, ts)
+		}
+
+		st.Status = "active"
+		st.Payments--
+		updateScheduledTransfer(tx, &st, ts)
+	}
+
+	return nil
+}
+
+// reorganize switches the main chain to the branch ending at head if that
+// branch has more work than the main chain above their common ancestor.
+// Blocks can have been accepted at different difficulties, so a shorter
+// branch can win; on a tie the chain seen first is kept. It returns the
+// reorg and the payout to head's miner, or nil if the main chain is kept.
+//
+// A reorg that would take back funds an address has already spent is
+// refused and the branch stays on the side, so balances never go negative.
+// It is tried again each time the branch grows.
+func reorganize(tx *sql.Tx, head, tip string, timestamp int) (*ReorgEvent, int, error) {
+	var branch []Block
+
+	fork := head
+	for {
+		blk, err := querySideBlock(tx, fork)
+		if err != nil {
+			return nil, 0, err
+		}
+		if blk == nil {
+			break
+		}
+		branch = append([]Block{*blk}, branch...)
+		fork = blk.PrevBlock
+	}
+
+	orphaned, err := queryBlocksAfter(tx, fork)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if branchWork(branch).Cmp(branchWork(orphaned)) <= 0 {
+		return nil, 0, nil
+	}
+
+	if _, err := tx.Exec("SAVEPOINT reorganize"); err != nil {
+		return nil, 0, err
+	}
+	for i := len(orphaned) - 1; i >= 0; i-- {
+		err := disconnectBlock(tx, orphaned[i], timestamp)
+		if errors.Is(err, errReorgDeficit) {
+			slog.Warn("chain reorganization refused", "newTip", head, "error", err)
+			reorgsRefused.inc()
+			if _, err := tx.Exec("ROLLBACK TO reorganize"); err != nil {
+				return nil, 0, err
+			}
+			_, err = tx.Exec("RELEASE reorganize")
+			return nil, 0, err
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		insertSideBlock(tx, orphaned[i], "orphaned")
+	}
+	if _, err := tx.Exec("RELEASE reorganize"); err != nil {
+		return nil, 0, err
+	}
+
+	var payout int
+	for _, blk := range branch {
+		deleteSideBlock(tx, blk.BlockContent)
+		if payout, err = connectBlock(tx, blk); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	ev := &ReorgEvent{Time: timestamp, ForkBlock: fork, OldTip: tip, NewTip: head, Orphaned: len(orphaned), Adopted: len(branch)}
+	ev.ID = insertReorg(tx, ev)
+
+	return ev, payout, nil
+}
+
+// lockTransfer debits amount plus fee from the sender and holds amount in
+// a scheduled transfer until the scheduler releases it to the recipient.
+// The fee is paid to the next block's miner like any other transfer.
+func lockTransfer(st *ScheduledTransfer) (int64, error) {
+	var txID int64
+
+	err := withLedgerTx(func(tx *sql.Tx) error {
+		if err := requireKeySender(tx, st.Sender); err != nil {
+			return err
+		}
+
+		if queryAddressFrozen(tx, st.Sender) {
+			return errFrozen
+		}
+
+		if queryAddress(tx, st.Sender) < st.Amount+st.Fee {
+			return errInsufficientFunds
+		}
+
+		creditAddress(tx, st.Sender, -(st.Amount + st.Fee))
+		txID = insertTransaction(tx, st.Sender, st.Amount, st.Fee, lockedAccount, st.Memo, st.Created)
+		st.ID = newRecordID()
+		st.Funding = txID
+		insertScheduledTransfer(tx, st, st.Created)
+
+		return nil
+	})
+
+	return txID, err
+}
+
+// addRecurringTransfer stores a recurring transfer. No funds move until
+// its first payment falls due.
+func addRecurringTransfer(st *ScheduledTransfer) error {
+	return withLedgerTx(func(tx *sql.Tx) error {
+		if err := requireKeySender(tx, st.Sender); err != nil {
+			return err
+		}
+
+		st.ID = newRecordID()
+		insertScheduledTransfer(tx, st, st.Created)
+		return nil
+	})
+}
+
+// runScheduledTransfer releases a due locked transfer or makes the next
+// payment of a recurring one. A recurring payment the sender can't cover
+// is counted as missed and the schedule moves on.
+//
+// Scheduled transfers are replicated, so every node runs them. A run's
+// transaction gets a hash and time derived from the schedule, and a run
+// whose transaction already arrived from a peer only updates the schedule.
+func runScheduledTransfer(id int64, now, height int) error {
+	return withLedgerTx(func(tx *sql.Tx) error {
+		st, err := queryScheduledTransfer(tx, id)
+		if err != nil {
+			return err
+		}
+		if st == nil || st.Status != "active" {
+			return nil
+		}
+
+		hash := derivedTransactionHash("schedule", strconv.FormatInt(st.ID, 10), strconv.Itoa(st.Runs))
+		at, err := scheduledRunTime(tx, st)
+		if err != nil {
+			return err
+		}
+		done := transactionHashExists(tx, hash)
+		st.Runs++
+
+		if st.Kind == "locked" {
+			if !done {
+				creditAddress(tx, st.Recipient, st.Amount)
+				txID := insertHashedTransaction(tx, hash, lockedAccount, nativeAsset, st.Amount, 0, st.Recipient, st.Memo, at)
+				if st.Memo != "" {
+					markInvoicePaid(tx, st.Memo, st.Recipient, st.Amount, txID, at)
+				}
+			}
+
+			st.Status = "released"
+			st.Payments++
+			updateScheduledTransfer(tx, st, now)
+			return nil
+		}
+
+		if done {
+			st.Payments++
+		} else {
+			txID, err := transferTx(tx, st.Sender, st.Recipient, st.Amount, st.Fee, st.Memo, at)
+			switch {
+			case errors.Is(err, errFrozen), errors.Is(err, errInsufficientFunds):
+				st.Missed++
+			case err != nil:
+				return err
+			default:
+				setTransactionHash(tx, txID, hash)
+				st.Payments++
+			}
+		}
+
+		// Periods that passed while the server was down are skipped rather
+		// than paid all at once.
+		if st.EverySeconds > 0 {
+			for st.UnlockTime <= now {
+				st.UnlockTime += st.EverySeconds
+			}
+		} else {
+			for st.UnlockHeight <= height {
+				st.UnlockHeight += st.EveryBlocks
+			}
+		}
+		updateScheduledTransfer(tx, st, now)
+
+		return nil
+	})
+}
+
+// scheduledRunTime is the time a scheduled transfer fell due: the later of
+// its unlock time and the time of the block at its unlock height.
+func scheduledRunTime(db dbtx, st *ScheduledTransfer) (int, error) {
+	at := st.UnlockTime
+	if st.UnlockHeight > 0 {
+		blk, err := queryBlockAtHeight(db, st.UnlockHeight)
+		if err != nil {
+			return 0, err
+		}
+		if blk != nil && blk.Time > at {
+			at = blk.Time
+		}
+	}
+	return at, nil
+}
+
+// cancelScheduledTransfer stops a recurring transfer on behalf of its
+// sender. Locked transfers are irrevocable once accepted.
+func cancelScheduledTransfer(id int64, sender string, timestamp int) (*ScheduledTransfer, error) {
+	var st *ScheduledTransfer
+
+	err := withLedgerTx(func(tx *sql.Tx) error {
+		var err error
+		st, err = queryScheduledTransfer(tx, id)
+		if err != nil {
+			return err
+		}
+		if st == nil {
+			return errScheduleNotFound
+		}
+		if st.Sender != sender {
+			return errNotScheduleSender
+		}
+		if st.Kind != "recurring" || st.Status != "active" {
+			return errNotCancellable
+		}
+
+		st.Status = "cancelled"
+		updateScheduledTransfer(tx, st, timestamp)
+		return nil
+	})
+
+	return st, err
+}
+
+// openEscrow debits amount plus fee from the buyer and holds amount in the
+// escrow until it is settled.
+func openEscrow(e *Escrow) (int64, error) {
+	var txID int64
+
+	err := withLedgerTx(func(tx *sql.Tx) error {
+		if err := requireKeySender(tx, e.Buyer); err != nil {
+			return err
+		}
+
+		if queryAddressFrozen(tx, e.Buyer) {
+			return errFrozen
+		}
+
+		if queryAddress(tx, e.Buyer) < e.Amount+e.Fee {
+			return errInsufficientFunds
+		}
+
+		creditAddress(tx, e.Buyer, -(e.Amount + e.Fee))
+		txID = insertTransaction(tx, e.Buyer, e.Amount, e.Fee, escrowAccount, e.Memo, e.Created)
+		e.ID = newRecordID()
+		e.Funding = txID
+		insertEscrow(tx, e, e.Created)
+
+		return nil
+	})
+
+	return txID, err
+}
+
+// voteEscrow records a party's vote to release or refund an open escrow
+// and settles it once two parties agree. A party may change their vote
+// until then.
+func voteEscrow(id int64, party, vote string, timestamp int) (*Escrow, error) {
+	var e *Escrow
+
+	err := withLedgerTx(func(tx *sql.Tx) error {
+		var err error
+		e, err = queryEscrow(tx, id)
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			return errEscrowNotFound
+		}
+		if e.Status != "open" {
+			return errEscrowSettled
+		}
+
+		switch party {
+		case e.Buyer:
+			e.BuyerVote, e.BuyerVoted = vote, timestamp
+		case e.Seller:
+			e.SellerVote, e.SellerVoted = vote, timestamp
+		case e.Arbiter:
+			e.ArbiterVote, e.ArbiterVoted = vote, timestamp
+		default:
+			return errNotEscrowParty
+		}
+
+		if outcome, at := escrowOutcome(e); outcome != "" {
+			if err := settleEscrowTx(tx, e, outcome, escrowSettlementHash(e.ID), at); err != nil {
+				return err
+			}
+		}
+
+		updateEscrow(tx, e, timestamp)
+		return nil
+	})
+
+	return e, err
+}
+
+// escrowOutcome returns the outcome at least two of an escrow's parties
+// voted for and the time of the latest of those votes, or "" if no two
+// agree.
+func escrowOutcome(e *Escrow) (string, int) {
+	votes := []struct {
+		vote string
+		at   int
+	}{{e.BuyerVote, e.BuyerVoted}, {e.SellerVote, e.SellerVoted}, {e.ArbiterVote, e.ArbiterVoted}}
+
+	for _, outcome := range []string{escrowRelease, escrowRefund} {
+		count, at := 0, 0
+		for _, v := range votes {
+			if v.vote == outcome {
+				count++
+				at = max(at, v.at)
+			}
+		}
+		if count >= 2 {
+			return outcome, at
+		}
+	}
+
+	return "", 0
+}
+
+// escrowSettlementHash is the hash of an escrow's settlement. Every node
+// that settles the escrow uses it, so the escrowed amount is paid once.
+func escrowSettlementHash(id int64) string {
+	return derivedTransactionHash("escrow", strconv.FormatInt(id, 10))
+}
+
+// expireEscrow refunds an escrow that is still open at its expiry.
+func expireEscrow(id int64, timestamp int) error {
+	return withLedgerTx(func(tx *sql.Tx) error {
+		e, err := queryEscrow(tx, id)
+		if err != nil {
+			return err
+		}
+		if e == nil || e.Status != "open" || e.Expires > timestamp {
+			return nil
+		}
+
+		if err := settleEscrowTx(tx, e, escrowRefund, escrowSettlementHash(e.ID), e.Expires); err != nil {
+			return err
+		}
+		updateEscrow(tx, e, timestamp)
+		return nil
+	})
+}
+
+// settleEscrowTx pays the escrowed amount to the seller on release or back
+// to the buyer on refund, recording the payment under hash. If a payment
+// with that hash already arrived from a peer, the escrow takes its outcome
+// instead of paying again. The caller persists the updated escrow.
+func settleEscrowTx(tx *sql.Tx, e *Escrow, outcome, hash string, timestamp int) error {
+	paid, err := queryHashedTransaction(tx, hash)
+	if err != nil {
+		return err
+	}
+	if paid != nil {
+		outcome = escrowRelease
+		if paid.Recipient == e.Buyer {
+			outcome = escrowRefund
+		}
+	}
+
+	recipient, status := e.Seller, "released"
+	if outcome == escrowRefund {
+		recipient, status = e.Buyer, "refunded"
+	}
+
+	if paid != nil {
+		e.SettledTransaction, e.SettledAt = int64(paid.ID), paid.Time
+	} else {
+		creditAddress(tx, recipient, e.Amount)
+		e.SettledTransaction = insertHashedTransaction(tx, hash, escrowAccount, nativeAsset, e.Amount, 0, recipient, e.Memo, timestamp)
+		e.SettledAt = timestamp
+	}
+	e.Status = status
+
+	return nil
+}
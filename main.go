@@ -1,48 +1,327 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/grpc"
 )
 
 func main() {
-	var overwrite bool
-	var dbLocation string
-
-	flag.BoolVar(&overwrite, "o", false, "Overwrite the database")
-	flag.StringVar(&dbLocation, "db", "", "Path to the database file")
-	flag.Parse()
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "config" {
+		configCommand(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "tls" {
+		tlsCommand(args[1:])
+		return
+	}
 
-	if dbLocation == "" {
-		fmt.Println("Error: Database file name must be specified using the -db flag.")
+	cfg, err := loadConfig(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	serverConfig = cfg
+
+	level, _ := parseLogLevel(cfg.LogLevel)
+	slog.SetDefault(newLogger(os.Stderr, cfg.LogFormat, level))
 
-	if overwrite {
-		initDatabase(dbLocation)
+	if cfg.overwrite {
+		initDatabase(cfg.Database)
 	}
 
-	loadDatabase(dbLocation)
+	loadDatabase(cfg.Database)
 	defer sqliteDatabase.Close()
 
 	mux := http.NewServeMux()
+	registerRoutes(mux, cfg)
+
+	if err := configurePeers(cfg); err != nil {
+		log.Printf("Peer setup failed: %v\n", err)
+		sqliteDatabase.Close()
+		os.Exit(1)
+	}
+	if err := configureReplica(cfg); err != nil {
+		log.Printf("Replica setup failed: %v\n", err)
+		sqliteDatabase.Close()
+		os.Exit(1)
+	}
+
+	var handler http.Handler = mux
+	if replica != nil {
+		handler = forwardWrites(mux)
+	}
+	if cfg.RateLimit.Enabled {
+		limiter = newRateLimiter(cfg.RateLimit, realClock{})
+		handler = rateLimit(limiter, mux, handler)
+	}
+
+	server := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           logRequests(instrumentHandler(handler)),
+		ReadTimeout:       cfg.ReadTimeout.Duration,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout.Duration,
+		WriteTimeout:      cfg.WriteTimeout.Duration,
+		IdleTimeout:       cfg.IdleTimeout.Duration,
+	}
+	server.RegisterOnShutdown(stopLongPolls)
+
+	if cfg.TLS.Enabled {
+		if err := ensureCertificates(cfg.TLS); err != nil {
+			log.Printf("TLS setup failed: %v\n", err)
+			sqliteDatabase.Close()
+			os.Exit(1)
+		}
+		server.TLSConfig, err = serverTLSConfig(cfg.TLS)
+		if err != nil {
+			log.Printf("TLS setup failed: %v\n", err)
+			sqliteDatabase.Close()
+			os.Exit(1)
+		}
+		if fingerprint, err := certificateFingerprint(cfg.TLS.CertFile); err == nil {
+			log.Printf("TLS certificate fingerprint (SHA-256): %s\n", fingerprint)
+		}
+	}
+
+	var grpcServer *grpc.Server
+	if cfg.GRPCListen != "" {
+		grpcServer, err = newGRPCServer(cfg)
+		if err != nil {
+			log.Printf("gRPC setup failed: %v\n", err)
+			sqliteDatabase.Close()
+			os.Exit(1)
+		}
+	}
+
+	if err := serve(server, grpcServer, cfg); err != nil {
+		log.Printf("Server error: %v\n", err)
+		sqliteDatabase.Close()
+		os.Exit(1)
+	}
+}
+
+// routeMux is what routes are registered on. It is an *http.ServeMux except
+// in tests, which also record the patterns.
+type routeMux interface {
+	Handle(pattern string, handler http.Handler)
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+// registerRoutes registers every HTTP route on mux.
+func registerRoutes(mux routeMux, cfg *Config) {
+	limitBody := func(h http.HandlerFunc) http.Handler {
+		return http.MaxBytesHandler(h, cfg.MaxBodyBytes)
+	}
+
+	// Miners can be restricted to holders of a client certificate signed by
+	// the configured client CA.
+	blockHandler := limitBody(submitBlock)
+	if cfg.TLS.RequireMinerCerts {
+		blockHandler = requireClientCert(blockHandler)
+	}
+
+	// Peers identify themselves with a client certificate.
+	entriesHandler := requireClientCert(http.HandlerFunc(getPeerEntries))
+
+	// API routes are also served under /v2 with typed responses and
+	// structured errors. Node-to-node, metrics and snapshot routes aren't
+	// versioned.
+	handle := func(pattern string, handler http.Handler) {
+		method, path, _ := strings.Cut(pattern, " ")
+		mux.Handle(pattern, handler)
+		mux.Handle(method+" "+v2Prefix+path, handler)
+	}
+	handleFunc := func(pattern string, handler http.HandlerFunc) {
+		handle(pattern, handler)
+	}
+
+	handleFunc("GET /address/{address}", getAddress)                           // Get a single address
+	handleFunc("GET /addresses", getAddresses)                                 // Get all addresses
+	handle("POST /transaction", limitBody(createTransaction))                  // Create a transaction
+	handleFunc("GET /transaction/{id}", getTransaction)                        // Get single transaction by ID
+	handleFunc("GET /transactions/{address}", getAddressTransactions)          // Get all transactions relating to an address
+	handleFunc("GET /transactions", getTransactions)                           // Get all transactions from database
+	handle("POST /block", blockHandler)                                        // Submit a block
+	handleFunc("GET /block", getBlock)                                         // Get last block
+	handleFunc("GET /block/{hash}", getBlockByHash)                            // Get a block by hash
+	handleFunc("GET /block/{by}/{n}", getBlockAtHeight)                        // Get the main chain block at /block/height/{n}
+	handleFunc("GET /block/{hash}/transactions", getBlockTransactions)         // Get the transactions in a block
+	handleFunc("GET /chain/tip", getChainTip)                                  // Get the height, hash, time and cumulative work of the tip
+	handleFunc("GET /blocks", getBlocks)                                       // Get all blocks
+	handleFunc("GET /blocks/orphaned", getOrphanedBlocks)                      // Get side branch and orphaned blocks
+	handleFunc("GET /reorgs", getReorgs)                                       // Get chain reorganization events
+	handleFunc("GET /supply", getTotalSupply)                                  // Get total currency supply
+	handle("POST /invoice", limitBody(createInvoice))                          // Create a payment request
+	handleFunc("GET /invoice/{id}", getInvoice)                                // Get an invoice and its payment status
+	handle("POST /multisig", limitBody(createMultisig))                        // Create a multisig address
+	handleFunc("GET /multisig/{address}", getMultisig)                         // Describe a multisig address
+	handle("POST /multisig/transaction", limitBody(createMultisigTransaction)) // Spend from a multisig address
+	handle("POST /schedule", limitBody(createSchedule))                        // Create a time-locked or recurring transfer
+	handleFunc("GET /schedule/{id}", getSchedule)                              // Get a scheduled transfer
+	handle("POST /schedule/{id}/cancel", limitBody(cancelSchedule))            // Cancel a recurring transfer
+	handleFunc("GET /schedules/{address}", getAddressSchedules)                // Get scheduled transfers sent or received by an address
+	handle("POST /escrow", limitBody(createEscrow))                            // Lock funds in escrow for a seller
+	handleFunc("GET /escrow/{id}", getEscrow)                                  // Get an escrow and its votes
+	handle("POST /escrow/{id}/approve", limitBody(approveEscrow))              // Vote to release an escrow to the seller
+	handle("POST /escrow/{id}/refund", limitBody(refundEscrow))                // Vote to refund an escrow to the buyer
+	handle("POST /asset", limitBody(createAsset))                              // Issue a new asset
+	handle("POST /asset/{id}/mint", limitBody(mintAssetSupply))                // Mint more of a mintable asset
+	handleFunc("GET /assets", getAssets)                                       // Get all issued assets
+	mux.Handle("GET /p2p/entries", entriesHandler)                             // Ledger entries for peers to apply, with long-polling
+	handleFunc("GET /changes", getChanges)                                     // Ledger changes after a cursor, with optional long-polling
+	mux.HandleFunc("GET /metrics", getMetrics)                                 // Prometheus metrics
+	handleFunc("GET /healthz", getHealth)                                      // Process liveness
+	handleFunc("GET /readyz", getReadiness)                                    // Database, schema and chain tip readiness
+	handleFunc("GET /info", getInfo)                                           // Node and economy information
+	mux.HandleFunc("GET /openapi.json", getOpenAPI)                            // OpenAPI description of this API
+
+	mux.HandleFunc("GET /{$}", getExplorerHome)                              // Block explorer: latest blocks, transactions and supply
+	mux.HandleFunc("GET /explorer/block/{hash}", getExplorerBlock)           // Explorer page for a block
+	mux.HandleFunc("GET /explorer/address/{address}", getExplorerAddress)    // Explorer page for an address's balance and history
+	mux.HandleFunc("GET /explorer/transaction/{id}", getExplorerTransaction) // Explorer page for a transaction
+	mux.HandleFunc("GET /explorer/search", searchExplorer)                   // Find an address, transaction ID or block hash
+	mux.Handle("GET /explorer/static/", explorerStatic())                    // Explorer stylesheet
+
+	handleFunc("GET /admin/config", requireRole("viewer", getAdminConfig))                         // View effective server config
+	handleFunc("GET /admin/audit", requireRole("viewer", getAdminAudit))                           // View the audit log
+	handleFunc("GET /admin/conflicts", requireRole("viewer", getAdminConflicts))                   // View peer entries refused as conflicts
+	handleFunc("POST /admin/address/{address}/freeze", requireRole("operator", freezeAddress))     // Freeze an address
+	handleFunc("POST /admin/address/{address}/unfreeze", requireRole("operator", unfreezeAddress)) // Unfreeze an address
+	handleFunc("POST /admin/verify", requireRole("operator", triggerVerification))                 // Verify chain and balances
+	handle("POST /admin/adjustment", limitBody(requireRole("admin", createAdjustment)))            // Mint or burn funds
+
+	// Replicas identify themselves with a client certificate.
+	if cfg.Replica.Primary == "" {
+		mux.Handle("GET /replication/snapshot", requireClientCert(http.HandlerFunc(getReplicationSnapshot))) // Download a database snapshot for a replica
+	}
+}
+
+// serve runs the server, and the gRPC server if configured, until SIGINT or
+// SIGTERM is received, then waits for in-flight requests to finish before
+// returning.
+func serve(server *http.Server, grpcServer *grpc.Server, cfg *Config) error {
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return err
+	}
+
+	var grpcListener net.Listener
+	if grpcServer != nil {
+		grpcListener, err = net.Listen("tcp", cfg.GRPCListen)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// A replica's data comes from its primary, which runs the scheduler.
+	if replica != nil {
+		go runReplication(ctx, cfg.Replica.SyncInterval.Duration)
+	} else {
+		go runScheduler(ctx, cfg.SchedulerInterval.Duration)
+	}
+	if len(peerList) > 0 {
+		runPeerSync(ctx, cfg.P2P.SyncInterval.Duration)
+	}
+
+	serveErr := make(chan error, 2)
+	if grpcServer != nil {
+		go func() {
+			log.Printf("gRPC server listening to %s\n", grpcListener.Addr())
+			serveErr <- grpcServer.Serve(grpcListener)
+		}()
+	}
+	go func() {
+		log.Printf("Server listening to %s\n", listener.Addr())
+		if cfg.TLS.Enabled {
+			serveErr <- server.ServeTLS(listener, cfg.TLS.CertFile, cfg.TLS.KeyFile)
+			return
+		}
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down, draining in-flight requests...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
+	defer cancel()
+
+	grpcStopped := make(chan struct{})
+	go func() {
+		if grpcServer != nil {
+			stopGRPC(shutdownCtx, grpcServer)
+		}
+		close(grpcStopped)
+	}()
+
+	err = server.Shutdown(shutdownCtx)
+	<-grpcStopped
+	if err != nil {
+		return err
+	}
+	log.Println("Server stopped")
+
+	return nil
+}
+
+func configCommand(args []string) {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Println("Usage: gc-server config print [flags]")
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig(args[1:])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := cfg.print(os.Stdout); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func tlsCommand(args []string) {
+	if len(args) < 2 || args[0] != "client-cert" {
+		fmt.Println("Usage: gc-server tls client-cert <name> [flags]")
+		os.Exit(1)
+	}
+
+	name := args[1]
+	cfg, err := loadConfig(args[2:])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := issueClientCertificate(cfg.TLS, name, name+".crt", name+".key"); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 
-	mux.HandleFunc("GET /address/{address}", getAddress)                  // Get a single address
-	mux.HandleFunc("GET /addresses", getAddresses)                        // Get all addresses
-	mux.HandleFunc("POST /transaction", createTransaction)                // Create a transaction
-	mux.HandleFunc("GET /transaction/{id}", getTransaction)               // Get single transaction by ID
-	mux.HandleFunc("GET /transactions/{address}", getAddressTransactions) // Get all transactions relating to an address
-	mux.HandleFunc("GET /transactions", getTransactions)                  // Get all transactions from database
-	mux.HandleFunc("POST /block", submitBlock)                            // Submit a block
-	mux.HandleFunc("GET /block", getBlock)                                // Get last block
-	mux.HandleFunc("GET /blocks", getBlocks)                              // Get all blocks
-	mux.HandleFunc("GET /supply", getTotalSupply)                         // Get total currency supply
-
-	log.Println("Server listening to :8080")
-	http.ListenAndServe(":8080", mux)
+	fmt.Printf("Client certificate written to %s.crt and %s.key\n", name, name)
 }
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var defaultBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+var (
+	transactionsTotal = newCounterVec("gocash_transactions_total", "Transactions processed, by result and rejection reason.", "result", "reason")
+	blocksTotal       = newCounterVec("gocash_blocks_total", "Blocks submitted, by result and rejection reason.", "result", "reason")
+	reorgsTotal       = newCounterVec("gocash_reorgs_total", "Chain reorganizations to a branch with more work.")
+	reorgsRefused     = newCounterVec("gocash_reorgs_refused_total", "Reorganizations refused because they would leave a negative balance.")
+	peerConflicts     = newCounterVec("gocash_peer_conflicts_total", "Peer entries refused because they conflict with the local ledger, by entry type.", "type")
+	httpDuration      = newHistogramVec("gocash_http_request_duration_seconds", "HTTP request latency by route.", "method", "route", "code")
+	dbQueryDuration   = newHistogramVec("gocash_db_query_duration_seconds", "Database query latency by query.", "query")
+)
+
+// counterVec is a minimal Prometheus counter with labels.
+type counterVec struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	labels []string
+	values map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+}
+
+func (c *counterVec) inc(labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[strings.Join(labelValues, "\x00")]++
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, strings.Split(key, "\x00")), formatFloat(c.values[key]))
+	}
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// histogramVec is a minimal Prometheus histogram with labels.
+type histogramVec struct {
+	mu      sync.Mutex
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	values  map[string]*histogram
+}
+
+func newHistogramVec(name, help string, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: defaultBuckets, values: map[string]*histogram{}}
+}
+
+func (h *histogramVec) observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := strings.Join(labelValues, "\x00")
+	hist, ok := h.values[key]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hist
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			hist.counts[i]++
+		}
+	}
+	hist.sum += value
+	hist.count++
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.values) {
+		hist := h.values[key]
+		labelValues := strings.Split(key, "\x00")
+		bucketLabels := append(append([]string{}, h.labels...), "le")
+
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(bucketLabels, append(append([]string{}, labelValues...), formatFloat(bound))), hist.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(bucketLabels, append(append([]string{}, labelValues...), "+Inf")), hist.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, labelValues), formatFloat(hist.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, labelValues), hist.count)
+	}
+}
+
+func writeGauge(w io.Writer, name, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, formatFloat(value))
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%s", name, strconv.Quote(values[i]))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// observeQuery records the latency of a database query. Call it with defer
+// at the top of a database function.
+func observeQuery(query string, start time.Time) {
+	dbQueryDuration.observe(time.Since(start).Seconds(), query)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// instrumentHandler records request latency labelled with the route pattern
+// the mux matched, so path values such as addresses don't explode the label
+// cardinality.
+func instrumentHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		httpDuration.observe(time.Since(start).Seconds(), r.Method, route, strconv.Itoa(rec.status))
+	})
+}
+
+func getMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	height, err := queryChainHeight(sqliteDatabase)
+	if err != nil {
+		log.Println("metrics: chain height:", err)
+	}
+	supply, err := getSupply(sqliteDatabase)
+	if err != nil {
+		log.Println("metrics: supply:", err)
+	}
+	addressCount, err := queryAddressCount(sqliteDatabase)
+	if err != nil {
+		log.Println("metrics: address count:", err)
+	}
+
+	transactionsTotal.write(w)
+	blocksTotal.write(w)
+	reorgsTotal.write(w)
+	reorgsRefused.write(w)
+	peerConflicts.write(w)
+	writeGauge(w, "gocash_chain_height", "Height of the chain tip, with the genesis block at height 0.", float64(height))
+	writeGauge(w, "gocash_total_supply", "Total currency supply across all addresses.", float64(supply))
+	writeGauge(w, "gocash_addresses", "Number of known addresses.", float64(addressCount))
+	if replica != nil {
+		if lag, synced := replica.lag(); synced {
+			writeGauge(w, "gocash_replication_lag_seconds", "Time since the replica last had every change from the primary.", lag.Seconds())
+		}
+	}
+	httpDuration.write(w)
+	dbQueryDuration.write(w)
+}
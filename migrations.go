@@ -0,0 +1,346 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// migrations are applied in order on startup. Append new entries with the
+// next version number; never edit or reorder existing ones.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "index transactions and blocks",
+		sql: `CREATE INDEX IF NOT EXISTS transactions_sender ON transactions(sender);
+		CREATE INDEX IF NOT EXISTS transactions_recipient ON transactions(recipient);
+		CREATE INDEX IF NOT EXISTS blocks_block ON blocks(block);
+		CREATE INDEX IF NOT EXISTS addresses_address ON addresses(address);`,
+	},
+	{
+		version: 2,
+		name:    "admin freezes, adjustments and audit log",
+		sql: `ALTER TABLE addresses ADD COLUMN frozen INTEGER NOT NULL DEFAULT 0;
+		CREATE TABLE adjustments (
+			"id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+			"address" TEXT,
+			"amount" INTEGER,
+			"reason" TEXT,
+			"actor" TEXT,
+			"transaction" INTEGER,
+			"time" INTEGER
+		);
+		CREATE TABLE audit_log (
+			"id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+			"time" INTEGER,
+			"actor" TEXT,
+			"role" TEXT,
+			"action" TEXT,
+			"target" TEXT,
+			"details" TEXT,
+			"requestId" TEXT
+		);
+		CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'audit_log is append-only'); END;
+		CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'audit_log is append-only'); END;`,
+	},
+	{
+		version: 3,
+		name:    "transaction fees",
+		sql: `ALTER TABLE transactions ADD COLUMN fee INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE transactions ADD COLUMN block TEXT;
+		CREATE INDEX transactions_block ON transactions(block);`,
+	},
+	{
+		version: 4,
+		name:    "transaction memos",
+		sql: `ALTER TABLE transactions ADD COLUMN memo TEXT NOT NULL DEFAULT '';
+		CREATE INDEX transactions_memo ON transactions(memo);`,
+	},
+	{
+		version: 5,
+		name:    "invoices",
+		sql: `CREATE TABLE invoices (
+			"id" TEXT NOT NULL PRIMARY KEY,
+			"address" TEXT,
+			"amount" INTEGER,
+			"memo" TEXT,
+			"created" INTEGER,
+			"expires" INTEGER,
+			"paidTransaction" INTEGER,
+			"paidAt" INTEGER
+		);`,
+	},
+	{
+		version: 6,
+		name:    "multisig addresses",
+		sql: `CREATE TABLE multisig_addresses (
+			"address" TEXT NOT NULL PRIMARY KEY,
+			"threshold" INTEGER,
+			"pubkeys" TEXT,
+			"nonce" INTEGER,
+			"created" INTEGER
+		);`,
+	},
+	{
+		version: 7,
+		name:    "scheduled transfers",
+		sql: `CREATE TABLE scheduled_transfers (
+			"id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+			"kind" TEXT,
+			"sender" TEXT,
+			"recipient" TEXT,
+			"amount" INTEGER,
+			"fee" INTEGER,
+			"memo" TEXT,
+			"unlockTime" INTEGER,
+			"unlockHeight" INTEGER,
+			"everySeconds" INTEGER,
+			"everyBlocks" INTEGER,
+			"status" TEXT,
+			"payments" INTEGER NOT NULL DEFAULT 0,
+			"missed" INTEGER NOT NULL DEFAULT 0,
+			"created" INTEGER
+		);
+		CREATE INDEX scheduled_transfers_status ON scheduled_transfers(status);
+		CREATE INDEX scheduled_transfers_recipient ON scheduled_transfers(recipient);
+		CREATE INDEX scheduled_transfers_sender ON scheduled_transfers(sender);`,
+	},
+	{
+		version: 8,
+		name:    "escrows",
+		sql: `CREATE TABLE escrows (
+			"id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+			"buyer" TEXT,
+			"seller" TEXT,
+			"arbiter" TEXT,
+			"amount" INTEGER,
+			"fee" INTEGER,
+			"memo" TEXT,
+			"buyerVote" TEXT NOT NULL DEFAULT '',
+			"sellerVote" TEXT NOT NULL DEFAULT '',
+			"arbiterVote" TEXT NOT NULL DEFAULT '',
+			"status" TEXT,
+			"created" INTEGER,
+			"expires" INTEGER,
+			"settledTransaction" INTEGER,
+			"settledAt" INTEGER
+		);
+		CREATE INDEX escrows_status ON escrows(status);`,
+	},
+	{
+		version: 9,
+		name:    "assets",
+		sql: `CREATE TABLE assets (
+			"id" TEXT NOT NULL PRIMARY KEY,
+			"name" TEXT,
+			"issuer" TEXT,
+			"supply" INTEGER,
+			"mintable" INTEGER,
+			"created" INTEGER
+		);
+		CREATE TABLE asset_balances (
+			"address" TEXT NOT NULL,
+			"asset" TEXT NOT NULL,
+			"balance" INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (address, asset)
+		);
+		ALTER TABLE transactions ADD COLUMN asset TEXT NOT NULL DEFAULT 'GC';
+		CREATE INDEX transactions_asset ON transactions(asset);`,
+	},
+	{
+		version: 10,
+		name:    "peer replication",
+		sql: `ALTER TABLE transactions ADD COLUMN hash TEXT;
+		UPDATE transactions SET hash = lower(hex(randomblob(16))) WHERE sender != 'null' AND recipient != 'null';
+		CREATE UNIQUE INDEX transactions_hash ON transactions(hash);
+		ALTER TABLE blocks ADD COLUMN fees TEXT NOT NULL DEFAULT '[]';
+		UPDATE blocks SET fees = (SELECT json_group_array(t.hash) FROM transactions t
+			WHERE t.block = blocks.block AND t.sender != 'null' AND t.recipient != 'null');
+		ALTER TABLE escrows ADD COLUMN funding INTEGER;
+		ALTER TABLE escrows ADD COLUMN buyerVoted INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE escrows ADD COLUMN sellerVoted INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE escrows ADD COLUMN arbiterVoted INTEGER NOT NULL DEFAULT 0;
+		UPDATE escrows SET funding = (SELECT t.id FROM transactions t WHERE t.sender = escrows.buyer
+			AND t.recipient = 'escrow' AND t.amount = escrows.amount AND t.time = escrows.created ORDER BY t.id LIMIT 1);
+		ALTER TABLE scheduled_transfers ADD COLUMN funding INTEGER;
+		ALTER TABLE scheduled_transfers ADD COLUMN runs INTEGER NOT NULL DEFAULT 0;
+		UPDATE scheduled_transfers SET runs = payments + missed;
+		UPDATE scheduled_transfers SET funding = (SELECT t.id FROM transactions t WHERE t.sender = scheduled_transfers.sender
+			AND t.recipient = 'locked' AND t.amount = scheduled_transfers.amount AND t.time = scheduled_transfers.created ORDER BY t.id LIMIT 1)
+			WHERE kind = 'locked';
+		CREATE TABLE changes (
+			"id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+			"type" TEXT NOT NULL,
+			"time" INTEGER,
+			"block" TEXT,
+			"transaction" INTEGER,
+			"address" TEXT,
+			"asset" TEXT,
+			"escrow" INTEGER,
+			"schedule" INTEGER
+		);
+		INSERT INTO changes(type, time, block, "transaction", address, asset)
+		SELECT type, time, block, txn, address, asset FROM (
+			SELECT 'address' AS type, COALESCE((SELECT MIN(t.time) FROM transactions t WHERE t.recipient = a.address), 0) AS time,
+				NULL AS block, NULL AS txn, a.address AS address, NULL AS asset, 0 AS kind, a.id AS seq FROM addresses a
+			UNION ALL
+			SELECT 'asset', created, NULL, NULL, NULL, id, 1, rowid FROM assets
+			UNION ALL
+			SELECT 'multisig', created, NULL, NULL, address, NULL, 1, rowid FROM multisig_addresses
+			UNION ALL
+			SELECT 'block', time, block, NULL, NULL, NULL, 2, id FROM blocks
+			UNION ALL
+			SELECT CASE WHEN sender IN ('null', 'issuance', 'adjustment') THEN 'mint' ELSE 'transfer' END,
+				time, NULL, id, NULL, NULL, 3, id FROM transactions
+		) ORDER BY time, kind, seq;
+		CREATE TABLE peer_cursors (
+			"peer" TEXT NOT NULL PRIMARY KEY,
+			"cursor" INTEGER NOT NULL
+		);
+		CREATE TABLE peer_conflicts (
+			"id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+			"peer" TEXT NOT NULL,
+			"cursor" INTEGER NOT NULL,
+			"type" TEXT NOT NULL,
+			"entry" TEXT NOT NULL,
+			"reason" TEXT NOT NULL,
+			"time" INTEGER NOT NULL
+		);`,
+	},
+	{
+		version: 11,
+		name:    "side branches and reorgs",
+		sql: `CREATE TABLE side_blocks (
+			"block" TEXT NOT NULL PRIMARY KEY,
+			"prevBlock" TEXT,
+			"address" TEXT,
+			"nonce" TEXT,
+			"time" INTEGER,
+			"fees" TEXT NOT NULL DEFAULT '[]',
+			"status" TEXT
+		);
+		CREATE TABLE reorgs (
+			"id" integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+			"time" INTEGER,
+			"forkBlock" TEXT,
+			"oldTip" TEXT,
+			"newTip" TEXT,
+			"orphaned" INTEGER,
+			"adopted" INTEGER
+		);`,
+	},
+	{
+		version: 12,
+		name:    "block heights",
+		sql: `ALTER TABLE blocks ADD COLUMN height INTEGER;
+		UPDATE blocks SET height = (SELECT COUNT(*) FROM blocks b WHERE b.id < blocks.id);
+		CREATE UNIQUE INDEX blocks_height ON blocks(height);
+		ALTER TABLE side_blocks ADD COLUMN height INTEGER;
+		UPDATE side_blocks SET height = (
+			WITH RECURSIVE branch(block, prevBlock, depth) AS (
+				SELECT side_blocks.block, side_blocks.prevBlock, 1
+				UNION ALL
+				SELECT s.block, s.prevBlock, branch.depth + 1 FROM side_blocks s JOIN branch ON s.block = branch.prevBlock
+			)
+			SELECT b.height + branch.depth FROM branch JOIN blocks b ON b.block = branch.prevBlock
+		);`,
+	},
+	{
+		version: 13,
+		name:    "block difficulty",
+		sql: `ALTER TABLE blocks ADD COLUMN difficulty INTEGER NOT NULL DEFAULT -1;
+		ALTER TABLE side_blocks ADD COLUMN difficulty INTEGER NOT NULL DEFAULT -1;`,
+	},
+}
+
+func createMigrationsTable(db *sql.DB) error {
+	createTableSQL := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		"version" INTEGER NOT NULL PRIMARY KEY,
+		"name" TEXT,
+		"appliedAt" INTEGER
+	  );`
+
+	_, err := db.Exec(createTableSQL)
+	return err
+}
+
+func migrateDatabase(db *sql.DB) {
+	if err := createMigrationsTable(db); err != nil {
+		log.Fatal("Error creating schema_migrations table:", err)
+	}
+
+	current, err := querySchemaVersion(db)
+	if err != nil {
+		log.Fatal("Error reading schema version:", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		log.Printf("Applying migration %d: %s...\n", m.version, m.name)
+		if err := applyMigration(db, m); err != nil {
+			log.Fatalf("Error applying migration %d: %v", m.version, err)
+		}
+	}
+}
+
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.sql); err != nil {
+		return err
+	}
+
+	insertSQL := `INSERT INTO schema_migrations(version, name, appliedAt) VALUES (?, ?, ?)`
+	if _, err := tx.Exec(insertSQL, m.version, m.name, int(time.Now().Unix())); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func querySchemaVersion(db *sql.DB) (int, error) {
+	defer observeQuery("querySchemaVersion", time.Now())
+
+	querySQL := "SELECT COALESCE(MAX(version), 0) FROM schema_migrations"
+	var version int
+
+	err := db.QueryRow(querySQL).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+func latestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func checkMigrations(db *sql.DB) error {
+	version, err := querySchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	if version < latestSchemaVersion() {
+		return fmt.Errorf("schema version %d, want %d", version, latestSchemaVersion())
+	}
+
+	return nil
+}
@@ -1,134 +1,96 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"crypto/sha256"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
-)
+	"strconv"
+	"strings"
 
-const serverURL = "http://localhost:8080"
+	"github.com/hypnophobe/go-cash/client"
+)
 
-type BlockResponse struct {
-	Block string `json:"block"`
-	Ok    bool   `json:"ok"`
-}
+var serverURL = "http://localhost:8080"
 
-type SubmittedBlock struct {
-	Block         string `json:"block"`
-	PreviousBlock string `json:"prevBlock"`
-	Address       string `json:"address"`
-	Nonce         string `json:"nonce"`
-}
-
-type Address struct {
-	Address string `json:"address"`
-	Balance int    `json:"balance"`
-}
-
-type GetAddressResponse struct {
-	Addresses []Address `json:"addresses"`
-	Ok        bool      `json:"ok"`
-}
+var api *client.Client
 
 func getPrevBlock() (string, error) {
-	resp, err := http.Get(serverURL + "/block")
-	if err != nil {
-		return "", fmt.Errorf("failed to send GET request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var blockResp BlockResponse
-	if err := json.NewDecoder(resp.Body).Decode(&blockResp); err != nil {
-		return "", fmt.Errorf("failed to decode GET response: %v", err)
-	}
-
-	if !blockResp.Ok {
-		return "", fmt.Errorf("GET response was not successful")
-	}
-
-	return blockResp.Block, nil
+	return api.Tip(context.Background())
 }
 
-func submitBlock(prevBlock string) (bool, error) {
-	block := generateBlock(prevBlock)
-
-	subBlock := SubmittedBlock{
-		Block:         block,
-		PreviousBlock: prevBlock,
-		Address:       *address,
-		Nonce:         "nonce",
-	}
-
-	body, err := json.Marshal(subBlock)
-	if err != nil {
-		return false, fmt.Errorf("failed to marshal POST body: %v", err)
-	}
-
-	resp, err := http.Post(serverURL+"/block", "application/json", bytes.NewReader(body))
-	if err != nil {
-		return false, fmt.Errorf("failed to send POST request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	responseBody, err := ioutil.ReadAll(resp.Body)
+func getDifficulty() (int, error) {
+	info, err := api.Info(context.Background())
 	if err != nil {
-		return false, fmt.Errorf("failed to read response body: %v", err)
-	}
-
-	if len(responseBody) == 0 {
-		return false, fmt.Errorf("received empty response body")
-	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(responseBody, &result); err != nil {
-		return false, fmt.Errorf("failed to decode POST response: %v", err)
-	}
-
-	ok, exists := result["ok"].(bool)
-	if !exists || !ok {
-		return false, fmt.Errorf("POST response not successful")
+		return 0, err
 	}
+	return info.Difficulty, nil
+}
 
-	return true, nil
+func submitBlock(prevBlock, block, nonce string) error {
+	_, err := api.SubmitBlock(context.Background(), client.BlockSubmission{
+		Block:     block,
+		PrevBlock: prevBlock,
+		Address:   *address,
+		Nonce:     nonce,
+	})
+	return err
 }
 
-func generateBlock(prevBlock string) string {
-	data := prevBlock + *address + "nonce"
+func generateBlock(prevBlock, nonce string) string {
+	data := prevBlock + *address + nonce
 	hash := sha256.New()
 	hash.Write([]byte(data))
 	return fmt.Sprintf("%x", hash.Sum(nil))
 }
 
-func getBalance(address string) (int, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/address/%s", serverURL, address))
-	if err != nil {
-		return 0, fmt.Errorf("failed to fetch balance: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var balanceResp GetAddressResponse
-	if err := json.NewDecoder(resp.Body).Decode(&balanceResp); err != nil {
-		return 0, fmt.Errorf("failed to decode balance response: %v", err)
+// mineBlock searches for a nonce whose block hash starts with difficulty
+// zero hex digits.
+func mineBlock(prevBlock string, difficulty int) (string, string) {
+	target := strings.Repeat("0", difficulty)
+	for i := 0; ; i++ {
+		nonce := strconv.Itoa(i)
+		block := generateBlock(prevBlock, nonce)
+		if strings.HasPrefix(block, target) {
+			return block, nonce
+		}
 	}
+}
 
-	if !balanceResp.Ok || len(balanceResp.Addresses) == 0 {
-		return 0, fmt.Errorf("could not fetch balance")
+func getBalance(address string) (int, error) {
+	addr, err := api.Address(context.Background(), address)
+	if err != nil {
+		return 0, err
 	}
-
-	return balanceResp.Addresses[0].Balance, nil
+	return addr.Balance, nil
 }
 
 var address = flag.String("a", "", "The address to deposit mined funds")
+var server = flag.String("server", serverURL, "The server URL")
+var caFile = flag.String("ca", "", "A CA certificate file to trust for HTTPS")
+var fingerprint = flag.String("fingerprint", "", "The pinned SHA-256 fingerprint of the server certificate")
+var certFile = flag.String("cert", "", "A client certificate for mutual TLS")
+var keyFile = flag.String("key", "", "The private key for the client certificate")
 
 func main() {
 	flag.Parse()
 
+	httpClient, err := client.NewHTTPClient(client.TLSOptions{
+		CAFile:      *caFile,
+		Fingerprint: *fingerprint,
+		CertFile:    *certFile,
+		KeyFile:     *keyFile,
+	})
+	if err != nil {
+		log.Fatalf("Error configuring HTTPS: %v", err)
+	}
+	api, err = client.New(*server, client.WithHTTPClient(httpClient))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
 	if *address == "" {
 		fmt.Println("Error: address is required")
 		flag.Usage()
@@ -142,16 +104,17 @@ func main() {
 		}
 		fmt.Printf("prevBlock: %s\n", prevBlock)
 
-		newBlock := generateBlock(prevBlock)
+		difficulty, err := getDifficulty()
+		if err != nil {
+			log.Fatalf("Error fetching difficulty: %v", err)
+		}
+
+		newBlock, nonce := mineBlock(prevBlock, difficulty)
 		fmt.Printf("newBlock: %s\n", newBlock)
 
-		ok, err := submitBlock(prevBlock)
-		if err != nil {
+		if err := submitBlock(prevBlock, newBlock, nonce); err != nil {
 			log.Fatalf("Error submitting block: %v", err)
 		}
-		if !ok {
-			log.Fatal("Block submission failed")
-		}
 
 		balance, err := getBalance(*address)
 		if err != nil {
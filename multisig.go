@@ -0,0 +1,272 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxMultisigKeys = 15
+
+var (
+	errBadNonce          = errors.New("invalid nonce")
+	errNotEnoughSigs     = errors.New("not enough valid signatures")
+	errUnknownMultisig   = errors.New("unknown multisig address")
+	errInvalidPublicKeys = errors.New("invalid public keys")
+	errMultisigSender    = errors.New("multisig addresses can only be spent from with signatures")
+)
+
+type MultisigRequest struct {
+	Threshold  int      `json:"threshold"`
+	PublicKeys []string `json:"pubkeys"`
+}
+
+type MultisigSignature struct {
+	PublicKey string `json:"pubkey"`
+	Signature string `json:"signature"`
+}
+
+// MultisigTransaction is a transfer out of a multisig address. Co-signers
+// pass it around as a file, each appending a signature over signingMessage,
+// until it carries enough signatures to broadcast.
+type MultisigTransaction struct {
+	From       string              `json:"from"`
+	Address    string              `json:"address"`
+	Amount     int                 `json:"amount"`
+	Fee        int                 `json:"fee"`
+	Memo       string              `json:"memo"`
+	Nonce      int                 `json:"nonce"`
+	Signatures []MultisigSignature `json:"signatures"`
+}
+
+func (t *MultisigTransaction) signingMessage() []byte {
+	return []byte(strings.Join([]string{
+		"go-cash-multisig",
+		t.From,
+		t.Address,
+		strconv.Itoa(t.Amount),
+		strconv.Itoa(t.Fee),
+		t.Memo,
+		strconv.Itoa(t.Nonce),
+	}, "\n"))
+}
+
+// normalizePublicKeys validates hex encoded ed25519 public keys and returns
+// them lowercased and sorted so the same set always derives the same address.
+func normalizePublicKeys(keys []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		raw, err := hex.DecodeString(key)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: %q is not a hex encoded ed25519 public key", errInvalidPublicKeys, key)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: duplicate key %s", errInvalidPublicKeys, key)
+		}
+		seen[key] = true
+		normalized = append(normalized, key)
+	}
+
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// multisigAddressLength is longer than the 12 characters of a key's
+// address, so an address derived from a key set can never be one a single
+// private key spends from.
+const multisigAddressLength = 16
+
+func multisigAddress(threshold int, publicKeys []string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("multisig:%d:%s", threshold, strings.Join(publicKeys, ","))))
+	return hex.EncodeToString(sum[:])[:multisigAddressLength]
+}
+
+// countValidSignatures returns how many distinct keys of the multisig set
+// produced a valid signature over the transaction.
+func countValidSignatures(ms *MultisigAddress, txn *MultisigTransaction) int {
+	members := map[string]bool{}
+	for _, key := range ms.PublicKeys {
+		members[key] = true
+	}
+
+	message := txn.signingMessage()
+	signed := map[string]bool{}
+
+	for _, sig := range txn.Signatures {
+		key := strings.ToLower(sig.PublicKey)
+		if !members[key] || signed[key] {
+			continue
+		}
+
+		pub, err := hex.DecodeString(key)
+		if err != nil {
+			continue
+		}
+		signature, err := hex.DecodeString(sig.Signature)
+		if err != nil {
+			continue
+		}
+
+		if ed25519.Verify(pub, message, signature) {
+			signed[key] = true
+		}
+	}
+
+	return len(signed)
+}
+
+func multisigResponse(ms *MultisigAddress) MultisigResponse {
+	return MultisigResponse{
+		Address:    ms.Address,
+		Threshold:  ms.Threshold,
+		PublicKeys: ms.PublicKeys,
+		Nonce:      ms.Nonce,
+		Balance:    queryAddress(sqliteDatabase, ms.Address),
+	}
+}
+
+func createMultisig(w http.ResponseWriter, r *http.Request) {
+	var req MultisigRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	keys, err := normalizePublicKeys(req.PublicKeys)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_public_keys", err.Error())
+		return
+	}
+
+	if len(keys) == 0 || len(keys) > maxMultisigKeys {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_public_keys", fmt.Sprintf("between 1 and %d public keys are required", maxMultisigKeys))
+		return
+	}
+
+	if req.Threshold < 1 || req.Threshold > len(keys) {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_threshold", "threshold must be between 1 and the number of public keys")
+		return
+	}
+
+	ms := &MultisigAddress{
+		Address:    multisigAddress(req.Threshold, keys),
+		Threshold:  req.Threshold,
+		PublicKeys: keys,
+	}
+	err = withLedgerTx(func(tx *sql.Tx) error {
+		insertMultisigAddress(tx, ms, int(time.Now().Unix()))
+		return nil
+	})
+	if err != nil {
+		log.Println("registering multisig address failed:", err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	response := multisigResponse(ms)
+	writeResponse(w, r, map[string]interface{}{"ok": true, "multisig": response}, response)
+}
+
+func getMultisig(w http.ResponseWriter, r *http.Request) {
+	ms, err := queryMultisigAddress(sqliteDatabase, r.PathValue("address"))
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+	if ms == nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "multisig_not_found", "multisig address not found")
+		return
+	}
+
+	response := multisigResponse(ms)
+	writeResponse(w, r, map[string]interface{}{"ok": true, "multisig": response}, response)
+}
+
+func createMultisigTransaction(w http.ResponseWriter, r *http.Request) {
+	var req MultisigTransaction
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		transactionsTotal.inc("rejected", "invalid_body")
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		transactionsTotal.inc("rejected", "invalid_body")
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	if !validateAddress(req.Address) {
+		transactionsTotal.inc("rejected", "invalid_address")
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_address", "invalid address")
+		return
+	}
+
+	if req.Amount <= 0 {
+		transactionsTotal.inc("rejected", "invalid_amount")
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_amount", "amount must be positive")
+		return
+	}
+
+	// The fee is signed before all signatures are collected, so the size
+	// used for per-byte fees leaves the signatures out.
+	unsigned := req
+	unsigned.Signatures = nil
+	unsignedBody, _ := json.Marshal(unsigned)
+
+	if !checkMemoAndFee(w, r, req.Memo, req.Fee, len(unsignedBody)) {
+		return
+	}
+
+	txID, err := multisigTransfer(&req, int(time.Now().Unix()))
+	switch {
+	case errors.Is(err, errUnknownMultisig):
+		transactionsTotal.inc("rejected", "unknown_multisig")
+		writeErrorResponse(w, r, http.StatusNotFound, "multisig_not_found", "multisig address not found")
+		return
+	case errors.Is(err, errBadNonce):
+		transactionsTotal.inc("rejected", "bad_nonce")
+		writeErrorResponse(w, r, http.StatusConflict, "invalid_nonce", "invalid nonce")
+		return
+	case errors.Is(err, errNotEnoughSigs):
+		transactionsTotal.inc("rejected", "not_enough_signatures")
+		writeErrorResponse(w, r, http.StatusForbidden, "insufficient_signatures", "not enough valid signatures")
+		return
+	case errors.Is(err, errFrozen):
+		transactionsTotal.inc("rejected", "frozen")
+		writeErrorResponse(w, r, http.StatusForbidden, "frozen", "address frozen")
+		return
+	case errors.Is(err, errInsufficientFunds):
+		transactionsTotal.inc("rejected", "insufficient_funds")
+		writeErrorResponse(w, r, http.StatusBadRequest, "insufficient_funds", "insufficient funds")
+		return
+	case err != nil:
+		log.Println("multisig transfer failed:", err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	annotate(r, slog.Int64("transactionId", txID), slog.String("multisig", req.From))
+	transactionsTotal.inc("accepted", "")
+
+	response := map[string]interface{}{"ok": true, "transaction": txID, "amount": req.Amount, "fee": req.Fee}
+	writeResponse(w, r, response, MultisigResult{TransactionID: txID, Amount: req.Amount, Fee: req.Fee})
+}
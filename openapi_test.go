@@ -0,0 +1,569 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hypnophobe/go-cash/client"
+)
+
+// openAPIDoc is the part of openapi.json the tests check responses against.
+// Schemas are left as decoded JSON.
+type openAPIDoc struct {
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas   map[string]map[string]interface{} `json:"schemas"`
+		Responses map[string]openAPIResponse        `json:"responses"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Responses map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIResponse struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema map[string]interface{} `json:"schema"`
+	} `json:"content"`
+}
+
+func loadOpenAPI(t *testing.T) *openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return &doc
+}
+
+// recordingMux records the patterns registerRoutes registers.
+type recordingMux struct {
+	*http.ServeMux
+	patterns []string
+}
+
+func (m *recordingMux) Handle(pattern string, handler http.Handler) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.Handle(pattern, handler)
+}
+
+func (m *recordingMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// newTestAPI opens a fresh database and registers every route, returning
+// the mux and the handler the server would run.
+func newTestAPI(t *testing.T) (*recordingMux, http.Handler) {
+	t.Helper()
+
+	logger := slog.Default()
+	slog.SetDefault(newLogger(io.Discard, "text", slog.LevelInfo))
+	t.Cleanup(func() { slog.SetDefault(logger) })
+
+	cfg := defaultConfig()
+	cfg.Database = filepath.Join(t.TempDir(), "test.db")
+	cfg.Difficulty = 0
+	cfg.Admin.Token = testAdminToken
+	serverConfig = cfg
+
+	initDatabase(cfg.Database)
+	loadDatabase(cfg.Database)
+	t.Cleanup(func() { sqliteDatabase.Close() })
+
+	mux := &recordingMux{ServeMux: http.NewServeMux()}
+	registerRoutes(mux, cfg)
+	return mux, logRequests(instrumentHandler(mux))
+}
+
+var pathParam = regexp.MustCompile(`\{[^}]+\}`)
+
+func TestRoutesAreDocumented(t *testing.T) {
+	mux, _ := newTestAPI(t)
+	spec := loadOpenAPI(t)
+
+	documented := map[string]bool{}
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			req := httptest.NewRequest(strings.ToUpper(method), pathParam.ReplaceAllString(path, "sample"), nil)
+			_, pattern := mux.Handler(req)
+			if pattern == "" {
+				t.Errorf("%s %s is documented but not routed", strings.ToUpper(method), path)
+				continue
+			}
+			documented[pattern] = true
+		}
+	}
+
+	for _, pattern := range mux.patterns {
+		if !documented[pattern] {
+			t.Errorf("%s is registered but not documented", pattern)
+		}
+	}
+}
+
+// apiChecker sends requests through the API and checks each response
+// against the operation openapi.json documents for it.
+type apiChecker struct {
+	t         *testing.T
+	spec      *openAPIDoc
+	handler   http.Handler
+	exercised map[string]bool
+}
+
+// call sends a request, checks the response, and returns its decoded JSON
+// body, or nil if it isn't JSON.
+func (c *apiChecker) call(method, target string, body interface{}, admin bool) (int, map[string]interface{}) {
+	c.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if admin {
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	}
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+
+	where := method + " " + target
+	path := c.specPath(method, req.URL.Path)
+	if path == "" {
+		c.t.Errorf("%s: no documented operation", where)
+		return rec.Code, nil
+	}
+	c.exercised[method+" "+path] = true
+
+	response, ok := c.spec.Paths[path][strings.ToLower(method)].Responses[strconv.Itoa(rec.Code)]
+	if !ok {
+		c.t.Errorf("%s: status %d is not documented for %s %s: %s", where, rec.Code, method, path, rec.Body)
+		return rec.Code, nil
+	}
+	if ref := strings.TrimPrefix(response.Ref, "#/components/responses/"); ref != "" {
+		response = c.spec.Components.Responses[ref]
+	}
+
+	if len(response.Content) == 0 {
+		if rec.Code != http.StatusFound && rec.Body.Len() > 0 {
+			c.t.Errorf("%s: status %d has an undocumented body", where, rec.Code)
+		}
+		return rec.Code, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	media, ok := response.Content[mediaType]
+	if !ok {
+		c.t.Errorf("%s: content type %q is not documented for status %d", where, mediaType, rec.Code)
+		return rec.Code, nil
+	}
+	if mediaType != "application/json" {
+		return rec.Code, nil
+	}
+
+	decoder := json.NewDecoder(rec.Body)
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		c.t.Errorf("%s: %v", where, err)
+		return rec.Code, nil
+	}
+	for _, problem := range c.spec.validate(media.Schema, value, "body", true) {
+		c.t.Errorf("%s: status %d: %s", where, rec.Code, problem)
+	}
+	object, _ := value.(map[string]interface{})
+	return rec.Code, object
+}
+
+// specPath finds the documented path a request path matches, preferring
+// literal segments over parameters.
+func (c *apiChecker) specPath(method, urlPath string) string {
+	segments := strings.Split(strings.Trim(urlPath, "/"), "/")
+	best, bestLiterals := "", -1
+	for path, operations := range c.spec.Paths {
+		if _, ok := operations[strings.ToLower(method)]; !ok {
+			continue
+		}
+		parts := strings.Split(strings.Trim(path, "/"), "/")
+		if len(parts) != len(segments) {
+			continue
+		}
+		literals := 0
+		for i, part := range parts {
+			if pathParam.MatchString(part) {
+				continue
+			}
+			if part != segments[i] {
+				literals = -1
+				break
+			}
+			literals++
+		}
+		if literals > bestLiterals {
+			best, bestLiterals = path, literals
+		}
+	}
+	return best
+}
+
+// schemaAt resolves a $ref.
+func (d *openAPIDoc) schemaAt(schema map[string]interface{}) map[string]interface{} {
+	for {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		schema = d.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+	}
+}
+
+// properties collects the properties an object schema documents, including
+// those of its allOf parts. open is true if it allows others.
+func (d *openAPIDoc) properties(schema map[string]interface{}) (properties map[string]interface{}, open bool) {
+	schema = d.schemaAt(schema)
+	properties = map[string]interface{}{}
+	if _, ok := schema["additionalProperties"]; ok {
+		open = true
+	}
+	if own, ok := schema["properties"].(map[string]interface{}); ok {
+		for name, property := range own {
+			properties[name] = property
+		}
+	} else if _, ok := schema["allOf"]; !ok {
+		open = true
+	}
+	parts, _ := schema["allOf"].([]interface{})
+	for _, part := range parts {
+		inherited, partOpen := d.properties(part.(map[string]interface{}))
+		for name, property := range inherited {
+			properties[name] = property
+		}
+		open = open || partOpen
+	}
+	return properties, open
+}
+
+// validate checks a value decoded with UseNumber against a schema. Objects
+// with properties may only have undocumented ones if additionalProperties
+// allows them; closed is false for allOf parts, which are checked together.
+func (d *openAPIDoc) validate(schema map[string]interface{}, value interface{}, at string, closed bool) []string {
+	schema = d.schemaAt(schema)
+	if value == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{at + ": null is not allowed"}
+	}
+
+	var problems []string
+	parts, _ := schema["allOf"].([]interface{})
+	for _, part := range parts {
+		problems = append(problems, d.validate(part.(map[string]interface{}), value, at, false)...)
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || fmt.Sprint(allowed) == fmt.Sprint(value)
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: %v is not one of %v", at, value, enum))
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return append(problems, at+": not an object")
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return append(problems, at+": not an array")
+		}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range items {
+				problems = append(problems, d.validate(itemSchema, item, fmt.Sprintf("%s[%d]", at, i), true)...)
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return append(problems, at+": not a string")
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(s) {
+			problems = append(problems, fmt.Sprintf("%s: %q does not match %s", at, s, pattern))
+		}
+		if max, ok := schema["maxLength"].(float64); ok && len([]rune(s)) > int(max) {
+			problems = append(problems, fmt.Sprintf("%s: %q is longer than %v", at, s, max))
+		}
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			return append(problems, at+": not a number")
+		}
+		f, err := n.Float64()
+		if schema["type"] == "integer" {
+			_, err = n.Int64()
+		}
+		if err != nil {
+			return append(problems, fmt.Sprintf("%s: %s is not an %s", at, n, schema["type"]))
+		}
+		if min, ok := schema["minimum"].(float64); ok && f < min {
+			problems = append(problems, fmt.Sprintf("%s: %s is less than %v", at, n, min))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return append(problems, at+": not a boolean")
+		}
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return problems
+	}
+	required, _ := schema["required"].([]interface{})
+	for _, name := range required {
+		if _, ok := object[name.(string)]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: missing %s", at, name))
+		}
+	}
+	own, _ := schema["properties"].(map[string]interface{})
+	for name, property := range own {
+		if v, ok := object[name]; ok {
+			problems = append(problems, d.validate(property.(map[string]interface{}), v, at+"."+name, true)...)
+		}
+	}
+	if extra, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+		for name, v := range object {
+			if _, ok := own[name]; !ok {
+				problems = append(problems, d.validate(extra, v, at+"."+name, true)...)
+			}
+		}
+	}
+	if closed {
+		documented, open := d.properties(schema)
+		for name := range object {
+			if _, ok := documented[name]; !ok && !open {
+				problems = append(problems, fmt.Sprintf("%s: %s is not documented", at, name))
+			}
+		}
+	}
+	return problems
+}
+
+// field reads a nested field of a decoded body as a string.
+func field(body map[string]interface{}, path ...interface{}) string {
+	var value interface{} = body
+	for _, step := range path {
+		switch step := step.(type) {
+		case string:
+			object, _ := value.(map[string]interface{})
+			value = object[step]
+		case int:
+			array, _ := value.([]interface{})
+			if step >= len(array) {
+				return ""
+			}
+			value = array[step]
+		}
+	}
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func TestResponsesMatchSpec(t *testing.T) {
+	_, handler := newTestAPI(t)
+	c := &apiChecker{t: t, spec: loadOpenAPI(t), handler: handler, exercised: map[string]bool{}}
+
+	alice, bob, carol := "alice-key", "bob-key", "carol-key"
+	aliceAddr, bobAddr, carolAddr := generateAddress(alice), generateAddress(bob), generateAddress(carol)
+	minerAddr := generateAddress("miner-key")
+	seed1, seed2 := sha256.Sum256([]byte("cosigner one")), sha256.Sum256([]byte("cosigner two"))
+	key1, key2 := ed25519.NewKeyFromSeed(seed1[:]), ed25519.NewKeyFromSeed(seed2[:])
+	pubkeys := []string{
+		hex.EncodeToString(key1.Public().(ed25519.PublicKey)),
+		hex.EncodeToString(key2.Public().(ed25519.PublicKey)),
+	}
+
+	expect := func(method, target string, got, want int) {
+		t.Helper()
+		if got != want {
+			t.Fatalf("%s %s = %d, want %d", method, target, got, want)
+		}
+	}
+
+	// Each API route is exercised under both prefixes, with the v2 call
+	// creating its own objects where the route writes.
+	prev := "0"
+	var txn, blockHash string
+	for _, prefix := range []string{"", v2Prefix} {
+		status, _ := c.call("POST", prefix+"/admin/adjustment", client.AdjustmentRequest{Address: aliceAddr, Amount: 1000, Reason: "test funds"}, true)
+		expect("POST", prefix+"/admin/adjustment", status, http.StatusOK)
+
+		status, body := c.call("POST", prefix+"/transaction", client.TransactionRequest{Pkey: alice, Address: bobAddr, Amount: 100, Fee: 5, Memo: "rent"}, false)
+		expect("POST", prefix+"/transaction", status, http.StatusOK)
+		if prefix == "" {
+			txn = field(body, "transaction")
+		}
+
+		nonce := "nonce" + prefix
+		blockHash = genBlock(prev, minerAddr, nonce)
+		status, _ = c.call("POST", prefix+"/block", client.BlockSubmission{Block: blockHash, PrevBlock: prev, Address: minerAddr, Nonce: nonce}, false)
+		expect("POST", prefix+"/block", status, http.StatusOK)
+		prev = blockHash
+
+		status, body = c.call("POST", prefix+"/invoice", client.InvoiceRequest{Address: bobAddr, Amount: 10, Memo: "order", ExpiresIn: 3600}, false)
+		expect("POST", prefix+"/invoice", status, http.StatusOK)
+		invoice := field(body, "invoices", 0, "id")
+		if prefix != "" {
+			invoice = field(body, "id")
+		}
+		c.call("GET", prefix+"/invoice/"+invoice, nil, false)
+
+		// Multisig addresses depend only on their keys, so the v2 call
+		// registers a 2-of-3 address.
+		keys := pubkeys
+		if prefix != "" {
+			seed := sha256.Sum256([]byte("cosigner three"))
+			keys = append(keys, hex.EncodeToString(ed25519.NewKeyFromSeed(seed[:]).Public().(ed25519.PublicKey)))
+		}
+		status, body = c.call("POST", prefix+"/multisig", map[string]interface{}{"threshold": 2, "pubkeys": keys}, false)
+		expect("POST", prefix+"/multisig", status, http.StatusOK)
+		multisig := field(body, "multisig", "address")
+		if prefix != "" {
+			multisig = field(body, "address")
+		}
+		c.call("GET", prefix+"/multisig/"+multisig, nil, false)
+		c.call("POST", prefix+"/transaction", client.TransactionRequest{Pkey: alice, Address: multisig, Amount: 50, Fee: 1}, false)
+		spend := &client.MultisigTransaction{From: multisig, Address: carolAddr, Amount: 20, Fee: 1, Nonce: 1}
+		spend.Sign(key1)
+		spend.Sign(key2)
+		status, _ = c.call("POST", prefix+"/multisig/transaction", spend, false)
+		expect("POST", prefix+"/multisig/transaction", status, http.StatusOK)
+
+		status, body = c.call("POST", prefix+"/schedule", client.ScheduleRequest{Pkey: alice, Address: carolAddr, Amount: 5, Fee: 1, EverySeconds: 3600}, false)
+		expect("POST", prefix+"/schedule", status, http.StatusOK)
+		schedule := field(body, "schedules", 0, "id")
+		if prefix != "" {
+			schedule = field(body, "schedule", "id")
+		}
+		c.call("GET", prefix+"/schedule/"+schedule, nil, false)
+		c.call("GET", prefix+"/schedules/"+aliceAddr, nil, false)
+		status, _ = c.call("POST", prefix+"/schedule/"+schedule+"/cancel", map[string]string{"pkey": alice}, false)
+		expect("POST", prefix+"/schedule/"+schedule+"/cancel", status, http.StatusOK)
+
+		escrows := make([]string, 2)
+		for i := range escrows {
+			status, body = c.call("POST", prefix+"/escrow", client.EscrowRequest{Pkey: alice, Seller: bobAddr, Arbiter: carolAddr, Amount: 10, Fee: 1}, false)
+			expect("POST", prefix+"/escrow", status, http.StatusOK)
+			escrows[i] = field(body, "escrows", 0, "id")
+			if prefix != "" {
+				escrows[i] = field(body, "escrow", "id")
+			}
+		}
+		c.call("GET", prefix+"/escrow/"+escrows[0], nil, false)
+		status, _ = c.call("POST", prefix+"/escrow/"+escrows[0]+"/approve", map[string]string{"pkey": alice}, false)
+		expect("POST", prefix+"/escrow/"+escrows[0]+"/approve", status, http.StatusOK)
+		status, _ = c.call("POST", prefix+"/escrow/"+escrows[1]+"/refund", map[string]string{"pkey": bob}, false)
+		expect("POST", prefix+"/escrow/"+escrows[1]+"/refund", status, http.StatusOK)
+
+		asset := "GOLD"
+		if prefix != "" {
+			asset = "SILVER"
+		}
+		status, _ = c.call("POST", prefix+"/asset", client.AssetRequest{Pkey: alice, ID: asset, Name: "Metal", Supply: 100, Mintable: true}, false)
+		expect("POST", prefix+"/asset", status, http.StatusOK)
+		status, _ = c.call("POST", prefix+"/asset/"+asset+"/mint", client.MintRequest{Pkey: alice, Amount: 10}, false)
+		expect("POST", prefix+"/asset/"+asset+"/mint", status, http.StatusOK)
+		c.call("GET", prefix+"/assets", nil, false)
+		c.call("GET", prefix+"/supply?asset="+asset, nil, false)
+
+		status, _ = c.call("POST", prefix+"/admin/address/"+bobAddr+"/freeze", nil, true)
+		expect("POST", prefix+"/admin/address/"+bobAddr+"/freeze", status, http.StatusOK)
+		status, _ = c.call("POST", prefix+"/transaction", client.TransactionRequest{Pkey: bob, Address: carolAddr, Amount: 1, Fee: 1}, false)
+		expect("POST", prefix+"/transaction", status, http.StatusForbidden)
+		status, _ = c.call("POST", prefix+"/admin/address/"+bobAddr+"/unfreeze", nil, true)
+		expect("POST", prefix+"/admin/address/"+bobAddr+"/unfreeze", status, http.StatusOK)
+		c.call("POST", prefix+"/admin/verify", nil, true)
+		c.call("GET", prefix+"/admin/config", nil, true)
+		c.call("GET", prefix+"/admin/audit", nil, true)
+		c.call("GET", prefix+"/admin/conflicts", nil, true)
+		c.call("GET", prefix+"/admin/config", nil, false)
+
+		c.call("GET", prefix+"/address/"+aliceAddr, nil, false)
+		c.call("GET", prefix+"/address/not-an-address", nil, false)
+		c.call("GET", prefix+"/addresses", nil, false)
+		c.call("GET", prefix+"/transaction/"+txn, nil, false)
+		c.call("GET", prefix+"/transaction/999999", nil, false)
+		c.call("GET", prefix+"/transactions/"+aliceAddr, nil, false)
+		c.call("GET", prefix+"/transactions", nil, false)
+		c.call("GET", prefix+"/block", nil, false)
+		c.call("GET", prefix+"/block/"+blockHash, nil, false)
+		c.call("GET", prefix+"/block/unknown", nil, false)
+		c.call("GET", prefix+"/block/height/1", nil, false)
+		c.call("GET", prefix+"/block/height/99", nil, false)
+		c.call("GET", prefix+"/block/"+blockHash+"/transactions", nil, false)
+		c.call("GET", prefix+"/chain/tip", nil, false)
+		c.call("GET", prefix+"/blocks", nil, false)
+		c.call("GET", prefix+"/blocks/orphaned", nil, false)
+		c.call("GET", prefix+"/reorgs", nil, false)
+		c.call("GET", prefix+"/supply", nil, false)
+		c.call("GET", prefix+"/changes", nil, false)
+		c.call("GET", prefix+"/changes?since=x", nil, false)
+		c.call("GET", prefix+"/healthz", nil, false)
+		c.call("GET", prefix+"/readyz", nil, false)
+		c.call("GET", prefix+"/info", nil, false)
+		c.call("POST", prefix+"/transaction", map[string]interface{}{"pkey": alice, "amount": "lots"}, false)
+	}
+
+	c.call("GET", "/p2p/entries", nil, false)
+	c.call("GET", "/replication/snapshot", nil, false)
+	c.call("GET", "/metrics", nil, false)
+	c.call("GET", "/openapi.json", nil, false)
+	c.call("GET", "/", nil, false)
+	c.call("GET", "/explorer/block/"+blockHash, nil, false)
+	c.call("GET", "/explorer/block/unknown", nil, false)
+	c.call("GET", "/explorer/address/"+aliceAddr, nil, false)
+	c.call("GET", "/explorer/transaction/"+txn, nil, false)
+	c.call("GET", "/explorer/search?q="+aliceAddr, nil, false)
+	c.call("GET", "/explorer/search?q=nothing", nil, false)
+	c.call("GET", "/explorer/static/explorer.css", nil, false)
+	c.call("GET", "/explorer/static/missing.css", nil, false)
+
+	var missed []string
+	for path, operations := range c.spec.Paths {
+		for method := range operations {
+			if operation := strings.ToUpper(method) + " " + path; !c.exercised[operation] {
+				missed = append(missed, operation)
+			}
+		}
+	}
+	sort.Strings(missed)
+	for _, operation := range missed {
+		t.Errorf("%s is documented but not exercised", operation)
+	}
+}
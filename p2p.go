@@ -0,0 +1,722 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// peerPollWait is how long a node waits on a peer's entry feed for new
+// entries before asking again.
+const peerPollWait = 30
+
+// errPeerConflict marks a peer entry that contradicts this node's ledger,
+// such as a transaction spending funds already spent here. It is recorded
+// and skipped rather than retried.
+var errPeerConflict = errors.New("conflicting peer entry")
+
+// PeerEntry is a ledger change as it is replicated between nodes: a
+// transaction, a block, a freeze, an asset definition, a multisig address,
+// an escrow or a scheduled transfer. Cursor is its position in the sending
+// node's change feed.
+type PeerEntry struct {
+	Cursor      int64            `json:"cursor"`
+	Type        string           `json:"type"`
+	Time        int              `json:"time"`
+	Transaction *PeerTransaction `json:"transaction,omitempty"`
+	Block       *PeerBlock       `json:"block,omitempty"`
+	Address     string           `json:"address,omitempty"`
+	Asset       *PeerAsset       `json:"asset,omitempty"`
+	Multisig    *PeerMultisig    `json:"multisig,omitempty"`
+	Escrow      *PeerEscrow      `json:"escrow,omitempty"`
+	Schedule    *PeerSchedule    `json:"schedule,omitempty"`
+}
+
+// PeerTransaction is a ledger row as it was recorded by the node that
+// accepted it. Hash identifies it on every node. It never carries a
+// private key.
+type PeerTransaction struct {
+	Hash      string `json:"hash"`
+	Sender    string `json:"sender"`
+	Recipient string `json:"recipient"`
+	Asset     string `json:"asset"`
+	Amount    int    `json:"amount"`
+	Fee       int    `json:"fee"`
+	Memo      string `json:"memo"`
+	Time      int    `json:"time"`
+}
+
+// PeerBlock is a block with the time the first node accepted it and the
+// hashes of the transactions whose fees it collects, so every node stores
+// the same block and pays its miner the same amount.
+type PeerBlock struct {
+	submittedBlock
+	Time int      `json:"time"`
+	Fees []string `json:"fees"`
+}
+
+type PeerAsset struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Issuer   string `json:"issuer"`
+	Mintable bool   `json:"mintable"`
+	Created  int    `json:"created"`
+}
+
+type PeerMultisig struct {
+	Address    string   `json:"address"`
+	Threshold  int      `json:"threshold"`
+	PublicKeys []string `json:"pubkeys"`
+	Nonce      int      `json:"nonce"`
+}
+
+// PeerEscrow is an escrow with every vote and the time it was cast.
+// Funding and Settlement are the hashes of the transactions that moved its
+// funds in and out.
+type PeerEscrow struct {
+	ID           int64  `json:"id"`
+	Buyer        string `json:"buyer"`
+	Seller       string `json:"seller"`
+	Arbiter      string `json:"arbiter"`
+	Amount       int    `json:"amount"`
+	Fee          int    `json:"fee"`
+	Memo         string `json:"memo"`
+	BuyerVote    string `json:"buyerVote"`
+	SellerVote   string `json:"sellerVote"`
+	ArbiterVote  string `json:"arbiterVote"`
+	BuyerVoted   int    `json:"buyerVoted"`
+	SellerVoted  int    `json:"sellerVoted"`
+	ArbiterVoted int    `json:"arbiterVoted"`
+	Status       string `json:"status"`
+	Created      int    `json:"created"`
+	Expires      int    `json:"expires"`
+	Funding      string `json:"funding"`
+	Settlement   string `json:"settlement,omitempty"`
+	SettledAt    int    `json:"settledAt,omitempty"`
+}
+
+// PeerSchedule is a scheduled transfer. Funding is the hash of the
+// transaction that locked a one-off transfer's funds.
+type PeerSchedule struct {
+	ID           int64  `json:"id"`
+	Kind         string `json:"kind"`
+	Sender       string `json:"sender"`
+	Recipient    string `json:"recipient"`
+	Amount       int    `json:"amount"`
+	Fee          int    `json:"fee"`
+	Memo         string `json:"memo"`
+	UnlockTime   int    `json:"unlockTime"`
+	UnlockHeight int    `json:"unlockHeight"`
+	EverySeconds int    `json:"everySeconds"`
+	EveryBlocks  int    `json:"everyBlocks"`
+	Status       string `json:"status"`
+	Payments     int    `json:"payments"`
+	Missed       int    `json:"missed"`
+	Runs         int    `json:"runs"`
+	Funding      string `json:"funding,omitempty"`
+	Created      int    `json:"created"`
+}
+
+var (
+	peerList []string
+	// peerClient reads the peers' entry feeds. It has no overall timeout
+	// since every request long-polls; requests end with their context.
+	peerClient = &http.Client{}
+)
+
+// configurePeers sets up the peers to pull entries from. Peers are trusted
+// if their certificate is signed by a system root or by tls.caCertFile, and
+// this node identifies itself with p2p.certFile.
+func configurePeers(cfg *Config) error {
+	peerList = nil
+	for _, peer := range cfg.P2P.Peers {
+		peerList = append(peerList, strings.TrimSuffix(peer, "/"))
+	}
+
+	if len(peerList) == 0 {
+		return nil
+	}
+
+	tlsConfig, err := clientTLSConfig(cfg.TLS, cfg.P2P.CertFile, cfg.P2P.KeyFile)
+	if err != nil {
+		return err
+	}
+	peerClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	return nil
+}
+
+// getPeerEntries returns the entries after the cursor since for a peer to
+// apply, long-polling like GET /changes. Changes that every node derives
+// for itself, such as block rewards and disconnected blocks, are left out
+// but still advance the cursor.
+func getPeerEntries(w http.ResponseWriter, r *http.Request) {
+	since, limit, wait, ok := parseFeedQuery(w, r)
+	if !ok {
+		return
+	}
+
+	changes, cursor, more, ok := pollChanges(w, r, since, limit, wait)
+	if !ok {
+		return
+	}
+
+	entries, err := peerEntries(sqliteDatabase, changes)
+	if err != nil {
+		log.Println("building peer entries failed:", err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve entries")
+		return
+	}
+
+	genesis, err := queryGenesisBlock(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "failed to retrieve entries")
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]interface{}{"ok": true, "entries": entries, "cursor": cursor, "more": more, "genesis": genesis})
+}
+
+// peerEntries turns changes into entries with the current state of what
+// they refer to.
+func peerEntries(db dbtx, changes []Change) ([]PeerEntry, error) {
+	entries := []PeerEntry{}
+
+	for _, c := range changes {
+		entry := PeerEntry{Cursor: c.Cursor, Type: c.Type, Time: c.Time}
+
+		switch c.Type {
+		case "transfer", "mint":
+			t, err := queryPeerTransaction(db, c.TransactionID)
+			if err != nil {
+				return nil, err
+			}
+			// Rewards and their reversals follow from the blocks.
+			if t == nil || t.Sender == "null" || t.Recipient == "null" {
+				continue
+			}
+			entry.Type = "transaction"
+			entry.Transaction = t
+
+		case "block":
+			blk, err := queryAnyBlock(db, c.BlockHash)
+			if err != nil {
+				return nil, err
+			}
+			// Every node creates its own genesis block.
+			if blk == nil || blk.Height == 0 {
+				continue
+			}
+			entry.Block = &PeerBlock{
+				submittedBlock: submittedBlock{Block: blk.BlockContent, PreviousBlock: blk.PrevBlock, Address: blk.Address, Nonce: blk.Nonce},
+				Time:           blk.Time,
+				Fees:           blk.Fees,
+			}
+
+		case "frozen", "unfrozen":
+			entry.Address = c.Address
+
+		case "asset":
+			asset, err := queryAsset(db, c.Asset)
+			if err != nil {
+				return nil, err
+			}
+			entry.Asset = &PeerAsset{ID: asset.ID, Name: asset.Name, Issuer: asset.Issuer, Mintable: asset.Mintable, Created: asset.Created}
+
+		case "multisig":
+			ms, err := queryMultisigAddress(db, c.Address)
+			if err != nil {
+				return nil, err
+			}
+			entry.Multisig = &PeerMultisig{Address: ms.Address, Threshold: ms.Threshold, PublicKeys: ms.PublicKeys, Nonce: ms.Nonce}
+
+		case "escrow":
+			e, err := queryEscrow(db, c.EscrowID)
+			if err != nil {
+				return nil, err
+			}
+			if entry.Escrow, err = peerEscrow(db, e); err != nil {
+				return nil, err
+			}
+
+		case "schedule":
+			st, err := queryScheduledTransfer(db, c.ScheduleID)
+			if err != nil {
+				return nil, err
+			}
+			if entry.Schedule, err = peerSchedule(db, st); err != nil {
+				return nil, err
+			}
+
+		default:
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func peerEscrow(db dbtx, e *Escrow) (*PeerEscrow, error) {
+	funding, err := queryTransactionHash(db, e.Funding)
+	if err != nil {
+		return nil, err
+	}
+	settlement, err := queryTransactionHash(db, e.SettledTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PeerEscrow{
+		ID: e.ID, Buyer: e.Buyer, Seller: e.Seller, Arbiter: e.Arbiter, Amount: e.Amount, Fee: e.Fee, Memo: e.Memo,
+		BuyerVote: e.BuyerVote, SellerVote: e.SellerVote, ArbiterVote: e.ArbiterVote,
+		BuyerVoted: e.BuyerVoted, SellerVoted: e.SellerVoted, ArbiterVoted: e.ArbiterVoted,
+		Status: e.Status, Created: e.Created, Expires: e.Expires, Funding: funding, Settlement: settlement, SettledAt: e.SettledAt,
+	}, nil
+}
+
+func peerSchedule(db dbtx, st *ScheduledTransfer) (*PeerSchedule, error) {
+	funding, err := queryTransactionHash(db, st.Funding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PeerSchedule{
+		ID: st.ID, Kind: st.Kind, Sender: st.Sender, Recipient: st.Recipient, Amount: st.Amount, Fee: st.Fee, Memo: st.Memo,
+		UnlockTime: st.UnlockTime, UnlockHeight: st.UnlockHeight, EverySeconds: st.EverySeconds, EveryBlocks: st.EveryBlocks,
+		Status: st.Status, Payments: st.Payments, Missed: st.Missed, Runs: st.Runs, Funding: funding, Created: st.Created,
+	}, nil
+}
+
+// runPeerSync follows every peer's entry feed until ctx is cancelled.
+func runPeerSync(ctx context.Context, retryInterval time.Duration) {
+	for _, peer := range peerList {
+		go followPeer(ctx, peer, retryInterval)
+	}
+}
+
+// followPeer applies a peer's entries from where it last left off, waiting
+// on the feed for new ones. After an error it tries again every
+// retryInterval from the first entry it couldn't apply.
+func followPeer(ctx context.Context, peer string, retryInterval time.Duration) {
+	cursor, err := queryPeerCursor(sqliteDatabase, peer)
+	if err != nil {
+		slog.Error("reading peer cursor failed", "peer", peer, "error", err)
+		return
+	}
+
+	for {
+		next, _, err := syncFromPeer(ctx, peerClient, peer, cursor, peerPollWait)
+		if next != cursor {
+			cursor = next
+			// Taken like any other write so it can't collide with a
+			// ledger transaction.
+			ledgerMu.Lock()
+			setPeerCursor(sqliteDatabase, peer, cursor)
+			ledgerMu.Unlock()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			continue
+		}
+
+		slog.Warn("peer sync failed", "peer", peer, "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// syncFromPeer fetches one page of a peer's entries after since with
+// client, waiting up to wait seconds for one, and applies them in order. It
+// returns the cursor to continue from and whether the peer has more
+// entries after it.
+func syncFromPeer(ctx context.Context, client *http.Client, peer string, since int64, wait int) (int64, bool, error) {
+	query := url.Values{"since": {strconv.FormatInt(since, 10)}, "wait": {strconv.Itoa(wait)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, peer+"/p2p/entries?"+query.Encode(), nil)
+	if err != nil {
+		return since, false, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return since, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return since, false, fmt.Errorf("GET /p2p/entries returned %s", resp.Status)
+	}
+
+	var page struct {
+		Entries []PeerEntry `json:"entries"`
+		Cursor  int64       `json:"cursor"`
+		More    bool        `json:"more"`
+		Genesis string      `json:"genesis"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return since, false, fmt.Errorf("decoding entries: %w", err)
+	}
+
+	genesis, err := queryGenesisBlock(sqliteDatabase)
+	if err != nil {
+		return since, false, err
+	}
+	if page.Genesis != genesis {
+		return since, false, fmt.Errorf("peer has a different genesis block %s", page.Genesis)
+	}
+
+	for _, entry := range page.Entries {
+		err := applyPeerEntry(entry)
+		if errors.Is(err, errPeerConflict) {
+			recordPeerConflict(peer, entry, err)
+		} else if err != nil {
+			return since, false, fmt.Errorf("%s entry %d: %w", entry.Type, entry.Cursor, err)
+		}
+		since = entry.Cursor
+	}
+
+	return page.Cursor, page.More, nil
+}
+
+// recordPeerConflict stores an entry this node refused so an operator can
+// review it with GET /admin/conflicts. The entry is not tried again.
+func recordPeerConflict(peer string, entry PeerEntry, reason error) {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		log.Fatalln(err.Error())
+	}
+
+	ledgerMu.Lock()
+	insertPeerConflict(sqliteDatabase, PeerConflict{
+		Peer:   peer,
+		Cursor: entry.Cursor,
+		Type:   entry.Type,
+		Entry:  body,
+		Reason: reason.Error(),
+		Time:   int(time.Now().Unix()),
+	})
+	ledgerMu.Unlock()
+
+	peerConflicts.inc(entry.Type)
+	slog.Warn("peer entry conflicts with the ledger", "peer", peer, "type", entry.Type, "cursor", entry.Cursor, "reason", reason)
+}
+
+// applyPeerEntry applies an entry from a peer. Entries this node already
+// has are skipped, so an entry that reaches it through several peers is
+// applied once. An entry that is malformed or contradicts this node's
+// ledger returns an error wrapping errPeerConflict.
+func applyPeerEntry(e PeerEntry) error {
+	if limit := time.Now().Add(serverConfig.P2P.MaxClockSkew.Duration); e.Time > int(limit.Unix()) {
+		return fmt.Errorf("time %d is ahead of this node's clock by more than %s", e.Time, serverConfig.P2P.MaxClockSkew)
+	}
+
+	switch e.Type {
+	case "transaction":
+		if e.Transaction == nil {
+			return fmt.Errorf("%w: missing transaction", errPeerConflict)
+		}
+		return applyPeerTransaction(e.Transaction)
+	case "block":
+		if e.Block == nil {
+			return fmt.Errorf("%w: missing block", errPeerConflict)
+		}
+		return applyPeerBlock(e.Block)
+	case "frozen", "unfrozen":
+		if !validateAddress(e.Address) {
+			return fmt.Errorf("%w: invalid address", errPeerConflict)
+		}
+		return applyPeerFreeze(e.Address, e.Type == "frozen", e.Time)
+	case "asset":
+		if e.Asset == nil {
+			return fmt.Errorf("%w: missing asset", errPeerConflict)
+		}
+		return applyPeerAsset(e.Asset)
+	case "multisig":
+		if e.Multisig == nil {
+			return fmt.Errorf("%w: missing multisig address", errPeerConflict)
+		}
+		return applyPeerMultisig(e.Multisig, e.Time)
+	case "escrow":
+		if e.Escrow == nil {
+			return fmt.Errorf("%w: missing escrow", errPeerConflict)
+		}
+		return applyPeerEscrow(e.Escrow, e.Time)
+	case "schedule":
+		if e.Schedule == nil {
+			return fmt.Errorf("%w: missing scheduled transfer", errPeerConflict)
+		}
+		return applyPeerSchedule(e.Schedule, e.Time)
+	}
+
+	// Entry types added by newer versions are skipped.
+	return nil
+}
+
+// applyPeerTransaction applies a transaction accepted by a peer if its
+// sender can cover it here. When two nodes accept transactions that
+// together spend more than the sender has, the one that arrives second is
+// a conflict on each node, so no balance goes negative. The sender's
+// freeze is not checked: it may have been frozen here after the peer
+// accepted the transaction.
+func applyPeerTransaction(t *PeerTransaction) error {
+	if t.Hash == "" || t.Sender == "null" || t.Recipient == "null" || t.Amount < 0 || t.Fee < 0 {
+		return fmt.Errorf("%w: invalid transaction", errPeerConflict)
+	}
+
+	return withLedgerTx(func(tx *sql.Tx) error {
+		if transactionHashExists(tx, t.Hash) {
+			return nil
+		}
+
+		// Only real addresses have balances; the other side of a mint,
+		// lock or escrow is a pseudo account.
+		if validateAddress(t.Sender) {
+			covered := queryAddress(tx, t.Sender) >= t.Amount+t.Fee
+			if t.Asset != nativeAsset {
+				covered = queryBalance(tx, t.Sender, t.Asset) >= t.Amount && queryAddress(tx, t.Sender) >= t.Fee
+			}
+			if !covered {
+				return fmt.Errorf("%w: transaction %s would overdraw %s", errPeerConflict, t.Hash, t.Sender)
+			}
+
+			creditBalance(tx, t.Sender, t.Asset, -t.Amount)
+			if t.Fee != 0 {
+				creditAddress(tx, t.Sender, -t.Fee)
+			}
+		}
+		if validateAddress(t.Recipient) {
+			creditBalance(tx, t.Recipient, t.Asset, t.Amount)
+		}
+		if t.Sender == issuanceAccount {
+			addAssetSupply(tx, t.Asset, t.Amount)
+		}
+
+		insertHashedTransaction(tx, t.Hash, t.Sender, t.Asset, t.Amount, t.Fee, t.Recipient, t.Memo, t.Time)
+		return nil
+	})
+}
+
+func applyPeerBlock(b *PeerBlock) error {
+	fees := b.Fees
+	if fees == nil {
+		fees = []string{}
+	}
+
+	// A block that fails here, such as one collecting the fee of a
+	// conflicting transaction, would fail every time.
+	_, err := acceptBlock(b.submittedBlock, b.Time, fees)
+	if errors.Is(err, errKnownBlock) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", errPeerConflict, err)
+	}
+
+	blocksTotal.inc("accepted", "")
+	return nil
+}
+
+// applyPeerFreeze freezes or unfreezes an address unless it has a later
+// freeze change already, so nodes agree on the last one. Of two at the
+// same time, the freeze wins.
+func applyPeerFreeze(address string, frozen bool, timestamp int) error {
+	return withLedgerTx(func(tx *sql.Tx) error {
+		last, at, err := queryLastFreeze(tx, address)
+		if err != nil {
+			return err
+		}
+		if at > timestamp || (at == timestamp && (last == "frozen" || !frozen)) {
+			return nil
+		}
+
+		if !setAddressFrozen(tx, address, frozen, timestamp) {
+			insertAddress(tx, address, 0)
+			setAddressFrozen(tx, address, frozen, timestamp)
+		}
+		return nil
+	})
+}
+
+// applyPeerAsset defines an asset issued on another node. Its supply
+// arrives with the issuance transactions.
+func applyPeerAsset(a *PeerAsset) error {
+	if a.ID == "" || a.ID == nativeAsset || !validateAddress(a.Issuer) {
+		return fmt.Errorf("%w: invalid asset", errPeerConflict)
+	}
+
+	return withLedgerTx(func(tx *sql.Tx) error {
+		existing, err := queryAsset(tx, a.ID)
+		if err != nil || existing != nil {
+			return err
+		}
+
+		asset := &Asset{ID: a.ID, Name: a.Name, Issuer: a.Issuer, Mintable: a.Mintable, Created: a.Created}
+		if err := insertAsset(tx, asset); err != nil {
+			return err
+		}
+		insertAssetChange(tx, asset.Created, asset.ID)
+		return nil
+	})
+}
+
+// applyPeerMultisig registers a multisig address created on another node
+// and raises its nonce to the highest one spent anywhere.
+func applyPeerMultisig(m *PeerMultisig, timestamp int) error {
+	if m.Threshold < 1 || m.Threshold > len(m.PublicKeys) || m.Address != multisigAddress(m.Threshold, m.PublicKeys) {
+		return fmt.Errorf("%w: invalid multisig address", errPeerConflict)
+	}
+
+	return withLedgerTx(func(tx *sql.Tx) error {
+		ms, err := queryMultisigAddress(tx, m.Address)
+		if err != nil {
+			return err
+		}
+
+		if ms == nil {
+			insertMultisigAddress(tx, &MultisigAddress{Address: m.Address, Threshold: m.Threshold, PublicKeys: m.PublicKeys, Nonce: m.Nonce}, timestamp)
+		} else if m.Nonce > ms.Nonce {
+			setMultisigNonce(tx, m.Address, m.Nonce, timestamp)
+		}
+		return nil
+	})
+}
+
+// applyPeerEscrow merges an escrow from a peer. Each party's later vote
+// wins. An escrow the peer settled is settled here with the same payment,
+// and one both nodes settled differently is a conflict. Once two merged
+// votes agree the escrow settles here under the same hash it gets on every
+// node, so its funds are paid out once.
+func applyPeerEscrow(p *PeerEscrow, timestamp int) error {
+	if !validateAddress(p.Buyer) || !validateAddress(p.Seller) || !validateAddress(p.Arbiter) || p.Amount <= 0 {
+		return fmt.Errorf("%w: invalid escrow", errPeerConflict)
+	}
+
+	return withLedgerTx(func(tx *sql.Tx) error {
+		e, err := queryEscrow(tx, p.ID)
+		if err != nil {
+			return err
+		}
+
+		var before Escrow
+		if e != nil {
+			before = *e
+		} else {
+			funding, err := queryHashedTransaction(tx, p.Funding)
+			if err != nil {
+				return err
+			}
+			if funding == nil {
+				return fmt.Errorf("%w: escrow %d is funded by unknown transaction %s", errPeerConflict, p.ID, p.Funding)
+			}
+			e = &Escrow{ID: p.ID, Buyer: p.Buyer, Seller: p.Seller, Arbiter: p.Arbiter, Amount: p.Amount, Fee: p.Fee, Memo: p.Memo,
+				Status: "open", Created: p.Created, Expires: p.Expires, Funding: int64(funding.ID)}
+		}
+
+		mergeVote(&e.BuyerVote, &e.BuyerVoted, p.BuyerVote, p.BuyerVoted)
+		mergeVote(&e.SellerVote, &e.SellerVoted, p.SellerVote, p.SellerVoted)
+		mergeVote(&e.ArbiterVote, &e.ArbiterVoted, p.ArbiterVote, p.ArbiterVoted)
+
+		switch {
+		case p.Status != "open" && e.Status == "open":
+			outcome := escrowRelease
+			if p.Status == "refunded" {
+				outcome = escrowRefund
+			}
+			if err := settleEscrowTx(tx, e, outcome, p.Settlement, p.SettledAt); err != nil {
+				return err
+			}
+		case p.Status != "open" && e.Status != p.Status:
+			return fmt.Errorf("%w: escrow %d is %s here but %s on the peer", errPeerConflict, p.ID, e.Status, p.Status)
+		case e.Status == "open":
+			if outcome, at := escrowOutcome(e); outcome != "" {
+				if err := settleEscrowTx(tx, e, outcome, escrowSettlementHash(e.ID), at); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Writing an unchanged escrow would send it back to the peer.
+		switch {
+		case before.ID == 0:
+			insertEscrow(tx, e, timestamp)
+		case *e != before:
+			updateEscrow(tx, e, timestamp)
+		}
+		return nil
+	})
+}
+
+// mergeVote keeps the later of two votes by the same party. Of two cast at
+// the same time, the greater wins so every node picks the same one.
+func mergeVote(vote *string, at *int, peerVote string, peerAt int) {
+	if peerAt > *at || (peerAt == *at && peerVote > *vote) {
+		*vote, *at = peerVote, peerAt
+	}
+}
+
+// applyPeerSchedule merges a scheduled transfer from a peer. The copy that
+// has run more often wins, and a cancellation always wins. Runs move no
+// funds here: their transactions arrive as entries of their own, or this
+// node's scheduler makes them under the same hashes.
+func applyPeerSchedule(p *PeerSchedule, timestamp int) error {
+	if (p.Kind != "locked" && p.Kind != "recurring") || !validateAddress(p.Sender) || !validateAddress(p.Recipient) || p.Amount <= 0 {
+		return fmt.Errorf("%w: invalid scheduled transfer", errPeerConflict)
+	}
+
+	return withLedgerTx(func(tx *sql.Tx) error {
+		st, err := queryScheduledTransfer(tx, p.ID)
+		if err != nil {
+			return err
+		}
+
+		if st == nil {
+			st = &ScheduledTransfer{ID: p.ID, Kind: p.Kind, Sender: p.Sender, Recipient: p.Recipient, Amount: p.Amount, Fee: p.Fee,
+				Memo: p.Memo, UnlockTime: p.UnlockTime, UnlockHeight: p.UnlockHeight, EverySeconds: p.EverySeconds,
+				EveryBlocks: p.EveryBlocks, Status: p.Status, Payments: p.Payments, Missed: p.Missed, Runs: p.Runs, Created: p.Created}
+			if p.Kind == "locked" {
+				funding, err := queryHashedTransaction(tx, p.Funding)
+				if err != nil {
+					return err
+				}
+				if funding == nil {
+					return fmt.Errorf("%w: scheduled transfer %d is funded by unknown transaction %s", errPeerConflict, p.ID, p.Funding)
+				}
+				st.Funding = int64(funding.ID)
+			}
+			insertScheduledTransfer(tx, st, timestamp)
+			return nil
+		}
+
+		before := *st
+		if p.Runs > st.Runs || (p.Runs == st.Runs && p.Payments > st.Payments) {
+			st.UnlockTime, st.UnlockHeight = p.UnlockTime, p.UnlockHeight
+			st.Payments, st.Missed, st.Runs = p.Payments, p.Missed, p.Runs
+			if st.Status != "cancelled" {
+				st.Status = p.Status
+			}
+		}
+		if p.Status == "cancelled" {
+			st.Status = "cancelled"
+		}
+
+		// Writing an unchanged schedule would send it back to the peer.
+		if *st != before {
+			updateScheduledTransfer(tx, st, timestamp)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,626 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAdminToken = "test-admin-token"
+
+type testNode struct {
+	name   string
+	url    string
+	db     string
+	log    string
+	client *http.Client
+}
+
+// call sends a JSON request to the node, with the admin token, and decodes
+// the response into out if it isn't nil. It returns the status code, or 0
+// if the node couldn't be reached.
+func (n *testNode) call(t *testing.T, method, path string, body, out interface{}) int {
+	t.Helper()
+
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, n.url+path, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// mustCall is call for requests that must succeed.
+func (n *testNode) mustCall(t *testing.T, method, path string, body, out interface{}) {
+	t.Helper()
+
+	if status := n.call(t, method, path, body, out); status != http.StatusOK {
+		t.Fatalf("%s %s on %s: status %d", method, path, n.name, status)
+	}
+}
+
+// freePort returns a TCP port that was free a moment ago.
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// startTestNodes starts a server for each name with the peers in links,
+// all sharing one CA, and returns them once they are up.
+func startTestNodes(t *testing.T, names []string, links map[string][]string) map[string]*testNode {
+	t.Helper()
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "gc-server")
+	if out, err := exec.Command("go", "build", "-o", bin, ".").CombinedOutput(); err != nil {
+		t.Fatalf("building server: %v\n%s", err, out)
+	}
+
+	tlsCfg := TLSConfig{
+		AutoGenerate: true,
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		CACertFile:   filepath.Join(dir, "ca.crt"),
+		CAKeyFile:    filepath.Join(dir, "ca.key"),
+		Hosts:        []string{"127.0.0.1"},
+	}
+	if err := ensureCertificates(tlsCfg); err != nil {
+		t.Fatal(err)
+	}
+
+	caPEM, err := os.ReadFile(tlsCfg.CACertFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	httpClient := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}},
+	}
+
+	nodes := map[string]*testNode{}
+	for _, name := range names {
+		nodes[name] = &testNode{
+			name:   name,
+			url:    fmt.Sprintf("https://127.0.0.1:%d", freePort(t)),
+			db:     filepath.Join(dir, name+".db"),
+			log:    filepath.Join(dir, name+".log"),
+			client: httpClient,
+		}
+	}
+
+	for _, name := range names {
+		node := nodes[name]
+
+		certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+		if err := issueClientCertificate(tlsCfg, name, certFile, keyFile); err != nil {
+			t.Fatal(err)
+		}
+
+		var peers []string
+		for _, peer := range links[name] {
+			peers = append(peers, nodes[peer].url)
+		}
+
+		cfg := map[string]interface{}{
+			"database":          node.db,
+			"listen":            strings.TrimPrefix(node.url, "https://"),
+			"difficulty":        0,
+			"schedulerInterval": "100ms",
+			"reward":            map[string]interface{}{"blockReward": 100},
+			"fees":              map[string]interface{}{"minFee": 1, "perByte": 0},
+			"admin":             map[string]interface{}{"token": testAdminToken},
+			"tls": map[string]interface{}{
+				"enabled":      true,
+				"certFile":     tlsCfg.CertFile,
+				"keyFile":      tlsCfg.KeyFile,
+				"caCertFile":   tlsCfg.CACertFile,
+				"clientCAFile": tlsCfg.CACertFile,
+			},
+			"p2p": map[string]interface{}{
+				"peers":        peers,
+				"syncInterval": "200ms",
+				"certFile":     certFile,
+				"keyFile":      keyFile,
+			},
+		}
+		data, err := json.Marshal(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfgFile := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(cfgFile, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		logFile, err := os.Create(node.log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(bin, "-config", cfgFile, "-o")
+		cmd.Stdout, cmd.Stderr = logFile, logFile
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			cmd.Process.Signal(os.Interrupt)
+			cmd.Wait()
+			logFile.Close()
+			if t.Failed() {
+				out, _ := os.ReadFile(node.log)
+				t.Logf("%s log:\n%s", node.name, out)
+			}
+		})
+	}
+
+	for _, name := range names {
+		node := nodes[name]
+		eventually(t, node.name+" to start", func() bool {
+			return node.call(t, "GET", "/healthz", nil, nil) == http.StatusOK
+		})
+	}
+
+	return nodes
+}
+
+// eventually fails the test if cond doesn't hold within 20 seconds.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(20 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// mine submits a block extending prev on node and returns its hash.
+func mine(t *testing.T, node *testNode, prev, miner string) string {
+	t.Helper()
+
+	nonce := strconv.FormatInt(time.Now().UnixNano(), 10)
+	hash := genBlock(prev, miner, nonce)
+	node.mustCall(t, "POST", "/block", submittedBlock{Block: hash, PreviousBlock: prev, Address: miner, Nonce: nonce}, nil)
+	return hash
+}
+
+func balance(t *testing.T, node *testNode, address, asset string) int {
+	t.Helper()
+
+	var resp struct {
+		Addresses []struct {
+			Holdings []struct {
+				Asset   string `json:"asset"`
+				Balance int    `json:"balance"`
+			} `json:"holdings"`
+		} `json:"addresses"`
+	}
+	if node.call(t, "GET", "/address/"+address, nil, &resp) != http.StatusOK {
+		return -1
+	}
+	for _, h := range resp.Addresses[0].Holdings {
+		if h.Asset == asset {
+			return h.Balance
+		}
+	}
+	return 0
+}
+
+// chainTip returns the height and hash of the node's last block.
+func chainTip(t *testing.T, node *testNode) (int, string) {
+	t.Helper()
+
+	var tip struct {
+		Height int    `json:"height"`
+		Hash   string `json:"hash"`
+	}
+	node.mustCall(t, "GET", "/chain/tip", nil, &tip)
+	return tip.Height, tip.Hash
+}
+
+// supply returns the node's GET /supply response for the native coin.
+func supply(t *testing.T, node *testNode) map[string]int {
+	t.Helper()
+
+	var resp map[string]interface{}
+	node.mustCall(t, "GET", "/supply", nil, &resp)
+
+	amounts := map[string]int{}
+	for key, value := range resp {
+		if n, ok := value.(float64); ok {
+			amounts[key] = int(n)
+		}
+	}
+	return amounts
+}
+
+type testEscrow struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+type testSchedule struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+// escrow returns the escrow id as the node sees it, or nil if the node
+// doesn't have it.
+func escrow(t *testing.T, node *testNode, id int64) *testEscrow {
+	t.Helper()
+
+	var resp struct {
+		Escrows []testEscrow `json:"escrows"`
+	}
+	if node.call(t, "GET", "/escrow/"+strconv.FormatInt(id, 10), nil, &resp) != http.StatusOK {
+		return nil
+	}
+	return &resp.Escrows[0]
+}
+
+func schedule(t *testing.T, node *testNode, id int64) *testSchedule {
+	t.Helper()
+
+	var resp struct {
+		Schedules []testSchedule `json:"schedules"`
+	}
+	if node.call(t, "GET", "/schedule/"+strconv.FormatInt(id, 10), nil, &resp) != http.StatusOK {
+		return nil
+	}
+	return &resp.Schedules[0]
+}
+
+// ledgerState reads everything peers must agree on from a node's database.
+// Rewards are left out since each node records its own, including rewards
+// of blocks it connected and later disconnected, and so are addresses those
+// left empty. Escrows and schedules are compared without the IDs of their
+// transactions, which are local to each node.
+func ledgerState(t *testing.T, node *testNode) string {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", "file:"+node.db+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	queries := []string{
+		`SELECT address, balance, frozen FROM addresses WHERE balance != 0 OR frozen != 0 ORDER BY address`,
+		`SELECT address, asset, balance FROM asset_balances WHERE balance != 0 ORDER BY address, asset`,
+		`SELECT id, name, issuer, supply, mintable FROM assets ORDER BY id`,
+		`SELECT address, threshold, nonce FROM multisig_addresses ORDER BY address`,
+		`SELECT height, block, prevBlock, address, time, fees FROM blocks ORDER BY height`,
+		`SELECT hash, sender, recipient, asset, amount, fee, memo, time, COALESCE(block, '') FROM transactions
+			WHERE sender != 'null' AND recipient != 'null' ORDER BY hash`,
+		`SELECT id, buyer, seller, arbiter, amount, fee, memo, buyerVote, sellerVote, arbiterVote,
+			buyerVoted, sellerVoted, arbiterVoted, status, created, expires, COALESCE(settledAt, 0) FROM escrows ORDER BY id`,
+		`SELECT id, kind, sender, recipient, amount, fee, memo, unlockTime, unlockHeight, everySeconds, everyBlocks,
+			status, payments, missed, runs, created FROM scheduled_transfers ORDER BY id`,
+	}
+
+	var state strings.Builder
+	for _, query := range queries {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cols, _ := rows.Columns()
+		for rows.Next() {
+			values := make([]interface{}, len(cols))
+			ptrs := make([]interface{}, len(cols))
+			for i := range values {
+				ptrs[i] = &values[i]
+			}
+			if err := rows.Scan(ptrs...); err != nil {
+				t.Fatal(err)
+			}
+			fmt.Fprintln(&state, values...)
+		}
+		rows.Close()
+		state.WriteString("--\n")
+	}
+
+	return state.String()
+}
+
+// TestPeersConverge runs three nodes in a line, A - B - C, spreads every
+// kind of ledger change across them, including a fork, and checks that all
+// three end up with the same ledger.
+func TestPeersConverge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts three servers")
+	}
+
+	nodes := startTestNodes(t, []string{"a", "b", "c"}, map[string][]string{
+		"a": {"b"},
+		"b": {"a", "c"},
+		"c": {"b"},
+	})
+	a, b, c := nodes["a"], nodes["b"], nodes["c"]
+
+	alice, bob, carol, dave := "alice-key", "bob-key", "carol-key", "dave-key"
+	aliceAddr, bobAddr, carolAddr, daveAddr := generateAddress(alice), generateAddress(bob), generateAddress(carol), generateAddress(dave)
+	minerAddr := generateAddress("miner-key")
+
+	// Blocks and an admin adjustment from different nodes.
+	mine(t, a, "0", minerAddr)
+	b.mustCall(t, "POST", "/admin/adjustment", AdjustmentRequest{Address: aliceAddr, Amount: 1000, Reason: "test funds"}, nil)
+	eventually(t, "alice's funds to reach c", func() bool { return balance(t, c, aliceAddr, nativeAsset) == 1000 })
+
+	// A transfer on C, then a freeze on A and an unfreeze on C.
+	c.mustCall(t, "POST", "/transaction", TransactionRequest{Pkey: alice, Address: bobAddr, Amount: 100, Fee: 5}, nil)
+	eventually(t, "bob's funds to reach a", func() bool { return balance(t, a, bobAddr, nativeAsset) == 100 })
+
+	a.mustCall(t, "POST", "/admin/address/"+bobAddr+"/freeze", nil, nil)
+	eventually(t, "the freeze to reach c", func() bool {
+		// Overdrawn so it can't go through, but frozen is checked first.
+		return c.call(t, "POST", "/transaction", TransactionRequest{Pkey: bob, Address: carolAddr, Amount: 1000, Fee: 1}, nil) == http.StatusForbidden
+	})
+	time.Sleep(time.Second) // the unfreeze must be later than the freeze
+	c.mustCall(t, "POST", "/admin/address/"+bobAddr+"/unfreeze", nil, nil)
+	eventually(t, "the unfreeze to reach a", func() bool {
+		return a.call(t, "POST", "/transaction", TransactionRequest{Pkey: bob, Address: carolAddr, Amount: 10, Fee: 1}, nil) == http.StatusOK
+	})
+
+	// An asset issued on A, transferred on B and minted on C.
+	a.mustCall(t, "POST", "/asset", AssetRequest{Pkey: alice, ID: "GOLD", Name: "Gold", Supply: 500, Mintable: true}, nil)
+	eventually(t, "the asset to reach b", func() bool { return balance(t, b, aliceAddr, "GOLD") == 500 })
+	b.mustCall(t, "POST", "/transaction", TransactionRequest{Pkey: alice, Address: bobAddr, Amount: 50, Fee: 1, Asset: "GOLD"}, nil)
+	eventually(t, "the asset to reach c", func() bool { return balance(t, c, aliceAddr, "GOLD") == 450 })
+	c.mustCall(t, "POST", "/asset/GOLD/mint", MintRequest{Pkey: alice, Amount: 25}, nil)
+
+	// A multisig address registered on C, funded on B and spent on A.
+	seed1, seed2 := sha256.Sum256([]byte("cosigner one")), sha256.Sum256([]byte("cosigner two"))
+	key1, key2 := ed25519.NewKeyFromSeed(seed1[:]), ed25519.NewKeyFromSeed(seed2[:])
+	var ms struct {
+		Multisig struct {
+			Address string `json:"address"`
+			Nonce   int    `json:"nonce"`
+		} `json:"multisig"`
+	}
+	c.mustCall(t, "POST", "/multisig", MultisigRequest{Threshold: 2, PublicKeys: []string{
+		hex.EncodeToString(key1.Public().(ed25519.PublicKey)),
+		hex.EncodeToString(key2.Public().(ed25519.PublicKey)),
+	}}, &ms)
+	msAddr := ms.Multisig.Address
+	eventually(t, "the multisig address to reach b", func() bool {
+		return b.call(t, "GET", "/multisig/"+msAddr, nil, nil) == http.StatusOK
+	})
+	b.mustCall(t, "POST", "/transaction", TransactionRequest{Pkey: alice, Address: msAddr, Amount: 200, Fee: 1}, nil)
+	eventually(t, "the multisig funds to reach a", func() bool { return balance(t, a, msAddr, nativeAsset) == 200 })
+	spend := &MultisigTransaction{From: msAddr, Address: daveAddr, Amount: 60, Fee: 2, Nonce: 1}
+	for _, key := range []ed25519.PrivateKey{key1, key2} {
+		spend.Signatures = append(spend.Signatures, MultisigSignature{
+			PublicKey: hex.EncodeToString(key.Public().(ed25519.PublicKey)),
+			Signature: hex.EncodeToString(ed25519.Sign(key, spend.signingMessage())),
+		})
+	}
+	a.mustCall(t, "POST", "/multisig/transaction", spend, nil)
+	eventually(t, "the multisig nonce to reach c", func() bool {
+		return c.call(t, "GET", "/multisig/"+msAddr, nil, &ms) == http.StatusOK && ms.Multisig.Nonce == 1
+	})
+
+	// An escrow opened on B, approved by the buyer on A and by the seller
+	// on C, so it settles once the votes meet. Another stays open.
+	var opened struct {
+		Escrows []testEscrow `json:"escrows"`
+	}
+	b.mustCall(t, "POST", "/escrow", EscrowRequest{Pkey: alice, Seller: carolAddr, Arbiter: daveAddr, Amount: 30, Fee: 1}, &opened)
+	settled := opened.Escrows[0].ID
+	eventually(t, "the escrow to reach a and c", func() bool { return escrow(t, a, settled) != nil && escrow(t, c, settled) != nil })
+	a.mustCall(t, "POST", "/escrow/"+strconv.FormatInt(settled, 10)+"/approve", map[string]string{"pkey": alice}, nil)
+	c.mustCall(t, "POST", "/escrow/"+strconv.FormatInt(settled, 10)+"/approve", map[string]string{"pkey": carol}, nil)
+	eventually(t, "the escrow to be released on every node", func() bool {
+		for _, node := range nodes {
+			if e := escrow(t, node, settled); e == nil || e.Status != "released" {
+				return false
+			}
+		}
+		return true
+	})
+	c.mustCall(t, "POST", "/escrow", EscrowRequest{Pkey: alice, Seller: bobAddr, Arbiter: daveAddr, Amount: 15, Fee: 1}, &opened)
+	open := opened.Escrows[0].ID
+
+	// A recurring transfer created on A and cancelled on C before its
+	// first payment.
+	var scheduled struct {
+		Schedules []testSchedule `json:"schedules"`
+	}
+	a.mustCall(t, "POST", "/schedule", ScheduleRequest{Pkey: alice, Address: bobAddr, Amount: 7, Fee: 1, EverySeconds: 3600}, &scheduled)
+	recurring := scheduled.Schedules[0].ID
+	eventually(t, "the recurring transfer to reach c", func() bool { return schedule(t, c, recurring) != nil })
+	c.mustCall(t, "POST", "/schedule/"+strconv.FormatInt(recurring, 10)+"/cancel", map[string]string{"pkey": alice}, nil)
+	eventually(t, "the cancellation to reach a", func() bool {
+		st := schedule(t, a, recurring)
+		return st != nil && st.Status == "cancelled"
+	})
+
+	// A transfer locked on A until a height reached by blocks mined on C,
+	// which also collect the fees paid on every node.
+	eventually(t, "all fees to reach c", func() bool {
+		return supply(t, c)["pendingFees"] == 5+1+1+1+2+1+1
+	})
+	height, hash := chainTip(t, a)
+	a.mustCall(t, "POST", "/schedule", ScheduleRequest{Pkey: alice, Address: carolAddr, Amount: 20, Fee: 1, UnlockHeight: height + 2}, nil)
+	eventually(t, "the schedule's fee to reach c", func() bool {
+		return supply(t, c)["pendingFees"] == 13
+	})
+	block := mine(t, c, hash, minerAddr)
+	block = mine(t, c, block, minerAddr)
+	eventually(t, "the locked transfer to be released", func() bool { return balance(t, b, carolAddr, nativeAsset) == 10+30+20 })
+
+	// A fork: A mines a block collecting a fee, and once it reaches C, C
+	// mines a longer branch beside it, returning the fee to pending.
+	eventually(t, "the tip to reach a", func() bool {
+		_, tip := chainTip(t, a)
+		return tip == block
+	})
+	a.mustCall(t, "POST", "/transaction", TransactionRequest{Pkey: alice, Address: daveAddr, Amount: 5, Fee: 3}, nil)
+	orphan := mine(t, a, block, generateAddress("other-miner-key"))
+	eventually(t, "a's block to reach c", func() bool {
+		_, tip := chainTip(t, c)
+		return tip == orphan
+	})
+	fork := mine(t, c, block, minerAddr)
+	fork = mine(t, c, fork, minerAddr)
+	eventually(t, "every node to adopt the longer branch", func() bool {
+		for _, node := range nodes {
+			if _, tip := chainTip(t, node); tip != fork {
+				return false
+			}
+		}
+		return true
+	})
+
+	// Every node has the same ledger and supply, and they are consistent.
+	var states []string
+	eventually(t, "the ledgers to converge", func() bool {
+		states = states[:0]
+		for _, name := range []string{"a", "b", "c"} {
+			states = append(states, ledgerState(t, nodes[name]))
+		}
+		return states[0] == states[1] && states[1] == states[2]
+	})
+	want := supply(t, a)
+	for _, node := range []*testNode{b, c} {
+		if got := supply(t, node); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: supply = %v, want %v as on a", node.name, got, want)
+		}
+	}
+	for _, node := range nodes {
+		var resp struct {
+			Report VerificationReport `json:"report"`
+		}
+		node.mustCall(t, "POST", "/admin/verify", nil, &resp)
+		if !resp.Report.OK {
+			t.Errorf("%s: verification failed: %v", node.name, resp.Report.Problems)
+		}
+		if e := escrow(t, node, open); e == nil || e.Status != "open" {
+			t.Errorf("%s: escrow %d = %+v, want it open", node.name, open, e)
+		}
+	}
+
+	balances := map[string]int{
+		aliceAddr: 1000 - 105 - 1 - 201 - 31 - 16 - 21 - 8,
+		bobAddr:   100 - 11,
+		carolAddr: 60,
+		daveAddr:  60 + 5,
+		minerAddr: 100 + 100*4 + 13,
+	}
+	for address, amount := range balances {
+		if got := balance(t, a, address, nativeAsset); got != amount {
+			t.Errorf("balance of %s = %d, want %d", address, got, amount)
+		}
+	}
+	if got := balance(t, c, bobAddr, "GOLD"); got != 50 {
+		t.Errorf("bob's GOLD = %d, want 50", got)
+	}
+	if want["escrowed"] != 15 || want["locked"] != 0 || want["pendingFees"] != 3 {
+		t.Errorf("supply = %v, want 15 escrowed, nothing locked and the 3 collected by the orphaned block pending", want)
+	}
+}
+
+// TestPeerOverdraftIsAConflict spends the same funds on two nodes. B
+// follows A but not the other way round, so A never sees B's spend, and B
+// must refuse A's instead of taking the sender's balance below zero.
+func TestPeerOverdraftIsAConflict(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts two servers")
+	}
+
+	nodes := startTestNodes(t, []string{"a", "b"}, map[string][]string{"b": {"a"}})
+	a, b := nodes["a"], nodes["b"]
+
+	alice := "alice-key"
+	aliceAddr, bobAddr, carolAddr := generateAddress(alice), generateAddress("bob-key"), generateAddress("carol-key")
+
+	a.mustCall(t, "POST", "/admin/adjustment", AdjustmentRequest{Address: aliceAddr, Amount: 100, Reason: "test funds"}, nil)
+	eventually(t, "alice's funds to reach b", func() bool { return balance(t, b, aliceAddr, nativeAsset) == 100 })
+
+	b.mustCall(t, "POST", "/transaction", TransactionRequest{Pkey: alice, Address: bobAddr, Amount: 99, Fee: 1}, nil)
+	a.mustCall(t, "POST", "/transaction", TransactionRequest{Pkey: alice, Address: carolAddr, Amount: 99, Fee: 1}, nil)
+
+	var resp struct {
+		Conflicts []PeerConflict `json:"conflicts"`
+	}
+	eventually(t, "b to record the conflict", func() bool {
+		return b.call(t, "GET", "/admin/conflicts", nil, &resp) == http.StatusOK && len(resp.Conflicts) > 0
+	})
+	if got := resp.Conflicts[0]; got.Type != "transaction" || !strings.Contains(got.Reason, "overdraw") {
+		t.Errorf("conflict = %+v, want a transaction that would overdraw", got)
+	}
+
+	// B keeps its own spend and stays consistent.
+	want := map[string]int{aliceAddr: 0, bobAddr: 99}
+	for address, amount := range want {
+		if got := balance(t, b, address, nativeAsset); got != amount {
+			t.Errorf("balance of %s on b = %d, want %d", address, got, amount)
+		}
+	}
+	var report struct {
+		Report VerificationReport `json:"report"`
+	}
+	b.mustCall(t, "POST", "/admin/verify", nil, &report)
+	if !report.Report.OK {
+		t.Errorf("verification failed: %v", report.Report.Problems)
+	}
+}
+
+func TestPeerEntriesRequireClientCertificate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a server")
+	}
+
+	nodes := startTestNodes(t, []string{"a"}, nil)
+
+	if status := nodes["a"].call(t, "GET", "/p2p/entries", nil, nil); status != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403 without a client certificate", status)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var limiter *rateLimiter
+
+type clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type offenses struct {
+	times       []time.Time
+	bannedUntil time.Time
+}
+
+// rateLimiter applies token-bucket limits per client and route, and bans
+// clients that keep submitting invalid blocks.
+type rateLimiter struct {
+	mu        sync.Mutex
+	clock     clock
+	cfg       RateLimitConfig
+	buckets   map[string]*bucket
+	offenders map[string]*offenses
+	lastPrune time.Time
+}
+
+func newRateLimiter(cfg RateLimitConfig, c clock) *rateLimiter {
+	return &rateLimiter{
+		clock:     c,
+		cfg:       cfg,
+		buckets:   map[string]*bucket{},
+		offenders: map[string]*offenses{},
+		lastPrune: c.Now(),
+	}
+}
+
+func (l *rateLimiter) rule(route string) RateLimitRule {
+	if rule, ok := l.cfg.Routes[route]; ok {
+		return rule
+	}
+	return RateLimitRule{RequestsPerMinute: l.cfg.RequestsPerMinute, Burst: l.cfg.Burst}
+}
+
+// allow takes a token from the client's bucket for the route. When the
+// bucket is empty it returns false and how long until a token is available.
+func (l *rateLimiter) allow(client, route string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	l.prune(now)
+
+	rule := l.rule(route)
+	if rule.RequestsPerMinute <= 0 {
+		return true, 0
+	}
+	rate := float64(rule.RequestsPerMinute) / 60
+
+	key := client + " " + route
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(rule.Burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// recordInvalidBlock counts an invalid block submission and bans the client
+// once it reaches the threshold within the ban window.
+func (l *rateLimiter) recordInvalidBlock(client string) {
+	if l.cfg.BanThreshold <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	o, ok := l.offenders[client]
+	if !ok {
+		o = &offenses{}
+		l.offenders[client] = o
+	}
+
+	recent := o.times[:0]
+	for _, t := range o.times {
+		if now.Sub(t) < l.cfg.BanWindow.Duration {
+			recent = append(recent, t)
+		}
+	}
+	o.times = append(recent, now)
+
+	if len(o.times) >= l.cfg.BanThreshold {
+		o.bannedUntil = now.Add(l.cfg.BanDuration.Duration)
+		o.times = nil
+	}
+}
+
+func (l *rateLimiter) banned(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	o, ok := l.offenders[client]
+	if !ok {
+		return false, 0
+	}
+
+	remaining := o.bannedUntil.Sub(l.clock.Now())
+	if remaining <= 0 {
+		return false, 0
+	}
+
+	return true, remaining
+}
+
+// prune drops state for clients that have been idle long enough for their
+// buckets to refill and whose bans have expired.
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > 10*time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+	for client, o := range l.offenders {
+		if len(o.times) == 0 && now.After(o.bannedUntil) {
+			delete(l.offenders, client)
+		}
+	}
+}
+
+// clientIdentity keys rate limits by the verified client certificate when
+// one was presented, and by IP address otherwise.
+func clientIdentity(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return "cert:" + r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+	return "ip:" + clientIP(r)
+}
+
+// retryAfter sets the Retry-After header and returns the same number of
+// seconds for the error details.
+func retryAfter(w http.ResponseWriter, wait time.Duration) map[string]interface{} {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	return map[string]interface{}{"retryAfter": seconds}
+}
+
+// rateLimit looks up the route the mux would dispatch to so that limits can
+// be configured per route pattern, then passes allowed requests to next.
+func rateLimit(l *rateLimiter, mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := clientIdentity(r)
+		_, route := mux.Handler(r)
+		route = unversionedRoute(route)
+
+		// Bans only lock clients out of block submission so they can still
+		// check balances and the chain tip.
+		if banned, wait := l.banned(client); banned && route == "POST /block" {
+			writeErrorDetails(w, r, http.StatusForbidden, "banned", "temporarily banned", retryAfter(w, wait))
+			return
+		}
+
+		if ok, wait := l.allow(client, route); !ok {
+			writeErrorDetails(w, r, http.StatusTooManyRequests, "rate_limited", "rate limit exceeded", retryAfter(w, wait))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func recordInvalidBlock(r *http.Request) {
+	if limiter != nil {
+		limiter.recordInvalidBlock(clientIdentity(r))
+	}
+}
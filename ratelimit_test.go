@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestLimiter() (*rateLimiter, *fakeClock) {
+	c := &fakeClock{now: time.Unix(1700000000, 0)}
+	cfg := RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 60,
+		Burst:             3,
+		Routes: map[string]RateLimitRule{
+			"POST /block": {RequestsPerMinute: 6, Burst: 1},
+			"GET /health": {RequestsPerMinute: 0, Burst: 1},
+		},
+		BanThreshold: 3,
+		BanWindow:    Duration{10 * time.Minute},
+		BanDuration:  Duration{time.Hour},
+	}
+	return newRateLimiter(cfg, c), c
+}
+
+func TestRateLimiterBurstAndRefill(t *testing.T) {
+	l, c := newTestLimiter()
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("ip:1", "GET /blocks"); !ok {
+			t.Fatalf("request %d within the burst was limited", i+1)
+		}
+	}
+
+	ok, wait := l.allow("ip:1", "GET /blocks")
+	if ok {
+		t.Fatal("request beyond the burst was allowed")
+	}
+	if wait != time.Second {
+		t.Errorf("wait = %v, want 1s at 60 requests per minute", wait)
+	}
+
+	c.advance(500 * time.Millisecond)
+	if ok, wait := l.allow("ip:1", "GET /blocks"); ok || wait != 500*time.Millisecond {
+		t.Errorf("after half a token: allowed = %v, wait = %v, want false, 500ms", ok, wait)
+	}
+
+	c.advance(500 * time.Millisecond)
+	if ok, _ := l.allow("ip:1", "GET /blocks"); !ok {
+		t.Error("request was limited after a token refilled")
+	}
+
+	// A long idle period refills only up to the burst.
+	c.advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("ip:1", "GET /blocks"); !ok {
+			t.Fatalf("request %d after refilling was limited", i+1)
+		}
+	}
+	if ok, _ := l.allow("ip:1", "GET /blocks"); ok {
+		t.Error("bucket refilled beyond the burst")
+	}
+}
+
+func TestRateLimiterKeysByClientAndRoute(t *testing.T) {
+	l, _ := newTestLimiter()
+
+	if ok, _ := l.allow("ip:1", "POST /block"); !ok {
+		t.Fatal("first block submission was limited")
+	}
+	if ok, wait := l.allow("ip:1", "POST /block"); ok || wait != 10*time.Second {
+		t.Errorf("second block submission: allowed = %v, wait = %v, want false, 10s", ok, wait)
+	}
+	if ok, _ := l.allow("ip:2", "POST /block"); !ok {
+		t.Error("another client shared the first client's bucket")
+	}
+	if ok, _ := l.allow("ip:1", "GET /blocks"); !ok {
+		t.Error("another route shared the block route's bucket")
+	}
+
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.allow("ip:1", "GET /health"); !ok {
+			t.Fatal("route with no limit was limited")
+		}
+	}
+}
+
+func TestRateLimitMiddlewareReturns429(t *testing.T) {
+	l, c := newTestLimiter()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /block", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := rateLimit(l, mux, mux)
+
+	submit := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/block", strings.NewReader("{}"))
+		req.RemoteAddr = "192.0.2.1:4000"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := submit(); rec.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want 200", rec.Code)
+	}
+
+	rec := submit()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "10" {
+		t.Errorf("Retry-After = %q, want 10", got)
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["ok"] != false || body["error"] != "rate limit exceeded" {
+		t.Errorf("body = %v, want ok false and a rate limit error", body)
+	}
+
+	// Retry-After rounds a partial second up.
+	c.advance(9500 * time.Millisecond)
+	if got := submit().Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+
+	c.advance(500 * time.Millisecond)
+	if rec := submit(); rec.Code != http.StatusOK {
+		t.Errorf("after Retry-After: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestRateLimiterBanThreshold(t *testing.T) {
+	l, c := newTestLimiter()
+
+	l.recordInvalidBlock("ip:1")
+	l.recordInvalidBlock("ip:1")
+	if banned, _ := l.banned("ip:1"); banned {
+		t.Fatal("client was banned below the threshold")
+	}
+
+	l.recordInvalidBlock("ip:1")
+	banned, remaining := l.banned("ip:1")
+	if !banned || remaining != time.Hour {
+		t.Fatalf("at the threshold: banned = %v, remaining = %v, want true, 1h", banned, remaining)
+	}
+	if banned, _ := l.banned("ip:2"); banned {
+		t.Error("another client was banned")
+	}
+
+	c.advance(59 * time.Minute)
+	if banned, remaining := l.banned("ip:1"); !banned || remaining != time.Minute {
+		t.Errorf("before expiry: banned = %v, remaining = %v, want true, 1m", banned, remaining)
+	}
+
+	c.advance(time.Minute)
+	if banned, _ := l.banned("ip:1"); banned {
+		t.Error("ban did not expire")
+	}
+
+	// The count restarts after a ban.
+	l.recordInvalidBlock("ip:1")
+	if banned, _ := l.banned("ip:1"); banned {
+		t.Error("one offense after a ban banned the client again")
+	}
+}
+
+func TestRateLimiterBanWindow(t *testing.T) {
+	l, c := newTestLimiter()
+
+	l.recordInvalidBlock("ip:1")
+	c.advance(6 * time.Minute)
+	l.recordInvalidBlock("ip:1")
+	c.advance(5 * time.Minute)
+
+	// The first offense is now outside the window.
+	l.recordInvalidBlock("ip:1")
+	if banned, _ := l.banned("ip:1"); banned {
+		t.Fatal("offenses outside the window counted towards a ban")
+	}
+
+	c.advance(time.Minute)
+	l.recordInvalidBlock("ip:1")
+	if banned, _ := l.banned("ip:1"); !banned {
+		t.Error("three offenses within the window did not ban the client")
+	}
+}
+
+func TestRateLimitMiddlewareBansOnlyBlockSubmission(t *testing.T) {
+	l, _ := newTestLimiter()
+	for i := 0; i < 3; i++ {
+		l.recordInvalidBlock("ip:192.0.2.1")
+	}
+
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	mux.HandleFunc("POST /block", ok)
+	mux.HandleFunc("POST /v2/block", ok)
+	mux.HandleFunc("GET /blocks", ok)
+	handler := rateLimit(l, mux, mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/block", strings.NewReader("{}"))
+	req.RemoteAddr = "192.0.2.1:4000"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("banned block submission: status = %d, want 403", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "3600" {
+		t.Errorf("Retry-After = %q, want 3600", got)
+	}
+
+	var body ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Code != "banned" || body.Error.Details["retryAfter"] != float64(3600) {
+		t.Errorf("error = %+v, want code banned and retryAfter 3600", body.Error)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/blocks", nil)
+	req.RemoteAddr = "192.0.2.1:4000"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("banned client reading blocks: status = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// replicaState records how up to date a replica is. It is nil unless the
+// server was started with -follow.
+type replicaState struct {
+	primary string
+
+	mu        sync.Mutex
+	lastSync  time.Time // when the replica last caught up with the primary
+	syncedTo  time.Time // the primary's time it then had every change up to
+	lastError string
+}
+
+var (
+	replica *replicaState
+	// replicaClient has no overall timeout since downloading a snapshot of
+	// a large ledger can take a while and every feed request long-polls.
+	// Shutdown cancels a sync through its context instead.
+	replicaClient = &http.Client{}
+)
+
+func configureReplica(cfg *Config) error {
+	replica = nil
+	if cfg.Replica.Primary == "" {
+		return nil
+	}
+
+	replica = &replicaState{primary: strings.TrimSuffix(cfg.Replica.Primary, "/")}
+
+	tlsConfig, err := clientTLSConfig(cfg.TLS, cfg.Replica.CertFile, cfg.Replica.KeyFile)
+	if err != nil {
+		return err
+	}
+	replicaClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	return nil
+}
+
+// lag is how old the replica's data may be: the time since it last had
+// every change the primary had. While the feed is idle it grows up to the
+// length of a long-poll, and it keeps growing while syncs fail.
+func (s *replicaState) lag() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.syncedTo.IsZero() {
+		return 0, false
+	}
+	return time.Since(s.syncedTo), true
+}
+
+func (s *replicaState) status() ReplicaInfo {
+	lag, synced := s.lag()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := ReplicaInfo{Primary: s.primary, Synced: synced, Error: s.lastError}
+	if synced {
+		status.LastSync = s.lastSync.Unix()
+		status.LagSeconds = int(lag.Seconds())
+	}
+	return status
+}
+
+// info is status in the original GET /info shape, which leaves out the
+// fields that don't apply yet.
+func (s *replicaState) info() map[string]interface{} {
+	status := s.status()
+
+	info := map[string]interface{}{
+		"primary": status.Primary,
+		"synced":  status.Synced,
+	}
+	if status.Synced {
+		info["lastSync"] = status.LastSync
+		info["lagSeconds"] = status.LagSeconds
+	}
+	if status.Error != "" {
+		info["error"] = status.Error
+	}
+	return info
+}
+
+// getReplicationSnapshot serves a consistent copy of the database for a new
+// replica to start from. It is only routed on a primary, behind a client
+// certificate check, since a snapshot holds the whole ledger and is costly
+// to take.
+func getReplicationSnapshot(w http.ResponseWriter, r *http.Request) {
+	dir, err := os.MkdirTemp("", "gc-snapshot")
+	if err != nil {
+		log.Println("snapshot:", err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "snapshot.db")
+	taken := time.Now()
+	if err := snapshotDatabase(path); err != nil {
+		log.Println("snapshot:", err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Println("snapshot:", err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("X-GC-Snapshot-Time", strconv.FormatInt(taken.UnixMilli(), 10))
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
+
+// runReplication loads a snapshot from the primary unless the replica
+// already has its data, then follows the primary's entry feed until ctx is
+// cancelled. After an error it tries again every retryInterval.
+func runReplication(ctx context.Context, retryInterval time.Duration) {
+	for {
+		err := syncFromPrimary(ctx)
+
+		replica.mu.Lock()
+		if err != nil {
+			replica.lastError = err.Error()
+		} else {
+			replica.lastError = ""
+		}
+		replica.mu.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			continue
+		}
+
+		slog.Warn("replication failed", "primary", replica.primary, "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// syncFromPrimary applies one page of the primary's entry feed, the way a
+// node applies a peer's. A replica without a cursor in the feed loads a
+// snapshot first, which records the cursor it was taken at.
+func syncFromPrimary(ctx context.Context) error {
+	cursor, err := queryPeerCursor(sqliteDatabase, replica.primary)
+	if err != nil {
+		return err
+	}
+	if cursor == 0 {
+		return loadSnapshot(ctx)
+	}
+
+	// Until it has caught up once the replica isn't ready, so it doesn't
+	// wait for new entries.
+	wait := peerPollWait
+	if _, synced := replica.lag(); !synced {
+		wait = 0
+	}
+
+	next, more, err := syncFromPeer(ctx, replicaClient, replica.primary, cursor, wait)
+	if next != cursor {
+		ledgerMu.Lock()
+		setPeerCursor(sqliteDatabase, replica.primary, next)
+		ledgerMu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+
+	// The primary answers as soon as it has a new entry, so with none left
+	// the replica has everything the primary had when it answered.
+	if !more {
+		replica.mu.Lock()
+		replica.lastSync = time.Now()
+		replica.syncedTo = replica.lastSync
+		replica.mu.Unlock()
+	}
+	return nil
+}
+
+// loadSnapshot downloads a snapshot from the primary and replaces the local
+// data with it.
+func loadSnapshot(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, replica.primary+"/replication/snapshot", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := replicaClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET /replication/snapshot returned %s", resp.Status)
+	}
+
+	takenMillis, err := strconv.ParseInt(resp.Header.Get("X-GC-Snapshot-Time"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot time: %w", err)
+	}
+
+	file, err := os.CreateTemp("", "gc-replica-*.db")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("downloading snapshot: %w", err)
+	}
+
+	if err := restoreSnapshot(ctx, file.Name(), replica.primary); err != nil {
+		return fmt.Errorf("applying snapshot: %w", err)
+	}
+
+	replica.mu.Lock()
+	replica.lastSync = time.Now()
+	replica.syncedTo = time.UnixMilli(takenMillis)
+	replica.mu.Unlock()
+
+	return nil
+}
+
+// primaryReads are read routes forwarded to the primary along with writes.
+// Invoices aren't part of the entry feed, so a replica only has those that
+// were in its snapshot.
+var primaryReads = map[string]bool{
+	"GET /invoice/{id}":                 true,
+	"GET " + v2Prefix + "/invoice/{id}": true,
+}
+
+// forwardWrites serves reads from mux and forwards every other request the
+// mux would route to the primary, since a replica only takes changes from
+// the primary's feed.
+func forwardWrites(mux *http.ServeMux) http.Handler {
+	target, _ := url.Parse(replica.primary)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = replicaClient.Transport
+	// The replica already set X-Request-ID to the ID it passed on.
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		resp.Header.Del("X-Request-ID")
+		return nil
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		slog.Warn("forwarding to primary failed", "primary", replica.primary, "error", err)
+		writeErrorResponse(w, r, http.StatusBadGateway, "primary_unavailable", "primary unavailable")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		read := r.Method == http.MethodGet || r.Method == http.MethodHead
+		if pattern == "" || (read && !primaryReads[pattern]) {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		r.Pattern = pattern
+		r.Header.Set("X-Request-ID", requestID(r))
+		annotate(r, slog.String("forwardedTo", replica.primary))
+		proxy.ServeHTTP(w, r)
+	})
+}
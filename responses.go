@@ -0,0 +1,416 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Every JSON route is also served under /v2. Version 2 responses are the
+// typed structs below: single resources are returned as objects rather than
+// one-element arrays, lists are wrapped in an object, field names are
+// camelCase, times are Unix seconds and there is no "ok" field. Errors are
+// an ErrorResponse. The unprefixed routes keep their original shapes.
+
+const v2Prefix = "/v2"
+
+// isV2 reports whether r was made to a /v2 route.
+func isV2(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, v2Prefix+"/")
+}
+
+// unversionedRoute returns the original route for a /v2 pattern, so rate
+// limits and bans configured for a route cover both versions.
+func unversionedRoute(pattern string) string {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok || !strings.HasPrefix(path, v2Prefix+"/") {
+		return pattern
+	}
+	return method + " " + strings.TrimPrefix(path, v2Prefix)
+}
+
+// writeResponse writes v2 to /v2 requests and legacy to the rest.
+func writeResponse(w http.ResponseWriter, r *http.Request, legacy, v2 interface{}) {
+	if isV2(r) {
+		writeJSONResponse(w, http.StatusOK, v2)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, legacy)
+}
+
+// APIError describes a failed /v2 request. Code is a stable identifier
+// such as "insufficient_funds" for clients to act on, Message is for
+// people, and Details holds extra fields for some codes.
+type APIError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details"`
+}
+
+type ErrorResponse struct {
+	Error     APIError `json:"error"`
+	RequestID string   `json:"requestId"`
+}
+
+type HoldingResponse struct {
+	Asset   string `json:"asset"`
+	Balance int    `json:"balance"`
+}
+
+// AddressResponse describes an address. Holdings start with the native
+// coin.
+type AddressResponse struct {
+	Address  string            `json:"address"`
+	Balance  int               `json:"balance"`
+	Locked   int               `json:"locked"`
+	Holdings []HoldingResponse `json:"holdings"`
+}
+
+type AddressSummary struct {
+	Address string `json:"address"`
+	Balance int    `json:"balance"`
+}
+
+type AddressListResponse struct {
+	Addresses []AddressSummary `json:"addresses"`
+}
+
+// TransactionResponse is a recorded transaction. Block is empty while the
+// fee is pending, and Sender is "null" for block rewards.
+type TransactionResponse struct {
+	ID        int    `json:"id"`
+	Sender    string `json:"sender"`
+	Recipient string `json:"recipient"`
+	Amount    int    `json:"amount"`
+	Fee       int    `json:"fee"`
+	Time      int64  `json:"time"`
+	Block     string `json:"block"`
+	Memo      string `json:"memo"`
+	Asset     string `json:"asset"`
+}
+
+func transactionResponse(t *Transaction) TransactionResponse {
+	return TransactionResponse{
+		ID:        t.ID,
+		Sender:    t.Sender,
+		Recipient: t.Recipient,
+		Amount:    t.Amount,
+		Fee:       t.Fee,
+		Time:      int64(t.Time),
+		Block:     t.Block,
+		Memo:      t.Memo,
+		Asset:     t.Asset,
+	}
+}
+
+type TransactionListResponse struct {
+	Transactions []TransactionResponse `json:"transactions"`
+}
+
+func transactionListResponse(transactions []Transaction) TransactionListResponse {
+	result := TransactionListResponse{Transactions: make([]TransactionResponse, 0, len(transactions))}
+	for i := range transactions {
+		result.Transactions = append(result.Transactions, transactionResponse(&transactions[i]))
+	}
+	return result
+}
+
+// TransactionResult is the outcome of POST /v2/transaction. When Known is
+// set the transaction had already been recorded and only Hash is filled in.
+type TransactionResult struct {
+	TransactionID int64  `json:"transactionId"`
+	Hash          string `json:"hash"`
+	Asset         string `json:"asset"`
+	Amount        int    `json:"amount"`
+	Fee           int    `json:"fee"`
+	Known         bool   `json:"known"`
+}
+
+type BlockResponse struct {
+	ID        int    `json:"id"`
+	Height    int    `json:"height"`
+	Hash      string `json:"hash"`
+	PrevBlock string `json:"prevBlock"`
+	Address   string `json:"address"`
+	Nonce     string `json:"nonce"`
+	Time      int    `json:"time"`
+}
+
+func blockResponse(b *Block) BlockResponse {
+	return BlockResponse{
+		ID:        b.ID,
+		Height:    b.Height,
+		Hash:      b.BlockContent,
+		PrevBlock: b.PrevBlock,
+		Address:   b.Address,
+		Nonce:     b.Nonce,
+		Time:      b.Time,
+	}
+}
+
+type BlockListResponse struct {
+	Blocks []BlockResponse `json:"blocks"`
+}
+
+func blockListResponse(blocks []Block) BlockListResponse {
+	result := BlockListResponse{Blocks: make([]BlockResponse, 0, len(blocks))}
+	for i := range blocks {
+		result.Blocks = append(result.Blocks, blockResponse(&blocks[i]))
+	}
+	return result
+}
+
+// SideBlockResponse is a block off the main chain, with the status "side"
+// or "orphaned".
+type SideBlockResponse struct {
+	BlockResponse
+	Status string `json:"status"`
+}
+
+type SideBlockListResponse struct {
+	Blocks []SideBlockResponse `json:"blocks"`
+}
+
+// BlockDetailResponse is a block with its status, "main", "side" or
+// "orphaned". NextBlock is empty for side blocks and the tip.
+type BlockDetailResponse struct {
+	BlockResponse
+	Status    string `json:"status"`
+	NextBlock string `json:"nextBlock"`
+}
+
+// ChainTipResponse describes the last main chain block. Work is a decimal
+// string since it can exceed 64 bits.
+type ChainTipResponse struct {
+	Height int    `json:"height"`
+	Hash   string `json:"hash"`
+	Time   int    `json:"time"`
+	Work   string `json:"work"`
+}
+
+// BlockResult is the outcome of POST /v2/block. When Known is set the block
+// had already been accepted and nothing else is filled in.
+type BlockResult struct {
+	Reward int         `json:"reward"`
+	Branch string      `json:"branch"`
+	Reorg  *ReorgEvent `json:"reorg"`
+	Known  bool        `json:"known"`
+}
+
+type ReorgListResponse struct {
+	Reorgs []ReorgEvent `json:"reorgs"`
+}
+
+type InvoiceResponse struct {
+	ID              string `json:"id"`
+	Address         string `json:"address"`
+	Amount          int    `json:"amount"`
+	Memo            string `json:"memo"`
+	Created         int    `json:"created"`
+	Expires         int    `json:"expires"`
+	Status          string `json:"status"`
+	PaidTransaction int64  `json:"paidTransaction"`
+	PaidAt          int    `json:"paidAt"`
+	URI             string `json:"uri"`
+}
+
+type MultisigResponse struct {
+	Address    string   `json:"address"`
+	Threshold  int      `json:"threshold"`
+	PublicKeys []string `json:"pubkeys"`
+	Nonce      int      `json:"nonce"`
+	Balance    int      `json:"balance"`
+}
+
+type MultisigResult struct {
+	TransactionID int64 `json:"transactionId"`
+	Amount        int   `json:"amount"`
+	Fee           int   `json:"fee"`
+}
+
+type ScheduleResponse struct {
+	ID           int64  `json:"id"`
+	Kind         string `json:"kind"`
+	Sender       string `json:"sender"`
+	Recipient    string `json:"recipient"`
+	Amount       int    `json:"amount"`
+	Fee          int    `json:"fee"`
+	Memo         string `json:"memo"`
+	UnlockTime   int    `json:"unlockTime"`
+	UnlockHeight int    `json:"unlockHeight"`
+	EverySeconds int    `json:"everySeconds"`
+	EveryBlocks  int    `json:"everyBlocks"`
+	Status       string `json:"status"`
+	Payments     int    `json:"payments"`
+	Missed       int    `json:"missed"`
+	Created      int    `json:"created"`
+}
+
+type ScheduleListResponse struct {
+	Schedules []ScheduleResponse `json:"schedules"`
+}
+
+// ScheduleResult is the outcome of POST /v2/schedule. TransactionID is the
+// transfer that locked the funds, or zero for a recurring transfer.
+type ScheduleResult struct {
+	TransactionID int64            `json:"transactionId"`
+	Schedule      ScheduleResponse `json:"schedule"`
+}
+
+type EscrowVotes struct {
+	Buyer   string `json:"buyer"`
+	Seller  string `json:"seller"`
+	Arbiter string `json:"arbiter"`
+}
+
+type EscrowResponse struct {
+	ID                 int64       `json:"id"`
+	Buyer              string      `json:"buyer"`
+	Seller             string      `json:"seller"`
+	Arbiter            string      `json:"arbiter"`
+	Amount             int         `json:"amount"`
+	Fee                int         `json:"fee"`
+	Memo               string      `json:"memo"`
+	Votes              EscrowVotes `json:"votes"`
+	Status             string      `json:"status"`
+	Created            int         `json:"created"`
+	Expires            int         `json:"expires"`
+	SettledTransaction int64       `json:"settledTransaction"`
+	SettledAt          int         `json:"settledAt"`
+}
+
+// EscrowResult is the outcome of POST /v2/escrow. TransactionID is the
+// transfer that locked the funds.
+type EscrowResult struct {
+	TransactionID int64          `json:"transactionId"`
+	Escrow        EscrowResponse `json:"escrow"`
+}
+
+type AssetResponse struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Issuer   string `json:"issuer"`
+	Supply   int    `json:"supply"`
+	Mintable bool   `json:"mintable"`
+	Created  int    `json:"created"`
+}
+
+type AssetListResponse struct {
+	Assets []AssetResponse `json:"assets"`
+}
+
+// AssetResult is the outcome of POST /v2/asset. TransactionID is the
+// issuance of the initial supply, or zero if there was none.
+type AssetResult struct {
+	TransactionID int64         `json:"transactionId"`
+	Asset         AssetResponse `json:"asset"`
+}
+
+type MintResult struct {
+	TransactionID int64  `json:"transactionId"`
+	Asset         string `json:"asset"`
+	Amount        int    `json:"amount"`
+}
+
+// ChangeResponse is a change feed entry. The fields after Time are set
+// depending on Type, as in the original feed.
+type ChangeResponse struct {
+	Cursor        int64                `json:"cursor"`
+	Type          string               `json:"type"`
+	Time          int                  `json:"time"`
+	BlockHash     string               `json:"blockHash,omitempty"`
+	Block         *BlockResponse       `json:"block,omitempty"`
+	TransactionID int64                `json:"transactionId,omitempty"`
+	Transaction   *TransactionResponse `json:"transaction,omitempty"`
+	Address       string               `json:"address,omitempty"`
+	Asset         string               `json:"asset,omitempty"`
+	EscrowID      int64                `json:"escrowId,omitempty"`
+	ScheduleID    int64                `json:"scheduleId,omitempty"`
+}
+
+type ChangesPage struct {
+	Changes []ChangeResponse `json:"changes"`
+	Cursor  int64            `json:"cursor"`
+	More    bool             `json:"more"`
+}
+
+func changesPage(changes []Change, cursor int64, more bool) ChangesPage {
+	page := ChangesPage{Changes: make([]ChangeResponse, 0, len(changes)), Cursor: cursor, More: more}
+	for _, c := range changes {
+		change := ChangeResponse{
+			Cursor:        c.Cursor,
+			Type:          c.Type,
+			Time:          c.Time,
+			BlockHash:     c.BlockHash,
+			TransactionID: c.TransactionID,
+			Address:       c.Address,
+			Asset:         c.Asset,
+			EscrowID:      c.EscrowID,
+			ScheduleID:    c.ScheduleID,
+		}
+		if c.Block != nil {
+			blk := blockResponse(c.Block)
+			change.Block = &blk
+		}
+		if c.Transaction != nil {
+			txn := transactionResponse(c.Transaction)
+			change.Transaction = &txn
+		}
+		page.Changes = append(page.Changes, change)
+	}
+	return page
+}
+
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// ReadinessResponse reports each readiness check as "ok". A failed check
+// is a 503 "not_ready" error with the same checks in its details.
+type ReadinessResponse struct {
+	Checks map[string]string `json:"checks"`
+}
+
+type ReplicaInfo struct {
+	Primary    string `json:"primary"`
+	Synced     bool   `json:"synced"`
+	LastSync   int64  `json:"lastSync"`
+	LagSeconds int    `json:"lagSeconds"`
+	Error      string `json:"error"`
+}
+
+// InfoResponse describes the node. Replica is only set on a read-only
+// replica.
+type InfoResponse struct {
+	Version     string       `json:"version"`
+	Commit      string       `json:"commit"`
+	Uptime      int          `json:"uptime"`
+	Height      int          `json:"height"`
+	Tip         string       `json:"tip"`
+	Genesis     string       `json:"genesis"`
+	Difficulty  int          `json:"difficulty"`
+	BlockReward int          `json:"blockReward"`
+	MinFee      int          `json:"minFee"`
+	FeePerByte  int          `json:"feePerByte"`
+	Replica     *ReplicaInfo `json:"replica,omitempty"`
+}
+
+type AdminConfigResponse struct {
+	Config Config `json:"config"`
+}
+
+type AuditLogResponse struct {
+	Entries []AuditEntry `json:"entries"`
+}
+
+type PeerConflictsResponse struct {
+	Conflicts []PeerConflict `json:"conflicts"`
+}
+
+type FrozenResponse struct {
+	Address string `json:"address"`
+	Frozen  bool   `json:"frozen"`
+}
+
+type AdjustmentResult struct {
+	TransactionID int64 `json:"transactionId"`
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ScheduleRequest creates a scheduled transfer. Without an interval it is
+// a one-off transfer locked until unlockTime (unix seconds) and/or
+// unlockHeight. With everySeconds or everyBlocks it recurs, starting at
+// unlockTime or unlockHeight if given and one interval from now otherwise.
+type ScheduleRequest struct {
+	Pkey         string `json:"pkey"`
+	Address      string `json:"address"`
+	Amount       int    `json:"amount"`
+	Fee          int    `json:"fee"`
+	Memo         string `json:"memo"`
+	UnlockTime   int    `json:"unlockTime"`
+	UnlockHeight int    `json:"unlockHeight"`
+	EverySeconds int    `json:"everySeconds"`
+	EveryBlocks  int    `json:"everyBlocks"`
+}
+
+type CancelScheduleRequest struct {
+	Pkey string `json:"pkey"`
+}
+
+func scheduleResponse(st *ScheduledTransfer) ScheduleResponse {
+	return ScheduleResponse{
+		ID:           st.ID,
+		Kind:         st.Kind,
+		Sender:       st.Sender,
+		Recipient:    st.Recipient,
+		Amount:       st.Amount,
+		Fee:          st.Fee,
+		Memo:         st.Memo,
+		UnlockTime:   st.UnlockTime,
+		UnlockHeight: st.UnlockHeight,
+		EverySeconds: st.EverySeconds,
+		EveryBlocks:  st.EveryBlocks,
+		Status:       st.Status,
+		Payments:     st.Payments,
+		Missed:       st.Missed,
+		Created:      st.Created,
+	}
+}
+
+func createSchedule(w http.ResponseWriter, r *http.Request) {
+	var req ScheduleRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	if !validateAddress(req.Address) {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_address", "invalid address")
+		return
+	}
+
+	if req.Amount <= 0 {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_amount", "amount must be positive")
+		return
+	}
+
+	if !checkMemoAndFee(w, r, req.Memo, req.Fee, len(body)) {
+		return
+	}
+
+	if req.UnlockTime < 0 || req.UnlockHeight < 0 || req.EverySeconds < 0 || req.EveryBlocks < 0 {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_schedule", "times, heights and intervals must not be negative")
+		return
+	}
+
+	height, err := queryChainHeight(sqliteDatabase)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	now := int(time.Now().Unix())
+	st := &ScheduledTransfer{
+		Sender:       generateAddress(req.Pkey),
+		Recipient:    req.Address,
+		Amount:       req.Amount,
+		Fee:          req.Fee,
+		Memo:         req.Memo,
+		UnlockTime:   req.UnlockTime,
+		UnlockHeight: req.UnlockHeight,
+		EverySeconds: req.EverySeconds,
+		EveryBlocks:  req.EveryBlocks,
+		Status:       "active",
+		Created:      now,
+	}
+
+	switch {
+	case req.EverySeconds > 0 && req.EveryBlocks > 0:
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_schedule", "only one of everySeconds and everyBlocks may be set")
+		return
+	case req.EverySeconds > 0:
+		st.Kind = "recurring"
+		if st.UnlockTime == 0 {
+			st.UnlockTime = now + req.EverySeconds
+		}
+	case req.EveryBlocks > 0:
+		st.Kind = "recurring"
+		if st.UnlockHeight == 0 {
+			st.UnlockHeight = height + req.EveryBlocks
+		}
+	default:
+		st.Kind = "locked"
+		if req.UnlockTime <= now && req.UnlockHeight <= height {
+			writeErrorResponse(w, r, http.StatusBadRequest, "invalid_unlock", "unlockTime or unlockHeight must be in the future")
+			return
+		}
+	}
+
+	// Recurring payments are made one at a time when they fall due, so only
+	// locked transfers take the funds now.
+	var txID int64
+	if st.Kind == "locked" {
+		txID, err = lockTransfer(st)
+	} else {
+		err = addRecurringTransfer(st)
+	}
+	switch {
+	case errors.Is(err, errFrozen):
+		writeErrorResponse(w, r, http.StatusForbidden, "frozen", "address frozen")
+		return
+	case errors.Is(err, errInsufficientFunds):
+		writeErrorResponse(w, r, http.StatusBadRequest, "insufficient_funds", "insufficient funds")
+		return
+	case errors.Is(err, errMultisigSender):
+		writeErrorResponse(w, r, http.StatusForbidden, "multisig_sender", "multisig addresses can only be spent from with POST /multisig/transaction")
+		return
+	case err != nil:
+		log.Println("scheduling transfer failed:", err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	annotate(r, slog.Int64("scheduleId", st.ID))
+
+	schedule := scheduleResponse(st)
+	response := map[string]interface{}{"ok": true, "schedules": []ScheduleResponse{schedule}}
+	if txID != 0 {
+		response["transaction"] = txID
+	}
+	writeResponse(w, r, response, ScheduleResult{TransactionID: txID, Schedule: schedule})
+}
+
+func getSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "schedule_not_found", "scheduled transfer not found")
+		return
+	}
+
+	st, err := queryScheduledTransfer(sqliteDatabase, id)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+	if st == nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "schedule_not_found", "scheduled transfer not found")
+		return
+	}
+
+	response := scheduleResponse(st)
+	writeResponse(w, r, map[string]interface{}{"ok": true, "schedules": []ScheduleResponse{response}}, response)
+}
+
+func getAddressSchedules(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+
+	if !validateAddress(address) {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_address", "invalid address")
+		return
+	}
+
+	transfers, err := queryAddressScheduledTransfers(sqliteDatabase, address)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	result := ScheduleListResponse{Schedules: make([]ScheduleResponse, 0, len(transfers))}
+	for i := range transfers {
+		result.Schedules = append(result.Schedules, scheduleResponse(&transfers[i]))
+	}
+
+	writeResponse(w, r, map[string]interface{}{"ok": true, "schedules": result.Schedules}, result)
+}
+
+func cancelSchedule(w http.ResponseWriter, r *http.Request) {
+	var req CancelScheduleRequest
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeErrorResponse(w, r, http.StatusNotFound, "schedule_not_found", "scheduled transfer not found")
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, r, http.StatusBadRequest, "invalid_body", "invalid request body")
+		return
+	}
+
+	st, err := cancelScheduledTransfer(id, generateAddress(req.Pkey), int(time.Now().Unix()))
+	switch {
+	case errors.Is(err, errScheduleNotFound):
+		writeErrorResponse(w, r, http.StatusNotFound, "schedule_not_found", "scheduled transfer not found")
+		return
+	case errors.Is(err, errNotScheduleSender):
+		writeErrorResponse(w, r, http.StatusForbidden, "not_sender", "only the sender can cancel a scheduled transfer")
+		return
+	case errors.Is(err, errNotCancellable):
+		writeErrorResponse(w, r, http.StatusConflict, "schedule_not_active", "only active recurring transfers can be cancelled")
+		return
+	case err != nil:
+		log.Println("cancelling scheduled transfer failed:", err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, "internal", "internal server error")
+		return
+	}
+
+	response := scheduleResponse(st)
+	writeResponse(w, r, map[string]interface{}{"ok": true, "schedules": []ScheduleResponse{response}}, response)
+}
+
+// runScheduler processes due scheduled transfers and expired escrows every
+// interval until ctx is cancelled.
+func runScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			processScheduledTransfers(int(now.Unix()))
+			processExpiredEscrows(int(now.Unix()))
+		}
+	}
+}
+
+func processScheduledTransfers(now int) {
+	height, err := queryChainHeight(sqliteDatabase)
+	if err != nil {
+		log.Println("scheduler: chain height:", err)
+		return
+	}
+
+	due, err := queryDueScheduledTransfers(sqliteDatabase, now, height)
+	if err != nil {
+		log.Println("scheduler: due transfers:", err)
+		return
+	}
+
+	for _, st := range due {
+		if err := runScheduledTransfer(st.ID, now, height); err != nil {
+			log.Printf("scheduler: transfer %d: %v\n", st.ID, err)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ensureCertificates generates and persists a self-signed CA and a server
+// certificate signed by it when autoGenerate is enabled and the files do not
+// exist yet. Existing files are never overwritten.
+func ensureCertificates(cfg TLSConfig) error {
+	if !cfg.AutoGenerate {
+		return nil
+	}
+
+	if !fileExists(cfg.CACertFile) || !fileExists(cfg.CAKeyFile) {
+		log.Println("Generating self-signed CA...")
+		caCert, caKey, err := generateCertificate(nil, nil, "go-cash CA", nil, true, false)
+		if err != nil {
+			return err
+		}
+		if err := writeCertificate(cfg.CACertFile, cfg.CAKeyFile, caCert, caKey); err != nil {
+			return err
+		}
+		log.Printf("CA written to %s\n", cfg.CACertFile)
+	}
+
+	if fileExists(cfg.CertFile) && fileExists(cfg.KeyFile) {
+		return nil
+	}
+
+	caCert, caKey, err := loadCA(cfg.CACertFile, cfg.CAKeyFile)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Generating server certificate...")
+	cert, key, err := generateCertificate(caCert, caKey, "go-cash server", cfg.Hosts, false, false)
+	if err != nil {
+		return err
+	}
+	if err := writeCertificate(cfg.CertFile, cfg.KeyFile, cert, key); err != nil {
+		return err
+	}
+	log.Printf("Server certificate written to %s\n", cfg.CertFile)
+
+	return nil
+}
+
+// issueClientCertificate signs a client certificate for a miner with the
+// configured CA so it can be used for mutual TLS.
+func issueClientCertificate(cfg TLSConfig, name, certFile, keyFile string) error {
+	caCert, caKey, err := loadCA(cfg.CACertFile, cfg.CAKeyFile)
+	if err != nil {
+		return err
+	}
+
+	cert, key, err := generateCertificate(caCert, caKey, name, nil, false, true)
+	if err != nil {
+		return err
+	}
+
+	return writeCertificate(certFile, keyFile, cert, key)
+}
+
+func generateCertificate(parent *x509.Certificate, parentKey *ecdsa.PrivateKey, commonName string, hosts []string, isCA bool, isClient bool) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating serial number: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName, Organization: []string{"go-cash"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+
+	switch {
+	case isCA:
+		template.IsCA = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		template.NotAfter = time.Now().AddDate(20, 0, 0)
+	case isClient:
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	default:
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		for _, host := range hosts {
+			if ip := net.ParseIP(host); ip != nil {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			} else {
+				template.DNSNames = append(template.DNSNames, host)
+			}
+		}
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent, parentKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating certificate: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cert, key, nil
+}
+
+func writeCertificate(certFile, keyFile string, cert *x509.Certificate, key *ecdsa.PrivateKey) error {
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		return fmt.Errorf("writing certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return fmt.Errorf("writing key: %w", err)
+	}
+
+	return nil
+}
+
+func loadCA(certFile, keyFile string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading CA: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, nil, errors.New("CA key must be an ECDSA key")
+	}
+
+	return cert, key, nil
+}
+
+// serverTLSConfig returns the TLS configuration for the listener. When a
+// client CA is configured, client certificates are verified if presented so
+// that requireClientCert can restrict individual routes.
+func serverTLSConfig(cfg TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	caPEM, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading client CA: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("no certificates found in client CA file")
+	}
+
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+
+	return tlsConfig, nil
+}
+
+// clientTLSConfig returns the TLS configuration for connections this
+// server makes to peers. Servers are trusted if their certificate is
+// signed by a system root or by tls.caCertFile, and certFile and keyFile,
+// if set, are presented as the client certificate.
+func clientTLSConfig(cfg TLSConfig, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if fileExists(cfg.CACertFile) {
+		caPEM, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func requireClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			writeErrorResponse(w, r, http.StatusForbidden, "certificate_required", "client certificate required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func certificateFingerprint(certFile string) (string, error) {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return "", err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", errors.New("no PEM data found in certificate file")
+	}
+
+	sum := sha256.Sum256(block.Bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
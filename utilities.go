@@ -1,11 +1,20 @@
 package main
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
+	"math/big"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// maxMemoLength is the longest memo, in bytes, a transaction may carry.
+const maxMemoLength = 140
+
 func generateAddress(pkey string) string {
 	sum := sha256.Sum256([]byte(pkey))
 	addressHex := hex.EncodeToString(sum[:])
@@ -25,3 +34,90 @@ func genBlock(block string, address string, nonce string) string {
 
 	return addressHex
 }
+
+// meetsDifficulty reports whether a block hash starts with at least
+// difficulty zero hex digits.
+func meetsDifficulty(block string, difficulty int) bool {
+	return strings.HasPrefix(block, strings.Repeat("0", difficulty))
+}
+
+// blockWork is the expected number of hashes needed to mine count blocks
+// at difficulty. Each leading zero hex digit makes a block 16 times harder
+// to find. Blocks of unknownDifficulty count at the configured difficulty.
+func blockWork(difficulty, count int) *big.Int {
+	if difficulty == unknownDifficulty {
+		difficulty = serverConfig.Difficulty
+	}
+	work := new(big.Int).Lsh(big.NewInt(1), uint(4*difficulty))
+	return work.Mul(work, big.NewInt(int64(count)))
+}
+
+// branchWork is the expected number of hashes needed to mine blocks.
+func branchWork(blocks []Block) *big.Int {
+	work := new(big.Int)
+	for _, blk := range blocks {
+		work.Add(work, blockWork(blk.Difficulty, 1))
+	}
+	return work
+}
+
+func validateMemo(memo string) bool {
+	if len(memo) > maxMemoLength || !utf8.ValidString(memo) {
+		return false
+	}
+
+	for _, r := range memo {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validateAssetID accepts 2 to 12 uppercase letters and digits starting
+// with a letter, such as GOLD or TKN2.
+func validateAssetID(id string) bool {
+	if len(id) < 2 || len(id) > 12 || id[0] < 'A' || id[0] > 'Z' {
+		return false
+	}
+
+	for _, r := range id {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newTransactionHash returns a random ID that identifies a transaction on
+// every node it is relayed to.
+func newTransactionHash() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// derivedTransactionHash returns the hash of a transaction that every node
+// creates for itself, such as an escrow settlement, so the copies made on
+// different nodes are recognised as one.
+func derivedTransactionHash(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// newRecordID returns a random ID for an escrow or scheduled transfer, so
+// it has the same ID on every node. It stays below 2^53 so JavaScript
+// clients can hold it exactly.
+func newRecordID() int64 {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return int64(binary.BigEndian.Uint64(b)>>11) | 1
+}
+
+func newInvoiceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
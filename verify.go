@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type VerificationReport struct {
+	OK       bool     `json:"ok"`
+	Blocks   int      `json:"blocks"`
+	Accounts int      `json:"accounts"`
+	Problems []string `json:"problems"`
+}
+
+// verifyLedger checks that every block links to and hashes from its
+// predecessor and that each stored balance equals the sum of the
+// transactions sent to and from the address, including fees paid.
+func verifyLedger(db *sql.DB) (*VerificationReport, error) {
+	defer observeQuery("verifyLedger", time.Now())
+
+	report := &VerificationReport{Problems: []string{}}
+
+	blocks, err := queryBlocks(db)
+	if err != nil {
+		return nil, err
+	}
+	report.Blocks = len(blocks)
+
+	for i, blk := range blocks {
+		if blk.Height != i {
+			report.Problems = append(report.Problems, fmt.Sprintf("block %d: height %d, want %d", blk.ID, blk.Height, i))
+		}
+		if i == 0 {
+			continue
+		}
+		if blk.PrevBlock != blocks[i-1].BlockContent {
+			report.Problems = append(report.Problems, fmt.Sprintf("block %d: prevBlock %s does not match block %d", blk.ID, blk.PrevBlock, blocks[i-1].ID))
+		}
+		if blk.BlockContent != genBlock(blk.PrevBlock, blk.Address, blk.Nonce) {
+			report.Problems = append(report.Problems, fmt.Sprintf("block %d: hash does not match its contents", blk.ID))
+		}
+	}
+
+	// Fees are always paid in the native coin, whatever asset was sent.
+	querySQL := `SELECT a.address, 'GC', a.balance,
+		COALESCE((SELECT SUM(amount) FROM transactions WHERE recipient = a.address AND asset = 'GC'), 0) -
+		COALESCE((SELECT SUM(amount) FROM transactions WHERE sender = a.address AND asset = 'GC'), 0) -
+		COALESCE((SELECT SUM(fee) FROM transactions WHERE sender = a.address), 0)
+		FROM addresses a`
+	if err := verifyBalances(db, report, querySQL); err != nil {
+		return nil, err
+	}
+
+	querySQL = `SELECT b.address, b.asset, b.balance,
+		COALESCE((SELECT SUM(amount) FROM transactions WHERE recipient = b.address AND asset = b.asset), 0) -
+		COALESCE((SELECT SUM(amount) FROM transactions WHERE sender = b.address AND asset = b.asset), 0)
+		FROM asset_balances b`
+	if err := verifyBalances(db, report, querySQL); err != nil {
+		return nil, err
+	}
+
+	assets, err := queryAssets(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, asset := range assets {
+		circulating, err := queryAssetCirculation(db, asset.ID)
+		if err != nil {
+			return nil, err
+		}
+		if circulating != asset.Supply {
+			report.Problems = append(report.Problems, fmt.Sprintf("asset %s: supply %d, balances sum to %d", asset.ID, asset.Supply, circulating))
+		}
+	}
+
+	report.OK = len(report.Problems) == 0
+	return report, nil
+}
+
+// verifyBalances runs a query returning (address, asset, balance, expected)
+// rows and reports every row where the two disagree.
+func verifyBalances(db *sql.DB, report *VerificationReport, querySQL string) error {
+	rows, err := db.Query(querySQL)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var address, asset string
+		var balance, expected int
+		if err := rows.Scan(&address, &asset, &balance, &expected); err != nil {
+			return err
+		}
+		report.Accounts++
+		if balance != expected {
+			report.Problems = append(report.Problems, fmt.Sprintf("address %s: %s balance %d, transactions sum to %d", address, asset, balance, expected))
+		}
+	}
+
+	return rows.Err()
+}
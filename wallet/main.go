@@ -1,41 +1,47 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/hypnophobe/go-cash/client"
 )
 
 var syncNode = "http://localhost:8080/"
 
-type Address struct {
-	Address string `json:"address"`
-	Balance int    `json:"balance"`
-}
-
-type GetAddressResponse struct {
-	Addresses []Address `json:"addresses"`
-	OK        bool      `json:"ok"`
-}
-
-type ErrorResponse struct {
-	Error string `json:"error"`
-	OK    bool   `json:"ok"`
-}
+var api *client.Client
 
 func usage() {
 	fmt.Println("Usage:")
+	fmt.Println("  gc-wallet [flags]")
+	fmt.Println("  gc-wallet -p (password) [-f fee] pay (uri)  Pay a go-cash: payment URI")
+	fmt.Println("  gc-wallet -p (password) pubkey  Print the multisig public key for a password")
+	fmt.Println("  gc-wallet -m (threshold) -k (keys) multisig create  Create an M-of-N multisig address")
+	fmt.Println("  gc-wallet -from (multisig) -r (address) -a (amount) [-f fee] [-memo memo] multisig propose (file)")
+	fmt.Println("  gc-wallet -p (password) multisig sign (file)  Add a signature to a proposal")
+	fmt.Println("  gc-wallet multisig broadcast (file)  Send a signed proposal")
+	fmt.Println("Flags:")
 	fmt.Println("  -b string The address to check the balance of")
 	fmt.Println("  -s        Send a transaction")
 	fmt.Println("  -p string The password for the private key (for send)")
 	fmt.Println("  -a int    The amount to send in the transaction (for send)")
+	fmt.Println("  -f int    The fee to pay the miner (for send)")
+	fmt.Println("  -memo string A memo or payment reference (for send)")
+	fmt.Println("  -asset string The asset to send, defaulting to the native coin (for send)")
 	fmt.Println("  -r string The recipient address to send to (for send)")
+	fmt.Println("  -m int    The number of signatures required (for multisig create)")
+	fmt.Println("  -k string Comma separated public keys (for multisig create)")
+	fmt.Println("  -from string The multisig address to spend from (for multisig propose)")
+	fmt.Println("  -n string The server URL (default http://localhost:8080/)")
+	fmt.Println("  -ca string A CA certificate file to trust for HTTPS")
+	fmt.Println("  -fingerprint string The pinned SHA-256 fingerprint of the server certificate")
 }
 
 func main() {
@@ -43,19 +49,74 @@ func main() {
 	send := flag.Bool("s", false, "Send a transaction")
 	password := flag.String("p", "", "The password for the private key (for send)")
 	amount := flag.Int("a", 0, "The amount to send in the transaction (for send)")
+	fee := flag.Int("f", 0, "The fee to pay the miner (for send)")
+	memo := flag.String("memo", "", "A memo or payment reference (for send)")
+	asset := flag.String("asset", "", "The asset to send, defaulting to the native coin (for send)")
 	address := flag.String("r", "", "The address to send to (for send)")
+	threshold := flag.Int("m", 0, "The number of signatures required (for multisig create)")
+	keys := flag.String("k", "", "Comma separated public keys (for multisig create)")
+	from := flag.String("from", "", "The multisig address to spend from (for multisig propose)")
+	node := flag.String("n", syncNode, "The server URL")
+	caFile := flag.String("ca", "", "A CA certificate file to trust for HTTPS")
+	fingerprint := flag.String("fingerprint", "", "The pinned SHA-256 fingerprint of the server certificate")
 
 	flag.Usage = usage
 
 	flag.Parse()
 
+	httpClient, err := client.NewHTTPClient(client.TLSOptions{CAFile: *caFile, Fingerprint: *fingerprint})
+	if err != nil {
+		log.Fatalf("Error configuring HTTPS: %v", err)
+	}
+	api, err = client.New(*node, client.WithHTTPClient(httpClient))
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	if flag.Arg(0) == "pay" {
+		if *password == "" || flag.Arg(1) == "" {
+			fmt.Println("Error: pay requires the -p flag and a payment URI.")
+			flag.Usage()
+			return
+		}
+		payAddress, payAmount, ref, err := parsePaymentURI(flag.Arg(1))
+		if err != nil {
+			log.Fatalf("Error parsing payment URI: %v", err)
+		}
+		sendTransaction(*password, payAddress, "", payAmount, *fee, ref)
+		return
+	}
+
+	if flag.Arg(0) == "pubkey" {
+		if *password == "" {
+			fmt.Println("Error: pubkey requires the -p flag.")
+			flag.Usage()
+			return
+		}
+		fmt.Println(publicKeyHex(*password))
+		return
+	}
+
+	if flag.Arg(0) == "multisig" {
+		if err := runMultisig(flag.Arg(1), flag.Arg(2), *password, *threshold, *keys, *from, *address, *amount, *fee, *memo); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
 	if *balanceAddress != "" {
-		balance, err := getBalance(*balanceAddress)
+		addr, err := getBalance(*balanceAddress)
 		if err != nil {
 			log.Fatalf("Error fetching balance: %v", err)
 		}
 		fmt.Printf("Address: %s\n", *balanceAddress)
-		fmt.Printf("Balance: %d\n", balance)
+		fmt.Printf("Balance: %d\n", addr.Balance)
+		// The first holding is the native coin shown as the balance.
+		for i, h := range addr.Holdings {
+			if i > 0 {
+				fmt.Printf("  %s: %d\n", h.Asset, h.Balance)
+			}
+		}
 		return
 	}
 
@@ -65,30 +126,40 @@ func main() {
 			flag.Usage()
 			return
 		}
-		sendTransaction(*password, *address, *amount)
+		sendTransaction(*password, *address, *asset, *amount, *fee, *memo)
 		return
 	}
 
 	flag.Usage()
 }
 
-func getBalance(address string) (int, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/address/%s", syncNode, address))
+func getBalance(address string) (*client.Address, error) {
+	addr, err := api.Address(context.Background(), address)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch balance for address %s: %v", address, errorMessage(err))
+	}
+	return addr, nil
+}
+
+// parsePaymentURI parses a go-cash:(address)?amount=(amount)&ref=(ref) URI.
+// The ref is sent as the transaction memo so the server can match the
+// payment to its invoice.
+func parsePaymentURI(uri string) (string, int, string, error) {
+	u, err := url.Parse(uri)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch balance: %v", err)
+		return "", 0, "", err
 	}
-	defer resp.Body.Close()
 
-	var balanceResp GetAddressResponse
-	if err := json.NewDecoder(resp.Body).Decode(&balanceResp); err != nil {
-		return 0, fmt.Errorf("failed to decode balance response: %v", err)
+	if u.Scheme != "go-cash" || u.Opaque == "" {
+		return "", 0, "", fmt.Errorf("expected a go-cash:(address) URI")
 	}
 
-	if !balanceResp.OK || len(balanceResp.Addresses) == 0 {
-		return 0, fmt.Errorf("could not fetch balance for address %s", address)
+	amount, err := strconv.Atoi(u.Query().Get("amount"))
+	if err != nil || amount <= 0 {
+		return "", 0, "", fmt.Errorf("invalid amount %q", u.Query().Get("amount"))
 	}
 
-	return balanceResp.Addresses[0].Balance, nil
+	return u.Opaque, amount, u.Query().Get("ref"), nil
 }
 
 func generatePkey(password string) string {
@@ -96,48 +167,45 @@ func generatePkey(password string) string {
 	return hex.EncodeToString(sum[:])
 }
 
-func sendTransaction(password, address string, amount int) {
-	pkey := generatePkey(password)
-
-	transaction := map[string]interface{}{
-		"pkey":    pkey,
-		"address": address,
-		"amount":  amount,
-	}
-
-	body, err := json.Marshal(transaction)
+func sendTransaction(password, address, asset string, amount, fee int, memo string) {
+	_, err := api.SendTransaction(context.Background(), client.TransactionRequest{
+		Pkey:    generatePkey(password),
+		Address: address,
+		Amount:  amount,
+		Fee:     fee,
+		Memo:    memo,
+		Asset:   asset,
+	})
 	if err != nil {
-		log.Fatalln("Failed to marshal JSON:", err)
+		var apiErr *client.APIError
+		if !errors.As(err, &apiErr) {
+			log.Fatalln("Failed to send request:", err)
+		}
+		fmt.Printf("Transaction failed: %s\n", apiErr.Message)
+		return
 	}
 
-	req, err := http.NewRequest("POST", syncNode+"transaction", bytes.NewBuffer(body))
-	if err != nil {
-		log.Fatalln("Failed to create request:", err)
+	fmt.Println("Transaction sent successfully.")
+	if asset != "" {
+		fmt.Printf("Asset:  %s\n", asset)
+		fmt.Printf("Amount: %d\n", amount)
+		fmt.Printf("Fee:    %d\n", fee)
+	} else {
+		fmt.Printf("Amount: %d\n", amount)
+		fmt.Printf("Fee:    %d\n", fee)
+		fmt.Printf("Total:  %d\n", amount+fee)
 	}
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Fatalln("Failed to send request:", err)
+	if memo != "" {
+		fmt.Printf("Memo:   %s\n", memo)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		errorBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Fatalln("Failed to read error response body:", err)
-		}
-
-		var errorResponse ErrorResponse
-		err = json.Unmarshal(errorBody, &errorResponse)
-		if err != nil {
-			log.Fatalln("Failed to unmarshal error response:", err)
-		}
+}
 
-		fmt.Printf("Transaction failed: %s\n", errorResponse.Error)
-		return
+// errorMessage returns the server's message for API errors, which already
+// say what went wrong, and the full error otherwise.
+func errorMessage(err error) string {
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Message
 	}
-
-	fmt.Println("Transaction sent successfully.")
+	return err.Error()
 }
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hypnophobe/go-cash/client"
+)
+
+// signingKey derives the ed25519 key for a password. The seed is separated
+// from generatePkey because the pkey is sent to the server with every
+// ordinary transaction.
+func signingKey(password string) ed25519.PrivateKey {
+	seed := sha256.Sum256([]byte("go-cash-multisig:" + password))
+	return ed25519.NewKeyFromSeed(seed[:])
+}
+
+func publicKeyHex(password string) string {
+	return hex.EncodeToString(signingKey(password).Public().(ed25519.PublicKey))
+}
+
+func runMultisig(command, file string, password string, threshold int, keys, from, address string, amount, fee int, memo string) error {
+	switch command {
+	case "create":
+		if threshold <= 0 || keys == "" {
+			return fmt.Errorf("create requires -m and -k")
+		}
+		return createMultisig(threshold, strings.Split(keys, ","))
+	case "propose":
+		if from == "" || address == "" || amount <= 0 || file == "" {
+			return fmt.Errorf("propose requires -from, -r, -a and a file")
+		}
+		return proposeMultisig(file, from, address, amount, fee, memo)
+	case "sign":
+		if password == "" || file == "" {
+			return fmt.Errorf("sign requires -p and a file")
+		}
+		return signMultisig(file, password)
+	case "broadcast":
+		if file == "" {
+			return fmt.Errorf("broadcast requires a file")
+		}
+		return broadcastMultisig(file)
+	default:
+		return fmt.Errorf("unknown multisig command %q", command)
+	}
+}
+
+func createMultisig(threshold int, keys []string) error {
+	multisig, err := api.CreateMultisig(context.Background(), threshold, keys)
+	if err != nil {
+		return errors.New(errorMessage(err))
+	}
+
+	fmt.Printf("Address:   %s\n", multisig.Address)
+	fmt.Printf("Threshold: %d of %d\n", multisig.Threshold, len(multisig.PublicKeys))
+	return nil
+}
+
+func proposeMultisig(file, from, address string, amount, fee int, memo string) error {
+	multisig, err := api.Multisig(context.Background(), from)
+	if err != nil {
+		return errors.New(errorMessage(err))
+	}
+
+	txn := &client.MultisigTransaction{
+		From:       from,
+		Address:    address,
+		Amount:     amount,
+		Fee:        fee,
+		Memo:       memo,
+		Nonce:      multisig.Nonce + 1,
+		Signatures: []client.MultisigSignature{},
+	}
+	if err := writeMultisigFile(file, txn); err != nil {
+		return err
+	}
+
+	fmt.Printf("Proposal written to %s (nonce %d, %d signatures required)\n", file, txn.Nonce, multisig.Threshold)
+	return nil
+}
+
+// signMultisig adds the password's signature to a proposal. It works
+// offline and replaces any earlier signature from the same key.
+func signMultisig(file, password string) error {
+	txn, err := readMultisigFile(file)
+	if err != nil {
+		return err
+	}
+
+	txn.Sign(signingKey(password))
+
+	if err := writeMultisigFile(file, txn); err != nil {
+		return err
+	}
+
+	fmt.Printf("Signed %s as %s (%d signatures)\n", file, publicKeyHex(password), len(txn.Signatures))
+	return nil
+}
+
+func broadcastMultisig(file string) error {
+	txn, err := readMultisigFile(file)
+	if err != nil {
+		return err
+	}
+
+	if _, err := api.SendMultisigTransaction(context.Background(), txn); err != nil {
+		return errors.New(errorMessage(err))
+	}
+
+	fmt.Println("Transaction sent successfully.")
+	fmt.Printf("From:   %s\n", txn.From)
+	fmt.Printf("Amount: %d\n", txn.Amount)
+	fmt.Printf("Fee:    %d\n", txn.Fee)
+	return nil
+}
+
+func readMultisigFile(file string) (*client.MultisigTransaction, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var txn client.MultisigTransaction
+	if err := json.Unmarshal(data, &txn); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	return &txn, nil
+}
+
+func writeMultisigFile(file string, txn *client.MultisigTransaction) error {
+	data, err := json.MarshalIndent(txn, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0o644)
+}